@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"github.com/gterranova/normaplus/backend/internal/export"
 	"github.com/gterranova/normaplus/backend/internal/store"
 	"github.com/gterranova/normaplus/backend/normattiva"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
 )
 
 // corsMiddleware adds CORS headers to allow frontend access
@@ -52,10 +54,32 @@ func main() {
 	exportService := export.NewService()
 
 	client := normattiva.NewClient(30 * time.Second)
+	// Feed the citation graph with every act we parse
+	client.OnParse(func(doc *document.Document) {
+		if err := store.SaveCitations(context.Background(), doc); err != nil {
+			log.Printf("Failed to index citations of %s: %v", doc.CodiceRedazionale, err)
+		}
+	})
+	// Archive the XML of every fetch, for the integrity manifests
+	client.OnSource(func(doc *document.Document, raw []byte) {
+		if err := store.ArchiveSource(context.Background(), doc, raw); err != nil {
+			log.Printf("Failed to archive the source of %s: %v", doc.CodiceRedazionale, err)
+		}
+	})
 	handler := api.NewHandler(client, store, aiService, exportService)
 
 	http.HandleFunc("/api/search", corsMiddleware(handler.Search))
 	http.HandleFunc("/api/document", corsMiddleware(handler.GetDocument))
+	http.HandleFunc("/api/document/citations", corsMiddleware(handler.HandleCitations))
+	http.HandleFunc("/api/document/citedby", corsMiddleware(handler.HandleCitedBy))
+	http.HandleFunc("/api/document/definitions", corsMiddleware(handler.HandleDefinitions))
+	http.HandleFunc("/api/document/deadlines", corsMiddleware(handler.HandleDeadlines))
+	http.HandleFunc("/api/document/inforce", corsMiddleware(handler.HandleEntryIntoForce))
+	http.HandleFunc("/api/document/status", corsMiddleware(handler.HandleStatus))
+	http.HandleFunc("/api/document/novelle", corsMiddleware(handler.HandleNovelle))
+	http.HandleFunc("/api/document/consolidate", corsMiddleware(handler.HandleConsolidate))
+	http.HandleFunc("/api/document/modifications", corsMiddleware(handler.HandleModifications))
+	http.HandleFunc("/api/document/select", corsMiddleware(handler.HandleSelect))
 
 	// New routes
 	http.HandleFunc("/api/users", corsMiddleware(handler.HandleUsers))
@@ -63,6 +87,10 @@ func main() {
 	http.HandleFunc("/api/annotations", corsMiddleware(handler.HandleAnnotations))
 	http.HandleFunc("/api/ai/generate", corsMiddleware(handler.HandleAIGenerate))
 	http.HandleFunc("/api/export", corsMiddleware(handler.HandleExport))
+	http.HandleFunc("/api/export/formats", corsMiddleware(handler.HandleExportFormats))
+	http.HandleFunc("/api/export/templates", corsMiddleware(handler.HandleExportTemplates))
+	http.HandleFunc("/api/export/compilation", corsMiddleware(handler.HandleExportCompilation))
+	http.HandleFunc("/api/integrity/verify", corsMiddleware(handler.HandleVerifyIntegrity))
 
 	// Serve static files from the embedded filesystem
 	staticFS := assets.GetFileSystem()
@@ -4,6 +4,8 @@ go 1.24.5
 
 require (
 	github.com/PuerkitoBio/goquery v1.11.0
+	github.com/go-pdf/fpdf v0.9.0
+	golang.org/x/image v0.30.0
 	golang.org/x/net v0.47.0
 	modernc.org/sqlite v1.44.3
 )
@@ -0,0 +1,337 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gterranova/normaplus/backend/internal/consolidate"
+	"github.com/gterranova/normaplus/backend/internal/deadlines"
+	"github.com/gterranova/normaplus/backend/internal/definitions"
+	"github.com/gterranova/normaplus/backend/internal/inforce"
+	"github.com/gterranova/normaplus/backend/internal/novella"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// --- Analysis Handlers ---
+
+// HandleDefinitions lists the terms defined by a document.
+// GET /api/document/definitions?id=...&date=...&vigenza=... or ?urn=...
+func (h *Handler) HandleDefinitions(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	doc, err := h.loadDocument(r.URL.Query())
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+
+	defs := definitions.Extract(doc)
+	if defs == nil {
+		defs = []definitions.Definition{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(defs)
+}
+
+// HandleDeadlines lists the time limits and dates stated by a document.
+// GET /api/document/deadlines?id=...&date=...&vigenza=...[&format=csv] or ?urn=...
+func (h *Handler) HandleDeadlines(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	query := r.URL.Query()
+	doc, err := h.loadDocument(query)
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+
+	found := deadlines.Extract(doc)
+	if found == nil {
+		found = []deadlines.Deadline{}
+	}
+
+	if query.Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"deadlines_%s.csv\"", doc.CodiceRedazionale))
+		if err := deadlines.WriteCSV(w, found); err != nil {
+			log.Printf("Failed to write the deadlines of %s: %v", doc.CodiceRedazionale, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(found)
+}
+
+// HandleEntryIntoForce computes when a document, and where stated each of its
+// articles, enters into force, explaining how every date was derived.
+// GET /api/document/inforce?id=...&date=...&vigenza=... or ?urn=...
+func (h *Handler) HandleEntryIntoForce(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	doc, err := h.loadDocument(r.URL.Query())
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+
+	result, err := inforce.Calculate(doc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleStatus summarizes which articles and commas of a document are
+// repealed, suspended or not yet in force at its vigenza.
+// GET /api/document/status?id=...&date=...&vigenza=... or ?urn=...
+func (h *Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	doc, err := h.loadDocument(r.URL.Query())
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(doc.StatusSummary())
+}
+
+// HandleNovelle lists the amendment instructions of an amending act: what it
+// replaces, inserts, repeals or adds in other acts.
+// GET /api/document/novelle?id=...&date=...[&target=<urn>] or ?urn=...
+func (h *Handler) HandleNovelle(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	query := r.URL.Query()
+	doc, err := h.loadDocument(query)
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+
+	ops := []novella.Operation{}
+	target := document.ActURN(query.Get("target"))
+	for _, op := range novella.Extract(doc) {
+		if target == "" || document.ActURN(op.Target.URN) == target {
+			ops = append(ops, op)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ops)
+}
+
+// actRef identifies an act in a request body, as the id/date/vigenza or urn
+// query parameters do.
+type actRef struct {
+	ID      string `json:"id"`
+	Date    string `json:"date"`
+	Vigenza string `json:"vigenza"`
+	URN     string `json:"urn"`
+}
+
+func (a actRef) query() url.Values {
+	q := url.Values{}
+	for k, v := range map[string]string{"id": a.ID, "date": a.Date, "vigenza": a.Vigenza, "urn": a.URN} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	return q
+}
+
+// HandleConsolidate applies amendment operations to an act and returns the
+// draft consolidated text, with the operations that could not be applied.
+// The operations are those given in the body, or else those of the amending act
+// that target the base act. format=markdown also renders the text.
+// POST /api/document/consolidate {"base": {...}, "amending": {...}, "operations": [...]}
+func (h *Handler) HandleConsolidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Base       actRef              `json:"base"`
+		Amending   *actRef             `json:"amending"`
+		Operations []novella.Operation `json:"operations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	base, err := h.loadDocument(body.Base.query())
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+
+	ops := body.Operations
+	if ops == nil && body.Amending != nil {
+		amending, err := h.loadDocument(body.Amending.query())
+		if err != nil {
+			documentError(w, err)
+			return
+		}
+		self := document.ActURN(base.URN)
+		for _, op := range novella.Extract(amending) {
+			if document.ActURN(op.Target.URN) == self {
+				ops = append(ops, op)
+			}
+		}
+	}
+	if len(ops) == 0 {
+		http.Error(w, "No operations to apply", http.StatusBadRequest)
+		return
+	}
+
+	res, err := consolidate.Apply(base, ops)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := struct {
+		*consolidate.Result
+		Markdown string `json:"markdown,omitempty"`
+	}{Result: res}
+	if query := r.URL.Query(); query.Get("format") == "markdown" {
+		md, err := res.Document.ToMarkdownWithOptions(markdownOptions(query, document.LinkApp))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.Markdown = string(md)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ArticleModifications lists the passive modifications of one article.
+type ArticleModifications struct {
+	SectionID     string                  `json:"sectionId"`
+	Section       string                  `json:"section"`
+	Modifications []document.Modification `json:"modifications"`
+}
+
+// HandleModifications lists the modifications recorded in the AKN metadata of
+// a document: per article the acts that amended it, and the acts it amends.
+// section=<eId> returns only the modifications of one article or comma.
+// GET /api/document/modifications?id=...&date=...&vigenza=...&section=...
+func (h *Handler) HandleModifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	query := r.URL.Query()
+	doc, err := h.loadDocument(query)
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+
+	if id := query.Get("section"); id != "" {
+		mods := doc.ModificationsOf(id)
+		if mods == nil {
+			mods = []document.Modification{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(mods)
+		return
+	}
+
+	resp := struct {
+		Lifecycle []document.LifecycleEvent `json:"lifecycle"`
+		Articles  []ArticleModifications    `json:"articles"`
+		Other     []document.Modification   `json:"other"`  // passive modifications outside any article
+		Active    []document.Modification   `json:"active"` // modifications of other acts
+	}{
+		Lifecycle: doc.Lifecycle,
+		Articles:  []ArticleModifications{},
+		Other:     []document.Modification{},
+		Active:    []document.Modification{},
+	}
+	if resp.Lifecycle == nil {
+		resp.Lifecycle = []document.LifecycleEvent{}
+	}
+
+	attributed := map[string]bool{}
+	doc.Walk(func(_ []*document.DocumentSection, s *document.DocumentSection) error {
+		if s.ID == "" || !s.IsArticle() {
+			return nil
+		}
+		if mods := doc.ModificationsOf(s.ID); len(mods) > 0 {
+			resp.Articles = append(resp.Articles, ArticleModifications{SectionID: s.ID, Section: s.Title, Modifications: mods})
+			for _, m := range mods {
+				attributed[m.ID+"|"+m.Target] = true
+			}
+		}
+		return document.SkipSection
+	})
+
+	for _, m := range doc.Modifications {
+		switch {
+		case m.Direction == document.ModActive:
+			resp.Active = append(resp.Active, m)
+		case !attributed[m.ID+"|"+m.Target]:
+			resp.Other = append(resp.Other, m)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleSelect returns the sections of a document matching a selector query,
+// e.g. q=article[title~="sanzion"]; see document.Selector for the syntax.
+// GET /api/document/select?id=...&date=...&vigenza=...&q=...
+func (h *Handler) HandleSelect(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("q") == "" {
+		http.Error(w, "Missing query parameter 'q'", http.StatusBadRequest)
+		return
+	}
+	sel, err := document.ParseSelector(query.Get("q"))
+	if err != nil {
+		http.Error(w, "Invalid selector: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	doc, err := h.loadDocument(query)
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(doc.Select(sel))
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gterranova/normaplus/backend/internal/store"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// --- Citation Graph Handlers ---
+
+// HandleCitations lists the outgoing references of a document.
+// GET /api/document/citations?id=...&date=...&vigenza=... or ?urn=...
+func (h *Handler) HandleCitations(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	doc, err := h.loadDocument(r.URL.Query())
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+
+	citations := store.DocumentCitations(doc)
+	if citations == nil {
+		citations = []store.Citation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(citations)
+}
+
+// HandleCitedBy lists the references to a document (or one of its articles)
+// from every other act loaded so far.
+// GET /api/document/citedby?urn=...[&article=18], or ?id=...&date=... to look the URN up.
+func (h *Handler) HandleCitedBy(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	query := r.URL.Query()
+	urn, article, _ := document.SplitURN(query.Get("urn"))
+	if a := query.Get("article"); a != "" {
+		article = a
+	}
+
+	if urn == "" {
+		doc, err := h.loadDocument(query)
+		if err != nil {
+			documentError(w, err)
+			return
+		}
+		if doc.URN == "" {
+			http.Error(w, "The document has no URN", http.StatusUnprocessableEntity)
+			return
+		}
+		urn = doc.URN
+	}
+
+	citations, err := h.store.ListCitedBy(r.Context(), urn, article)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if citations == nil {
+		citations = []store.Citation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(citations)
+}
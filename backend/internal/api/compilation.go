@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gterranova/normaplus/backend/internal/export"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// HandleExportCompilation exports a compilation ("raccolta") of acts as one
+// file: the body lists the acts in order, each at its vigenza and optionally
+// reduced to some sections, and the query takes the options of /api/export.
+// The output has a table of contents, a title page per act, references
+// between the acts resolved within the file and an appendix of sources;
+// manifest=true adds the integrity manifest of the acts, as for /api/export.
+func (h *Handler) HandleExportCompilation(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Title string `json:"title"`
+		Acts  []struct {
+			ID       string   `json:"id"`
+			Date     string   `json:"date"`
+			Vigenza  string   `json:"vigenza"`
+			Sections []string `json:"sections"`
+		} `json:"acts"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	if len(body.Acts) == 0 {
+		http.Error(w, "Missing acts", http.StatusBadRequest)
+		return
+	}
+	if body.Title == "" {
+		body.Title = "Raccolta normativa"
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "pdf"
+	}
+	opts := export.Options{MarkdownOptions: markdownOptions(query, document.LinkStandalone), TOC: true}
+	chunks, err := chunkOptions(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Chunks = chunks
+	if !h.applyExportTemplate(w, r, query, &opts) {
+		return
+	}
+
+	acts := make([]document.CompiledAct, 0, len(body.Acts))
+	var manifest export.Manifest
+	for _, act := range body.Acts {
+		if act.ID == "" || act.Date == "" {
+			http.Error(w, "Missing id/date", http.StatusBadRequest)
+			return
+		}
+		doc, err := h.client.Fetch(act.ID, "", act.Date, act.Vigenza)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("%s: %v", act.ID, err), http.StatusInternalServerError)
+			return
+		}
+		if len(act.Sections) > 0 {
+			if doc, err = doc.Subset(act.Sections); err != nil {
+				http.Error(w, fmt.Sprintf("%s: %v", act.ID, err), http.StatusBadRequest)
+				return
+			}
+		}
+		acts = append(acts, document.CompiledAct{Document: doc, Sections: act.Sections})
+		manifest.Acts = append(manifest.Acts, export.ManifestActOf(doc, act.Sections))
+	}
+	if query.Get("manifest") == "true" {
+		opts.Manifest = &manifest
+	}
+
+	out, err := h.exportService.ExportDocument(document.Compile(body.Title, acts), format, opts)
+	if errors.Is(err, export.ErrUnsupportedFormat) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if opts.Manifest != nil {
+		if out, err = opts.Manifest.Bundle(out, "raccolta."+out.Extension); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", out.MIMEType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"raccolta.%s\"", out.Extension))
+	w.Write(out.Data)
+}
@@ -1,10 +1,14 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/gterranova/normaplus/backend/internal/ai"
 	"github.com/gterranova/normaplus/backend/internal/export"
@@ -56,30 +60,11 @@ func (h *Handler) GetDocument(w http.ResponseWriter, r *http.Request) {
 	}
 
 	query := r.URL.Query()
-	id := query.Get("id")
-	date := query.Get("date")
-	vigenza := query.Get("vigenza")
-	urn := query.Get("urn")
 	format := query.Get("format")
-	name := ""
-
-	var doc *document.Document
-	var err error
 
-	if urn != "" {
-		doc, err = h.client.FetchByURN(urn)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	} else if id != "" {
-		doc, err = h.client.Fetch(id, name, date, vigenza)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	} else {
-		http.Error(w, "Missing/wrong 'id' or 'urn' parameters", http.StatusBadRequest)
+	doc, err := h.loadDocument(query)
+	if err != nil {
+		documentError(w, err)
 		return
 	}
 
@@ -98,19 +83,99 @@ func (h *Handler) GetDocument(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(jsonBytes)
 	case "markdown":
-		md, err := doc.ToMarkdown()
+		// The viewer resolves links inside the app
+		md, err := doc.ToMarkdownWithOptions(markdownOptions(query, document.LinkApp))
 		if err != nil {
 			http.Error(w, "Conversion failed: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 		w.Header().Set("Content-Type", "text/markdown")
 		w.Write([]byte(md))
+	case "html":
+		html, err := export.HTML(doc, export.Options{MarkdownOptions: markdownOptions(query, document.LinkApp)})
+		if err != nil {
+			http.Error(w, "Conversion failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(html)
 	default:
 		http.Error(w, "Unsupported format: "+format, http.StatusBadRequest)
 		return
 	}
 }
 
+var errMissingDocument = errors.New("Missing/wrong 'id' or 'urn' parameters")
+
+// loadDocument fetches the document identified by the urn, or by the
+// id/date/vigenza query parameters.
+func (h *Handler) loadDocument(query url.Values) (*document.Document, error) {
+	if urn := query.Get("urn"); urn != "" {
+		return h.client.FetchByURN(urn)
+	}
+	if id := query.Get("id"); id != "" {
+		return h.client.Fetch(id, "", query.Get("date"), query.Get("vigenza"))
+	}
+	return nil, errMissingDocument
+}
+
+// documentError reports a loadDocument failure with the matching status code.
+func documentError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errMissingDocument) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// splitList splits a comma-separated query parameter, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// markdownOptions reads the rendering options shared by /api/document and /api/export.
+// apparatus=hide drops the (( )) amendment markers and update notes;
+// links=normattiva|app|standalone|none overrides the format's default link mode;
+// inactive=collapse|omit shortens or drops repealed, suspended and not yet in force parts.
+func markdownOptions(query url.Values, defaultLinks document.LinkMode) document.MarkdownOptions {
+	return document.MarkdownOptions{
+		HideApparatus: query.Get("apparatus") == "hide",
+		Links:         document.ParseLinkMode(query.Get("links"), defaultLinks),
+		Inactive:      document.ParseInactiveMode(query.Get("inactive")),
+	}
+}
+
+// chunkOptions reads the JSONL record options of /api/export:
+// chunk=comma|article, chunkSize and chunkOverlap in characters.
+func chunkOptions(query url.Values) (export.ChunkOptions, error) {
+	opts := export.ChunkOptions{Unit: query.Get("chunk")}
+	if opts.Unit != "" && opts.Unit != "comma" && opts.Unit != "article" {
+		return opts, fmt.Errorf("invalid chunk %q", opts.Unit)
+	}
+	for _, p := range []struct {
+		name  string
+		value *int
+	}{{"chunkSize", &opts.Size}, {"chunkOverlap", &opts.Overlap}} {
+		if v := query.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return opts, fmt.Errorf("invalid %s", p.name)
+			}
+			*p.value = n
+		}
+	}
+	if opts.Overlap > 0 && opts.Overlap >= opts.Size {
+		return opts, errors.New("chunkOverlap must be less than chunkSize")
+	}
+	return opts, nil
+}
+
 // --- User Handlers ---
 
 func (h *Handler) HandleUsers(w http.ResponseWriter, r *http.Request) {
@@ -396,7 +461,7 @@ func (h *Handler) HandleExport(w http.ResponseWriter, r *http.Request) {
 	id := query.Get("id")
 	date := query.Get("date")
 	vigenza := query.Get("vigenza")
-	format := query.Get("format") // pdf, docx, html, md
+	format := query.Get("format") // see /api/export/formats
 
 	if id == "" || date == "" {
 		http.Error(w, "Missing id/date", http.StatusBadRequest)
@@ -407,25 +472,113 @@ func (h *Handler) HandleExport(w http.ResponseWriter, r *http.Request) {
 		format = "pdf"
 	}
 
+	// Exported files can't follow app routes: keep other acts on normattiva.it
+	opts := export.Options{MarkdownOptions: markdownOptions(query, document.LinkStandalone)}
+	opts.TOC = query.Get("toc") == "true"
+	chunks, err := chunkOptions(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Chunks = chunks
+	if !h.applyExportTemplate(w, r, query, &opts) {
+		return
+	}
+
 	doc, err := h.client.Fetch(id, "", date, vigenza)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	md, err := doc.ToMarkdown()
+	// Partial export: the listed sections under their ancestor headings
+	sections := splitList(query.Get("sections"))
+	if len(sections) > 0 {
+		doc, err = doc.Subset(sections)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Integrity manifest: appended to the text and shipped as JSON in a zip
+	if query.Get("manifest") == "true" {
+		opts.Manifest = &export.Manifest{Acts: []export.ManifestAct{export.ManifestActOf(doc, sections)}}
+	}
+
+	// Annotations of the given user, re-anchored in the exported text
+	if userIDStr := query.Get("annotations"); userIDStr != "" {
+		userID, err := strconv.Atoi(userIDStr)
+		if err != nil {
+			http.Error(w, "Invalid annotations userId", http.StatusBadRequest)
+			return
+		}
+		if opts.Annotations, err = h.exportAnnotations(r.Context(), userID, doc.CodiceRedazionale); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	out, err := h.exportService.ExportDocument(doc, format, opts)
+	if errors.Is(err, export.ErrUnsupportedFormat) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Conversion failed: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	data, contentType, err := h.exportService.Export(string(md), format)
+	if opts.Manifest != nil {
+		if out, err = opts.Manifest.Bundle(out, fmt.Sprintf("document_%s.%s", id, out.Extension)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", out.MIMEType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"document_%s.%s\"", id, out.Extension))
+	w.Write(out.Data)
+}
+
+// exportAnnotations loads the annotations a user made on an act, signed with
+// the user's name.
+func (h *Handler) exportAnnotations(ctx context.Context, userID int, docID string) ([]export.Annotation, error) {
+	stored, err := h.store.ListAnnotations(ctx, userID, docID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, err
+	}
+	author := ""
+	if user, err := h.store.GetUser(ctx, userID); err == nil {
+		author = user.Name
+	}
+
+	annotations := make([]export.Annotation, 0, len(stored))
+	for _, a := range stored {
+		date := a.CreatedAt
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		annotations = append(annotations, export.Annotation{
+			ID:         a.ID,
+			Author:     author,
+			Date:       date,
+			Text:       a.SelectionData,
+			Prefix:     a.Prefix,
+			Suffix:     a.Suffix,
+			LocationID: a.LocationID,
+			Comment:    a.Comment,
+		})
+	}
+	return annotations, nil
+}
+
+// HandleExportFormats lists the formats /api/export accepts.
+func (h *Handler) HandleExportFormats(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
 		return
 	}
 
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"document_%s.%s\"", id, format))
-	w.Write(data)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.exportService.Formats())
 }
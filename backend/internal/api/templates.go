@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gterranova/normaplus/backend/internal/export"
+	"github.com/gterranova/normaplus/backend/internal/store"
+)
+
+// HandleExportTemplates manages the export templates of a user: GET lists
+// them with the shared ones, POST saves one (shared with the team when
+// "shared" is set), DELETE removes ?id=.
+func (h *Handler) HandleExportTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	ctx := r.Context()
+	userID, err := strconv.Atoi(r.URL.Query().Get("userId"))
+	if err != nil {
+		http.Error(w, "Missing or invalid userId", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		templates, err := h.store.ListExportTemplates(ctx, userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if templates == nil {
+			templates = []store.ExportTemplate{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(templates)
+
+	case "POST":
+		var body struct {
+			store.ExportTemplate
+			Shared bool `json:"shared"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid body", http.StatusBadRequest)
+			return
+		}
+		t := body.ExportTemplate
+		if t.Name == "" {
+			http.Error(w, "Missing template name", http.StatusBadRequest)
+			return
+		}
+		if err := export.CheckStylesheet(t.Stylesheet); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(t.ReferenceDocx) > 0 {
+			if err := export.CheckTemplate(t.ReferenceDocx); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		t.UserID = userID
+		if body.Shared {
+			t.UserID = 0
+		}
+		if err := h.store.SaveExportTemplate(ctx, &t); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		t.HasReference = len(t.ReferenceDocx) > 0
+		t.ReferenceDocx = nil
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t)
+
+	case "DELETE":
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "Missing or invalid id", http.StatusBadRequest)
+			return
+		}
+		if err := h.store.DeleteExportTemplate(ctx, userID, id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// applyExportTemplate sets the theme, reference document and letterhead of
+// the template named by ?template= (looked up for ?userId=) on opts. It
+// returns false after writing an error response.
+func (h *Handler) applyExportTemplate(w http.ResponseWriter, r *http.Request, query url.Values, opts *export.Options) bool {
+	name := query.Get("template")
+	if name == "" {
+		return true
+	}
+	userID, _ := strconv.Atoi(query.Get("userId"))
+	t, err := h.store.GetExportTemplate(r.Context(), userID, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if t == nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return false
+	}
+
+	opts.Stylesheet = t.Stylesheet
+	opts.Template = t.ReferenceDocx
+	opts.Branding = export.Branding{
+		FirmName: t.FirmName,
+		Header:   t.HeaderText,
+		Footer:   t.FooterText,
+		Date:     time.Now().Format("2006-01-02"),
+	}
+	return true
+}
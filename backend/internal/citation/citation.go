@@ -0,0 +1,167 @@
+package citation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Citation is a reference to an Italian act found in free text, e.g.
+// "D.L. 22 giugno 2012, n. 83" or "decreto legislativo n. 36 del 2023".
+type Citation struct {
+	Text      string `json:"text"`
+	ActType   string `json:"actType"`   // URN act type, e.g. "decreto.legge"
+	Authority string `json:"authority"` // URN authority, e.g. "stato"
+	Date      string `json:"date"`      // YYYY-MM-DD, or YYYY when only the year is cited
+	Number    string `json:"number"`
+	Article   string `json:"article,omitempty"`
+	Comma     string `json:"comma,omitempty"`
+	Start     int    `json:"-"`
+	End       int    `json:"-"`
+}
+
+var months = map[string]string{
+	"gennaio": "01", "febbraio": "02", "marzo": "03", "aprile": "04",
+	"maggio": "05", "giugno": "06", "luglio": "07", "agosto": "08",
+	"settembre": "09", "ottobre": "10", "novembre": "11", "dicembre": "12",
+}
+
+// Order matters: longer forms must be tried before their prefixes (D.Lgs. before D.L., etc.).
+var actTypes = []struct {
+	pattern   string
+	actType   string
+	authority string
+	re        *regexp.Regexp
+}{
+	{`decreto[- ]legislativo|d\.\s?lgs\.?|d\.\s?l\.\s?vo|dlgs\.?`, "decreto.legislativo", "stato", nil},
+	{`decreto[- ]legge|d\.\s?l\.|dl`, "decreto.legge", "stato", nil},
+	{`decreto del presidente della repubblica|d\.\s?p\.\s?r\.|dpr`, "decreto.del.presidente.della.repubblica", "stato", nil},
+	{`decreto del presidente del consiglio dei ministri|d\.\s?p\.\s?c\.\s?m\.|dpcm`, "decreto.del.presidente.del.consiglio.dei.ministri", "presidente.consiglio.ministri", nil},
+	{`legge costituzionale|l\.\s?cost\.`, "legge.costituzionale", "stato", nil},
+	{`regio decreto|r\.\s?d\.`, "regio.decreto", "stato", nil},
+	{`legge|l\.`, "legge", "stato", nil},
+}
+
+var (
+	actRe     *regexp.Regexp
+	articleRe = regexp.MustCompile(`(?i)\bart(?:icolo|\.)\s*(\d+[\s-]?(?:bis|ter|quater|quinquies|sexies|septies|octies|novies|decies)?)(?:\s*,\s*comma\s*(\d+[\s-]?(?:bis|ter|quater|quinquies|sexies|septies|octies|novies|decies)?))?`)
+)
+
+func init() {
+	var alts []string
+	for i, t := range actTypes {
+		alts = append(alts, "(?:"+t.pattern+")")
+		actTypes[i].re = regexp.MustCompile(`^(?:` + t.pattern + `)$`)
+	}
+	// Either "22 giugno 2012, n. 83" or "n. 36 del 2023".
+	actRe = regexp.MustCompile(`(?i)\b(` + strings.Join(alts, "|") + `)\s+` +
+		`(?:(\d{1,2})°?\s+(gennaio|febbraio|marzo|aprile|maggio|giugno|luglio|agosto|settembre|ottobre|novembre|dicembre)\s+(\d{4}),?\s+n\.\s*(\d+)` +
+		`|n\.\s*(\d+)\s+del\s+(\d{4}))`)
+}
+
+// Find returns the acts cited in text, in order of appearance.
+func Find(text string) []Citation {
+	var out []Citation
+	for _, m := range actRe.FindAllStringSubmatchIndex(text, -1) {
+		c := Citation{
+			Text:  text[m[0]:m[1]],
+			Start: m[0],
+			End:   m[1],
+		}
+		c.ActType, c.Authority = classify(text[m[2]:m[3]])
+		if m[4] >= 0 {
+			day := text[m[4]:m[5]]
+			if len(day) == 1 {
+				day = "0" + day
+			}
+			month := months[strings.ToLower(text[m[6]:m[7]])]
+			c.Date = fmt.Sprintf("%s-%s-%s", text[m[8]:m[9]], month, day)
+			c.Number = text[m[10]:m[11]]
+		} else {
+			c.Number = text[m[12]:m[13]]
+			c.Date = text[m[14]:m[15]]
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// FindArticle returns the first "art. N, comma M" reference in text.
+func FindArticle(text string) (article, comma string) {
+	m := articleRe.FindStringSubmatch(text)
+	if m == nil {
+		return "", ""
+	}
+	return normalizeNum(m[1]), normalizeNum(m[2])
+}
+
+func classify(s string) (string, string) {
+	s = strings.ToLower(s)
+	for _, t := range actTypes {
+		if t.re.MatchString(s) {
+			return t.actType, t.authority
+		}
+	}
+	return "", ""
+}
+
+// normalizeNum turns "2 bis" or "2-bis" into "2bis", the form used in URN fragments.
+func normalizeNum(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.ReplaceAll(s, "-", "")
+	return strings.ReplaceAll(s, " ", "")
+}
+
+// URN returns the Normattiva URN of the cited act. Article and Comma are not
+// part of it: they stay in their own fields.
+func (c Citation) URN() string {
+	if c.ActType == "" {
+		return ""
+	}
+	return fmt.Sprintf("urn:nir:%s:%s:%s;%s", c.Authority, c.ActType, c.Date, c.Number)
+}
+
+var shortTypes = map[string]string{
+	"decreto.legislativo":                               "D.Lgs.",
+	"decreto.legge":                                     "D.L.",
+	"decreto.del.presidente.della.repubblica":           "D.P.R.",
+	"decreto.del.presidente.del.consiglio.dei.ministri": "D.P.C.M.",
+	"legge.costituzionale":                              "L. cost.",
+	"regio.decreto":                                     "R.D.",
+	"legge":                                             "L.",
+}
+
+var urnRe = regexp.MustCompile(`^urn:nir:([^:]+):([^:]+):(\d{4}(?:-\d{2}-\d{2})?);([^~!@$#]+)`)
+
+// FromURN rebuilds the citation of the act identified by a urn:nir.
+func FromURN(urn string) (Citation, bool) {
+	m := urnRe.FindStringSubmatch(strings.ToLower(strings.TrimSpace(urn)))
+	if m == nil {
+		return Citation{}, false
+	}
+	c := Citation{Authority: m[1], ActType: m[2], Date: m[3], Number: m[4]}
+	c.Text = c.Short()
+	return c, true
+}
+
+// Short renders the citation the way Normattiva notes do, e.g. "D.Lgs. 31 marzo 2023, n. 36".
+func (c Citation) Short() string {
+	kind, ok := shortTypes[c.ActType]
+	if !ok {
+		kind = strings.ReplaceAll(c.ActType, ".", " ")
+	}
+	if len(c.Date) != 10 {
+		return fmt.Sprintf("%s n. %s del %s", kind, c.Number, c.Date)
+	}
+	day := strings.TrimPrefix(c.Date[8:], "0")
+	if day == "1" {
+		day = "1°"
+	}
+	month := ""
+	for name, num := range months {
+		if num == c.Date[5:7] {
+			month = name
+		}
+	}
+	return fmt.Sprintf("%s %s %s %s, n. %s", kind, day, month, c.Date[:4], c.Number)
+}
@@ -0,0 +1,94 @@
+package citation
+
+import "testing"
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		text                          string
+		cited                         string
+		actType, authority, date, num string
+	}{
+		{"ai sensi del D.L. 22 giugno 2012, n. 83, convertito", "D.L. 22 giugno 2012, n. 83", "decreto.legge", "stato", "2012-06-22", "83"},
+		{"il decreto legislativo n. 36 del 2023 disciplina", "decreto legislativo n. 36 del 2023", "decreto.legislativo", "stato", "2023", "36"},
+		// D.Lgs. starts like D.L. and must not be read as a decree-law
+		{"dal D.Lgs. 31 marzo 2023, n. 36", "D.Lgs. 31 marzo 2023, n. 36", "decreto.legislativo", "stato", "2023-03-31", "36"},
+		{"dal D.Lgs 31 marzo 2023, n. 36", "D.Lgs 31 marzo 2023, n. 36", "decreto.legislativo", "stato", "2023-03-31", "36"},
+		{"con D.L.vo 30 marzo 2001, n. 165", "D.L.vo 30 marzo 2001, n. 165", "decreto.legislativo", "stato", "2001-03-30", "165"},
+		{"decreto-legge 17 marzo 2020, n. 18", "decreto-legge 17 marzo 2020, n. 18", "decreto.legge", "stato", "2020-03-17", "18"},
+		{"la legge 1° dicembre 1970, n. 898", "legge 1° dicembre 1970, n. 898", "legge", "stato", "1970-12-01", "898"},
+		{"L. 7 agosto 1990, n. 241", "L. 7 agosto 1990, n. 241", "legge", "stato", "1990-08-07", "241"},
+		{"legge costituzionale 18 ottobre 2001, n. 3", "legge costituzionale 18 ottobre 2001, n. 3", "legge.costituzionale", "stato", "2001-10-18", "3"},
+		{"il D.P.R. 28 dicembre 2000, n. 445", "D.P.R. 28 dicembre 2000, n. 445", "decreto.del.presidente.della.repubblica", "stato", "2000-12-28", "445"},
+		{"il d.p.c.m. 8 marzo 2020, n. 11", "d.p.c.m. 8 marzo 2020, n. 11", "decreto.del.presidente.del.consiglio.dei.ministri", "presidente.consiglio.ministri", "2020-03-08", "11"},
+		{"regio decreto 16 marzo 1942, n. 267", "regio decreto 16 marzo 1942, n. 267", "regio.decreto", "stato", "1942-03-16", "267"},
+	}
+	for _, tt := range tests {
+		got := Find(tt.text)
+		if len(got) != 1 {
+			t.Errorf("Find(%q) = %+v, want one citation", tt.text, got)
+			continue
+		}
+		c := got[0]
+		if c.Text != tt.cited || c.ActType != tt.actType || c.Authority != tt.authority || c.Date != tt.date || c.Number != tt.num {
+			t.Errorf("Find(%q) = %+v", tt.text, c)
+		}
+		if tt.text[c.Start:c.End] != c.Text {
+			t.Errorf("Find(%q): span %d-%d is %q", tt.text, c.Start, c.End, tt.text[c.Start:c.End])
+		}
+	}
+
+	if got := Find("Il D.L. 22 giugno 2012, n. 83, convertito dalla L. 7 agosto 2012, n. 134"); len(got) != 2 || got[1].Number != "134" {
+		t.Errorf("two citations = %+v", got)
+	}
+	if got := Find("entro 30 giorni dalla legge di bilancio"); len(got) != 0 {
+		t.Errorf("no citation expected, got %+v", got)
+	}
+}
+
+func TestFindArticle(t *testing.T) {
+	tests := []struct{ text, article, comma string }{
+		{"con l'art. 13, comma 1", "13", "1"},
+		{"articolo 2-bis, comma 3 ter", "2bis", "3ter"},
+		{"art. 5", "5", ""},
+		{"nessun articolo", "", ""},
+	}
+	for _, tt := range tests {
+		if article, comma := FindArticle(tt.text); article != tt.article || comma != tt.comma {
+			t.Errorf("FindArticle(%q) = %q, %q; want %q, %q", tt.text, article, comma, tt.article, tt.comma)
+		}
+	}
+}
+
+func TestURNRoundTrip(t *testing.T) {
+	tests := []struct{ text, urn, short string }{
+		{"D.L. 22 giugno 2012, n. 83", "urn:nir:stato:decreto.legge:2012-06-22;83", "D.L. 22 giugno 2012, n. 83"},
+		{"decreto legislativo 31 marzo 2023, n. 36", "urn:nir:stato:decreto.legislativo:2023-03-31;36", "D.Lgs. 31 marzo 2023, n. 36"},
+		{"legge 1° dicembre 1970, n. 898", "urn:nir:stato:legge:1970-12-01;898", "L. 1° dicembre 1970, n. 898"},
+		{"decreto legislativo n. 36 del 2023", "urn:nir:stato:decreto.legislativo:2023;36", "D.Lgs. n. 36 del 2023"},
+	}
+	for _, tt := range tests {
+		found := Find(tt.text)
+		if len(found) != 1 {
+			t.Fatalf("Find(%q) = %+v", tt.text, found)
+		}
+		c := found[0]
+		c.Article, c.Comma = "5", "2" // not part of the URN
+		if got := c.URN(); got != tt.urn {
+			t.Errorf("URN of %q = %s, want %s", tt.text, got, tt.urn)
+		}
+		back, ok := FromURN(c.URN())
+		if !ok || back.URN() != tt.urn || back.Text != tt.short {
+			t.Errorf("FromURN(%s) = %+v, %v", tt.urn, back, ok)
+		}
+	}
+
+	if (Citation{}).URN() != "" {
+		t.Error("URN of an unclassified citation should be empty")
+	}
+	if c, ok := FromURN("urn:nir:stato:legge:1990-08-07;241~art3-com1"); !ok || c.URN() != "urn:nir:stato:legge:1990-08-07;241" {
+		t.Errorf("FromURN with fragment = %+v, %v", c, ok)
+	}
+	if _, ok := FromURN("https://eur-lex.europa.eu/eli/reg/2016/679/oj"); ok {
+		t.Error("FromURN accepted an ELI")
+	}
+}
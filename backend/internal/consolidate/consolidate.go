@@ -0,0 +1,746 @@
+package consolidate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gterranova/normaplus/backend/internal/novella"
+	"github.com/gterranova/normaplus/backend/internal/xmlparser"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// Change is an operation applied to the consolidated text.
+type Change struct {
+	Operation novella.Operation `json:"operation"`
+	SectionID string            `json:"sectionId"`
+	Note      int               `json:"note"` // number of the update note recording the change
+}
+
+// Issue is an operation that was not applied, and why.
+type Issue struct {
+	Operation novella.Operation `json:"operation"`
+	Reason    string            `json:"reason"`
+}
+
+// Result is a draft consolidated text and the outcome of each operation.
+type Result struct {
+	Document  *document.Document `json:"document"`
+	Applied   []Change           `json:"applied"`
+	Failed    []Issue            `json:"failed"`
+	Ambiguous []Issue            `json:"ambiguous"`
+}
+
+// ambiguousError reports an operation matching more than one place.
+type ambiguousError struct{ reason string }
+
+func (e *ambiguousError) Error() string { return e.reason }
+
+func ambiguous(format string, args ...any) error {
+	return &ambiguousError{fmt.Sprintf(format, args...)}
+}
+
+var (
+	blockNumRe  = regexp.MustCompile(`^[\(\s]*(\d+(?:[\s-]?[a-z]+)?)\\?\.\s`)
+	letterRe    = regexp.MustCompile(`(?m)^[\(\s]*([a-z]{1,2}(?:-[a-z]+)?)\)\s`)
+	numberRe    = regexp.MustCompile(`(?m)^[\(\s]*(\d+(?:-[a-z]+)?)\)\s`)
+	headerRe    = regexp.MustCompile(`(?is)^\(*\s*art(?:icolo|\.)\s*(\d+(?:[\s-]?[a-z]+)?)\s*\.?\s*(?:\(([^)]*)\)|[-–]\s*([^\n]*?)(?:\n|$))?[\s.\-–]*`)
+	newCommaRe  = regexp.MustCompile(`(?:^|\s)(\d+)(?:[\s-]?([a-z]+))?\.\s+`)
+	sentenceEnd = regexp.MustCompile(`[.;]\s+`)
+)
+
+// Apply applies ops to a copy of base. Every change is wrapped in (( )) and
+// recorded in an update note citing the amending act, as Normattiva does.
+// Operations that cannot be located, or that match several places, are
+// reported instead of applied.
+func Apply(base *document.Document, ops []novella.Operation) (*Result, error) {
+	doc, err := clone(base)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Document: doc, Applied: []Change{}, Failed: []Issue{}, Ambiguous: []Issue{}}
+	self := document.ActURN(base.URN)
+	for _, op := range ops {
+		if act := document.ActURN(op.Target.URN); act != "" && self != "" && act != self {
+			res.Failed = append(res.Failed, Issue{op, "the operation amends another act: " + act})
+			continue
+		}
+
+		section, err := apply(doc, op)
+		var amb *ambiguousError
+		switch {
+		case errors.As(err, &amb):
+			res.Ambiguous = append(res.Ambiguous, Issue{op, err.Error()})
+		case err != nil:
+			res.Failed = append(res.Failed, Issue{op, err.Error()})
+		default:
+			res.Applied = append(res.Applied, Change{Operation: op, SectionID: section.ID, Note: lastNote(section)})
+		}
+	}
+
+	xmlparser.Reindex(doc)
+	return res, nil
+}
+
+func clone(d *document.Document) (*document.Document, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var c document.Document
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// apply edits doc and returns the section holding the change.
+func apply(doc *document.Document, op novella.Operation) (*document.DocumentSection, error) {
+	if op.Unit == novella.UnitArticle {
+		return applyArticle(doc, op)
+	}
+
+	if op.Target.Article == "" {
+		return nil, errors.New("the target article is not stated")
+	}
+	art := findArticle(doc, op.Target.Article)
+	if art == nil {
+		return nil, fmt.Errorf("article %s not found", op.Target.Article)
+	}
+
+	note := addNote(art, op)
+	var err error
+	if op.Unit == novella.UnitComma {
+		err = applyComma(art, op, note)
+	} else {
+		err = applyText(art, op, note)
+	}
+	if err != nil {
+		art.Updates = art.Updates[:len(art.Updates)-1]
+		return nil, err
+	}
+	return art, nil
+}
+
+// --- Articles ---
+
+func applyArticle(doc *document.Document, op novella.Operation) (*document.DocumentSection, error) {
+	switch op.Type {
+	case novella.OpReplace, novella.OpRepeal:
+		n := op.Target.Article
+		if n == "" {
+			n = op.Units
+		}
+		art := findArticle(doc, n)
+		if art == nil {
+			return nil, fmt.Errorf("article %s not found", n)
+		}
+		note := addNote(art, op)
+		if op.Type == novella.OpRepeal {
+			art.Content = []string{fmt.Sprintf("((ARTICOLO ABROGATO %s)) %s", sourceUpper(op), marker(note))}
+			return art, nil
+		}
+		_, heading, blocks := parseArticle(op.New)
+		if heading != "" {
+			art.Title = strings.TrimSuffix(strings.SplitN(art.Title, " - ", 2)[0], ".") + " - " + heading
+		}
+		art.Content = markBlocks(blocks, note)
+		return art, nil
+
+	case novella.OpInsert, novella.OpAdd:
+		num, heading, blocks := parseArticle(op.New)
+		if num == "" {
+			return nil, errors.New("the new article has no number")
+		}
+		if findArticle(doc, normalize(num)) != nil {
+			return nil, fmt.Errorf("article %s already exists", num)
+		}
+
+		var siblings *[]document.DocumentSection
+		var at int
+		if op.Type == novella.OpInsert {
+			siblings, at = parentOf(doc, findArticle(doc, op.After))
+			if siblings == nil {
+				return nil, fmt.Errorf("article %s not found", op.After)
+			}
+		} else {
+			siblings, at = parentOf(doc, lastArticle(doc))
+			if siblings == nil {
+				return nil, errors.New("the act has no articles")
+			}
+		}
+
+		art := document.DocumentSection{ID: "art_" + normalize(num), Type: "article", Title: "Art. " + num}
+		if heading != "" {
+			art.Title += " - " + heading
+		}
+		note := addNote(&art, op)
+		art.Content = markBlocks(blocks, note)
+
+		*siblings = append((*siblings)[:at+1], append([]document.DocumentSection{art}, (*siblings)[at+1:]...)...)
+		return &(*siblings)[at+1], nil
+	}
+	return nil, fmt.Errorf("unsupported operation %s on an article", op.Type)
+}
+
+// parseArticle splits the text of a new article into number, heading and commas.
+func parseArticle(text string) (num, heading string, blocks []string) {
+	text = strings.TrimSpace(text)
+	if m := headerRe.FindStringSubmatch(text); m != nil {
+		num = strings.TrimSpace(m[1])
+		heading = strings.TrimSpace(m[2] + m[3])
+		text = text[len(m[0]):]
+	}
+	return num, heading, splitCommas(text)
+}
+
+// splitCommas splits provision text at comma numbers following each other
+// (1., 2., 2-bis., 3.), so that "n. 36. La" is not taken for a comma.
+func splitCommas(text string) []string {
+	var blocks []string
+	start, expected := 0, 1
+	for _, m := range newCommaRe.FindAllStringSubmatchIndex(text, -1) {
+		n, _ := strconv.Atoi(text[m[2]:m[3]])
+		next, _ := utf8.DecodeRuneInString(text[m[1]:])
+		suffixed := m[4] >= 0
+		if !unicode.IsUpper(next) || !(n == expected || suffixed && n == expected-1) {
+			continue
+		}
+		if strings.TrimSpace(text[start:m[0]]) != "" {
+			blocks = append(blocks, strings.TrimSpace(text[start:m[0]]))
+		}
+		start = m[0]
+		if !suffixed {
+			expected = n + 1
+		}
+	}
+	if rest := strings.TrimSpace(text[start:]); rest != "" {
+		blocks = append(blocks, rest)
+	}
+	return blocks
+}
+
+// markBlocks renders new provisions the way Normattiva shows amended text:
+// the comma number escaped, the text in (( )), followed by the note marker.
+func markBlocks(blocks []string, note int) []string {
+	out := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		b = strings.TrimSpace(b)
+		num := ""
+		if m := newCommaRe.FindStringSubmatch(b); m != nil && strings.HasPrefix(b, m[1]) {
+			num = strings.TrimSuffix(strings.TrimSpace(b[:len(m[0])]), ".")
+			b = strings.TrimSpace(b[len(m[0]):])
+		}
+		b = strings.ReplaceAll(b, "\n", "\n\n")
+		if num != "" {
+			out = append(out, fmt.Sprintf("%s\\. ((%s)) %s", num, b, marker(note)))
+		} else {
+			out = append(out, fmt.Sprintf("((%s)) %s", b, marker(note)))
+		}
+	}
+	return out
+}
+
+// --- Commas ---
+
+func applyComma(art *document.DocumentSection, op novella.Operation, note int) error {
+	switch op.Type {
+	case novella.OpRepeal:
+		start, end, err := commaRange(art, op.Target.Comma)
+		if err != nil {
+			return err
+		}
+		block := fmt.Sprintf("%s\\. ((COMMA ABROGATO %s)) %s", displayNum(art.Content[start]), sourceUpper(op), marker(note))
+		art.Content = splice(art.Content, start, end, block)
+
+	case novella.OpReplace:
+		units := strings.Split(op.Units, ", ")
+		if op.Units == "" {
+			units = []string{op.Target.Comma}
+		}
+		start, _, err := commaRange(art, units[0])
+		if err != nil {
+			return err
+		}
+		_, end, err := commaRange(art, units[len(units)-1])
+		if err != nil {
+			return err
+		}
+		art.Content = splice(art.Content, start, end, markBlocks(splitCommas(op.New), note)...)
+
+	case novella.OpInsert:
+		_, end, err := commaRange(art, op.After)
+		if err != nil {
+			return err
+		}
+		art.Content = splice(art.Content, end, end, markBlocks(splitCommasAny(op.New), note)...)
+
+	case novella.OpAdd:
+		end := len(art.Content)
+		if op.Target.Comma != "" {
+			// "al comma 2, è aggiunto il seguente comma": a new comma after 2
+			var err error
+			if _, end, err = commaRange(art, op.Target.Comma); err != nil {
+				return err
+			}
+		}
+		art.Content = splice(art.Content, end, end, markBlocks(splitCommasAny(op.New), note)...)
+
+	default:
+		return fmt.Errorf("unsupported operation %s on a comma", op.Type)
+	}
+	return nil
+}
+
+// splitCommasAny splits inserted commas, which start at any number ("3-bis.").
+func splitCommasAny(text string) []string {
+	var blocks []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if len(blocks) > 0 && !blockNumRe.MatchString(line) {
+			blocks[len(blocks)-1] += "\n" + line
+			continue
+		}
+		blocks = append(blocks, line)
+	}
+	return blocks
+}
+
+// commaRange returns the content blocks [start, end) of comma n: the block
+// numbered n and the unnumbered blocks (lists, tables) following it.
+func commaRange(art *document.DocumentSection, n string) (int, int, error) {
+	start := -1
+	for i, block := range art.Content {
+		m := blockNumRe.FindStringSubmatch(block)
+		if m == nil {
+			continue
+		}
+		if start >= 0 {
+			return start, i, nil
+		}
+		if normalize(m[1]) == n {
+			start = i
+		}
+	}
+	if start < 0 {
+		return 0, 0, fmt.Errorf("comma %s not found in %s", n, art.Title)
+	}
+	return start, len(art.Content), nil
+}
+
+func displayNum(block string) string {
+	if m := blockNumRe.FindStringSubmatch(block); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func splice(content []string, start, end int, blocks ...string) []string {
+	out := append([]string{}, content[:start]...)
+	out = append(out, blocks...)
+	return append(out, content[end:]...)
+}
+
+// --- Text within a comma ---
+
+// segment is a byte range of one content block.
+type segment struct{ block, start, end int }
+
+func applyText(art *document.DocumentSection, op novella.Operation, note int) error {
+	segs, err := scope(art, op.Target)
+	if err != nil {
+		return err
+	}
+
+	switch op.Unit {
+	case novella.UnitWords:
+		return applyWords(art, segs, op, note)
+	case novella.UnitLetter, novella.UnitNumber, novella.UnitPeriod:
+		return applyItem(art, segs, op, note)
+	}
+	return fmt.Errorf("unsupported unit %s", op.Unit)
+}
+
+// scope returns the text the target points to: the whole article, a comma,
+// a letter or number of a list, or one period.
+func scope(art *document.DocumentSection, t novella.Target) ([]segment, error) {
+	var segs []segment
+	if t.Comma == "" {
+		for i, block := range art.Content {
+			segs = append(segs, segment{i, 0, len(block)})
+		}
+	} else {
+		start, end, err := commaRange(art, t.Comma)
+		if err != nil {
+			return nil, err
+		}
+		for i := start; i < end; i++ {
+			segs = append(segs, segment{i, 0, len(art.Content[i])})
+		}
+	}
+	if len(segs) == 0 {
+		return nil, errors.New("the target has no text")
+	}
+
+	var err error
+	if t.Letter != "" {
+		if segs, err = item(art, segs, letterRe, t.Letter, "letter"); err != nil {
+			return nil, err
+		}
+	}
+	if t.Number != "" {
+		if segs, err = item(art, segs, numberRe, t.Number, "number"); err != nil {
+			return nil, err
+		}
+	}
+	if t.Period != "" {
+		if len(segs) != 1 {
+			return nil, ambiguous("period %s: the target spans %d blocks", t.Period, len(segs))
+		}
+		seg, err := period(art.Content[segs[0].block], segs[0], t.Period)
+		if err != nil {
+			return nil, err
+		}
+		segs = []segment{seg}
+	}
+	return segs, nil
+}
+
+// item narrows segs to the list item labeled n ("b)" or "2)").
+func item(art *document.DocumentSection, segs []segment, re *regexp.Regexp, n, kind string) ([]segment, error) {
+	var found []segment
+	for _, seg := range segs {
+		text := art.Content[seg.block][seg.start:seg.end]
+		matches := re.FindAllStringSubmatchIndex(text, -1)
+		for j, m := range matches {
+			if normalize(text[m[2]:m[3]]) != n {
+				continue
+			}
+			end := len(text)
+			if j+1 < len(matches) {
+				end = matches[j+1][0]
+			}
+			end = m[0] + len(strings.TrimRight(text[m[0]:end], "\n "))
+			found = append(found, segment{seg.block, seg.start + m[0], seg.start + end})
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("%s %s) not found", kind, n)
+	case 1:
+		return found, nil
+	}
+	return nil, ambiguous("%s %s) occurs %d times", kind, n, len(found))
+}
+
+// period narrows seg to its n-th sentence ("last" for the last one).
+func period(text string, seg segment, n string) (segment, error) {
+	body := seg.start
+	if m := blockNumRe.FindStringIndex(text[seg.start:seg.end]); m != nil {
+		body += m[1]
+	}
+	var bounds [][2]int
+	from := body
+	for _, m := range sentenceEnd.FindAllStringIndex(text[body:seg.end], -1) {
+		bounds = append(bounds, [2]int{from, body + m[0] + 1})
+		from = body + m[1]
+	}
+	if from < seg.end {
+		bounds = append(bounds, [2]int{from, seg.end})
+	}
+	if len(bounds) == 0 {
+		return segment{}, fmt.Errorf("period %s not found: the text is empty", n)
+	}
+
+	i := len(bounds) - 1
+	if n != "last" {
+		k, err := strconv.Atoi(n)
+		if err != nil || k < 1 || k > len(bounds) {
+			return segment{}, fmt.Errorf("period %s not found", n)
+		}
+		i = k - 1
+	}
+	return segment{seg.block, bounds[i][0], bounds[i][1]}, nil
+}
+
+func applyWords(art *document.DocumentSection, segs []segment, op novella.Operation, note int) error {
+	if op.Type == novella.OpAdd {
+		seg := segs[len(segs)-1]
+		text := art.Content[seg.block]
+		at := seg.end
+		for at > seg.start && strings.ContainsRune(".;: \n", rune(text[at-1])) {
+			at--
+		}
+		art.Content[seg.block] = text[:at] + joinInsert(op.New, note) + text[at:]
+		return nil
+	}
+
+	needle := op.Old
+	if op.Type == novella.OpInsert {
+		needle = op.After
+	}
+	if needle == "" {
+		return errors.New("the words to look for are not stated")
+	}
+
+	type hit struct{ block, at int }
+	var hits []hit
+	for _, seg := range segs {
+		text := art.Content[seg.block][seg.start:seg.end]
+		for from := 0; ; {
+			i := strings.Index(text[from:], needle)
+			if i < 0 {
+				break
+			}
+			hits = append(hits, hit{seg.block, seg.start + from + i})
+			from += i + len(needle)
+		}
+	}
+	switch {
+	case len(hits) == 0:
+		return fmt.Errorf("«%s» not found in %s", needle, targetLabel(op))
+	case len(hits) > 1 && !op.Global:
+		return ambiguous("«%s» occurs %d times in %s", needle, len(hits), targetLabel(op))
+	}
+
+	// Edit from the last hit so earlier offsets stay valid
+	for i := len(hits) - 1; i >= 0; i-- {
+		h := hits[i]
+		text := art.Content[h.block]
+		end := h.at + len(needle)
+		switch op.Type {
+		case novella.OpReplace:
+			text = text[:h.at] + fmt.Sprintf("((%s)) %s", op.New, marker(note)) + text[end:]
+		case novella.OpRepeal:
+			// Normattiva leaves an empty (( )) where the words were
+			text = text[:h.at] + fmt.Sprintf("(( )) %s", marker(note)) + text[end:]
+		case novella.OpInsert:
+			text = text[:end] + joinInsert(op.New, note) + text[end:]
+		}
+		art.Content[h.block] = text
+	}
+	return nil
+}
+
+// joinInsert renders inserted words, with a leading space unless they start
+// with punctuation.
+func joinInsert(words string, note int) string {
+	sep := " "
+	if words != "" && strings.ContainsRune(",.;:)", rune(words[0])) {
+		sep = ""
+	}
+	return fmt.Sprintf("%s((%s)) %s", sep, words, marker(note))
+}
+
+// applyItem replaces, repeals or inserts letters, numbers and periods.
+func applyItem(art *document.DocumentSection, segs []segment, op novella.Operation, note int) error {
+	if op.Type == novella.OpInsert || op.Type == novella.OpAdd {
+		var at segment
+		switch {
+		case op.After != "":
+			t := op.Target
+			switch op.Unit {
+			case novella.UnitLetter:
+				t.Letter = op.After
+			case novella.UnitNumber:
+				t.Number = op.After
+			case novella.UnitPeriod:
+				t.Period = op.After
+			}
+			found, err := scope(art, t)
+			if err != nil {
+				return err
+			}
+			at = found[len(found)-1]
+		default:
+			at = segs[len(segs)-1]
+		}
+		text := art.Content[at.block]
+		sep := "\n\n"
+		if op.Unit == novella.UnitPeriod {
+			sep = " "
+		}
+		art.Content[at.block] = text[:at.end] + sep + fmt.Sprintf("((%s)) %s", op.New, marker(note)) + text[at.end:]
+		return nil
+	}
+
+	if len(segs) != 1 {
+		return ambiguous("the target spans %d blocks", len(segs))
+	}
+	seg := segs[0]
+	text := art.Content[seg.block]
+	label := ""
+	if op.Unit != novella.UnitPeriod {
+		re := letterRe
+		if op.Unit == novella.UnitNumber {
+			re = numberRe
+		}
+		if m := re.FindStringIndex(text[seg.start:seg.end]); m != nil {
+			label = strings.TrimLeft(text[seg.start:seg.start+m[1]], "( ")
+		}
+	}
+
+	var replacement string
+	switch op.Type {
+	case novella.OpRepeal:
+		if op.Unit == novella.UnitPeriod {
+			replacement = fmt.Sprintf("(( )) %s", marker(note))
+		} else {
+			noun := map[string]string{novella.UnitLetter: "LETTERA ABROGATA", novella.UnitNumber: "NUMERO ABROGATO"}[op.Unit]
+			replacement = fmt.Sprintf("%s((%s %s)) %s", label, noun, sourceUpper(op), marker(note))
+		}
+	case novella.OpReplace:
+		replacement = fmt.Sprintf("((%s)) %s", op.New, marker(note))
+	default:
+		return fmt.Errorf("unsupported operation %s on a %s", op.Type, op.Unit)
+	}
+	art.Content[seg.block] = text[:seg.start] + replacement + text[seg.end:]
+	return nil
+}
+
+// --- Notes ---
+
+// addNote records op in an update note of s and returns its number.
+func addNote(s *document.DocumentSection, op novella.Operation) int {
+	n := 1
+	for _, u := range s.Updates {
+		if u.Number >= n {
+			n = u.Number + 1
+		}
+	}
+
+	source := op.Source.Text
+	if source == "" {
+		source = "L'atto modificativo"
+	}
+	with := ""
+	if op.Source.Article != "" {
+		with = " (con l'art. " + op.Source.Article
+		if op.Source.Comma != "" {
+			with += ", comma " + op.Source.Comma
+		}
+		with += ")"
+	}
+	what := map[string]string{
+		novella.OpReplace: "la modifica",
+		novella.OpRepeal:  "l'abrogazione",
+		novella.OpInsert:  "l'introduzione",
+		novella.OpAdd:     "l'introduzione",
+	}[op.Type]
+	if op.Target.Path != "" {
+		if op.Type == novella.OpInsert || op.Type == novella.OpAdd {
+			what += " nell'" + op.Target.Path
+		} else {
+			what += " dell'" + op.Target.Path
+		}
+	}
+
+	s.Updates = append(s.Updates, document.UpdateNote{
+		Number:   n,
+		Heading:  fmt.Sprintf("AGGIORNAMENTO (%d)", n),
+		Text:     fmt.Sprintf("%s ha disposto%s %s.", source, with, what),
+		Amending: []document.Reference{op.Source},
+	})
+	return n
+}
+
+func lastNote(s *document.DocumentSection) int {
+	if len(s.Updates) == 0 {
+		return 0
+	}
+	return s.Updates[len(s.Updates)-1].Number
+}
+
+func marker(note int) string {
+	return fmt.Sprintf("((%d))", note)
+}
+
+// sourceUpper renders "DAL D.LGS. ..." for the repeal markers, which
+// Normattiva writes in capitals.
+func sourceUpper(op novella.Operation) string {
+	text := op.Source.Text
+	switch {
+	case text == "":
+		return "DALL'ATTO MODIFICATIVO"
+	case strings.HasPrefix(strings.ToLower(text), "legge"):
+		return "DALLA " + strings.ToUpper(text)
+	}
+	return "DAL " + strings.ToUpper(text)
+}
+
+func targetLabel(op novella.Operation) string {
+	if op.Target.Path == "" {
+		return "the act"
+	}
+	return op.Target.Path
+}
+
+// --- Lookup ---
+
+func findArticle(doc *document.Document, n string) *document.DocumentSection {
+	id, ok := doc.ArticleAnchors()[n]
+	if !ok {
+		return nil
+	}
+	var found *document.DocumentSection
+	doc.Walk(func(_ []*document.DocumentSection, s *document.DocumentSection) error {
+		if s.ID == id {
+			found = s
+			return errFound
+		}
+		return nil
+	})
+	return found
+}
+
+// errFound stops a Walk once the section looked for is found.
+var errFound = errors.New("found")
+
+// parentOf returns the slice holding s and its index in it.
+func parentOf(doc *document.Document, s *document.DocumentSection) (*[]document.DocumentSection, int) {
+	if s == nil {
+		return nil, 0
+	}
+	var siblings *[]document.DocumentSection
+	var at int
+	doc.Walk(func(path []*document.DocumentSection, c *document.DocumentSection) error {
+		if c != s {
+			return nil
+		}
+		siblings = &doc.Sections
+		if len(path) > 0 {
+			siblings = &path[len(path)-1].Children
+		}
+		for i := range *siblings {
+			if &(*siblings)[i] == s {
+				at = i
+			}
+		}
+		return errFound
+	})
+	return siblings, at
+}
+
+func lastArticle(doc *document.Document) *document.DocumentSection {
+	var last *document.DocumentSection
+	doc.Walk(func(_ []*document.DocumentSection, s *document.DocumentSection) error {
+		if s.IsArticle() {
+			last = s
+		}
+		return nil
+	})
+	return last
+}
+
+func normalize(n string) string {
+	n = strings.ToLower(strings.TrimSpace(n))
+	return strings.NewReplacer("-", "", " ", "").Replace(n)
+}
@@ -0,0 +1,144 @@
+package consolidate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/internal/novella"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func TestApply(t *testing.T) {
+	base := document.Document{
+		URN: "urn:nir:stato:decreto.legislativo:2023-03-31;36",
+		Sections: []document.DocumentSection{
+			{ID: "art_5", Type: "article", Title: "Art. 5 - Termini", Content: []string{
+				"1\\. Il termine è fissato dal bando.",
+				"2\\. Il ricorso è proposto entro trenta giorni dalla notifica. Il giudice decide entro il termine di trenta giorni.",
+			}},
+			{ID: "art_7", Type: "article", Title: "Art. 7 - Pubblicità", Content: []string{
+				"1\\. Gli atti sono pubblicati esclusivamente sul sito.",
+				"2\\. La pubblicazione è gratuita.",
+				"3\\. I dati sono aggiornati.",
+				"4\\. Gli atti sono conservati.",
+			}},
+			{ID: "art_9", Type: "article", Title: "Art. 9 - Vigilanza", Content: []string{"1\\. La vigilanza spetta all'ANAC."}},
+		},
+	}
+	amending := document.Document{
+		URN:   "urn:nir:stato:decreto.legislativo:2024-12-31;209",
+		Title: "DECRETO LEGISLATIVO 31 dicembre 2024, n. 209",
+		Sections: []document.DocumentSection{{
+			ID:    "art_1",
+			Type:  "article",
+			Title: "Art. 1 - Modifiche al decreto legislativo 31 marzo 2023, n. 36",
+			Content: []string{
+				"1\\. Al decreto legislativo 31 marzo 2023, n. 36, sono apportate le seguenti modificazioni:\n\na) all'articolo 5, comma 2, le parole «proposto entro trenta giorni» sono sostituite dalle seguenti: «proposto entro sessanta giorni»;\n\nb) all'articolo 7:\n\n  1) al comma 1, la parola «esclusivamente» è soppressa;\n\n  2) dopo il comma 3 è inserito il seguente: «3-bis. Le stazioni appaltanti pubblicano gli atti.»;\n\n  3) il comma 4 è abrogato;\n\nc) dopo l'articolo 9 è inserito il seguente:\n«Art. 9-bis (Controlli). - 1. I controlli sono svolti dall'ANAC.»;\n\nd) all'articolo 12, comma 1, la parola «sempre» è soppressa.",
+				"2\\. L'articolo 3 della legge 7 agosto 1990, n. 241, è sostituito dal seguente: «Art. 3. (Motivazione) 1. Ogni provvedimento è motivato.».",
+			},
+		}},
+	}
+
+	ops := novella.Extract(&amending)
+	// Not found in the base text, and found twice
+	ops = append(ops,
+		novella.Operation{Type: novella.OpReplace, Unit: novella.UnitWords, Target: novella.Target{Article: "5", Comma: "1", Path: "art. 5, comma 1"}, Old: "dal regolamento", New: "dalla legge"},
+		novella.Operation{Type: novella.OpReplace, Unit: novella.UnitWords, Target: novella.Target{Article: "5", Comma: "2", Path: "art. 5, comma 2"}, Old: "entro", New: "non oltre"},
+	)
+
+	res, err := Apply(&base, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Applied) != 5 || len(res.Failed) != 3 || len(res.Ambiguous) != 1 {
+		t.Fatalf("applied %d, failed %d, ambiguous %d: %+v %+v", len(res.Applied), len(res.Failed), len(res.Ambiguous), res.Failed, res.Ambiguous)
+	}
+
+	doc := res.Document
+	art5 := doc.Sections[0]
+	if want := "2\\. Il ricorso è ((proposto entro sessanta giorni)) ((1)) dalla notifica. Il giudice decide entro il termine di trenta giorni."; art5.Content[1] != want {
+		t.Errorf("art. 5, comma 2 = %q", art5.Content[1])
+	}
+	if len(art5.Updates) != 1 || !strings.HasPrefix(art5.Updates[0].Text, "D.Lgs. 31 dicembre 2024, n. 209 ha disposto (con l'art. 1, comma 1) la modifica dell'art. 5, comma 2") {
+		t.Errorf("unexpected update note %+v", art5.Updates)
+	}
+
+	art7 := doc.Sections[1]
+	want := []string{
+		"1\\. Gli atti sono pubblicati (( )) ((1)) sul sito.",
+		"2\\. La pubblicazione è gratuita.",
+		"3\\. I dati sono aggiornati.",
+		"3-bis\\. ((Le stazioni appaltanti pubblicano gli atti.)) ((2))",
+		"4\\. ((COMMA ABROGATO DAL D.LGS. 31 DICEMBRE 2024, N. 209)) ((3))",
+	}
+	if strings.Join(art7.Content, "|") != strings.Join(want, "|") {
+		t.Errorf("art. 7 = %q", art7.Content)
+	}
+	if art7.CommaStatus == nil || art7.CommaStatus[0].Comma != "4" {
+		t.Errorf("expected comma 4 marked repealed, got %+v", art7.CommaStatus)
+	}
+
+	if len(doc.Sections) != 4 || doc.Sections[3].ID != "art_9bis" || doc.Sections[3].Title != "Art. 9-bis - Controlli" {
+		t.Fatalf("expected art. 9-bis after art. 9, got %+v", doc.Sections[len(doc.Sections)-1])
+	}
+	if got := doc.Sections[3].Content[0]; got != "1\\. ((I controlli sono svolti dall'ANAC.)) ((1))" {
+		t.Errorf("art. 9-bis = %q", got)
+	}
+
+	// The base document is left untouched
+	if base.Sections[0].Content[1] == art5.Content[1] || len(base.Sections) != 3 {
+		t.Error("Apply modified the base document")
+	}
+}
+
+func TestApplyRepealMarkers(t *testing.T) {
+	base := document.Document{Sections: []document.DocumentSection{
+		{ID: "art_5", Type: "article", Title: "Art. 5", Content: []string{
+			"1\\. Il termine è fissato dal bando di gara.",
+			"2\\. Il ricorso è proposto entro trenta giorni. Il giudice decide entro sessanta giorni.",
+		}},
+	}}
+	ops := []novella.Operation{
+		{Type: novella.OpRepeal, Unit: novella.UnitWords, Target: novella.Target{Article: "5", Comma: "1", Path: "art. 5, comma 1"}, Old: "di gara"},
+		{Type: novella.OpRepeal, Unit: novella.UnitPeriod, Target: novella.Target{Article: "5", Comma: "2", Period: "2", Path: "art. 5, comma 2"}},
+	}
+
+	res, err := Apply(&base, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Applied) != 2 {
+		t.Fatalf("applied %d: %+v", len(res.Applied), res.Failed)
+	}
+	want := []string{
+		"1\\. Il termine è fissato dal bando (( )) ((1)).",
+		"2\\. Il ricorso è proposto entro trenta giorni. (( )) ((2))",
+	}
+	if got := res.Document.Sections[0].Content; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("art. 5 = %q", got)
+	}
+}
+
+func TestApplyEmptyTargets(t *testing.T) {
+	base := document.Document{Sections: []document.DocumentSection{
+		{ID: "art_4", Type: "article", Title: "Art. 4"},
+		{ID: "art_5", Type: "article", Title: "Art. 5", Content: []string{"1\\. "}},
+	}}
+	ops := []novella.Operation{
+		{Type: novella.OpAdd, Unit: novella.UnitWords, Target: novella.Target{Article: "4", Path: "art. 4"}, New: "e dei regolamenti"},
+		{Type: novella.OpReplace, Unit: novella.UnitPeriod, Target: novella.Target{Article: "5", Comma: "1", Period: "last", Path: "art. 5, comma 1"}, New: "Il termine è di trenta giorni."},
+	}
+
+	res, err := Apply(&base, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Applied) != 0 || len(res.Failed) != 2 {
+		t.Fatalf("applied %d, failed %d: %+v", len(res.Applied), len(res.Failed), res.Failed)
+	}
+	for _, issue := range res.Failed {
+		if issue.Reason == "" {
+			t.Errorf("no reason for %+v", issue)
+		}
+	}
+}
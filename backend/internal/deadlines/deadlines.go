@@ -0,0 +1,293 @@
+package deadlines
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gterranova/normaplus/backend/internal/citation"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// Deadline is a time expression found in the text of an act, such as
+// "entro trenta giorni dalla data di entrata in vigore" or "entro il 31 dicembre 2025".
+type Deadline struct {
+	Text         string `json:"text"`
+	Kind         string `json:"kind"`               // "duration" or "date"
+	Relation     string `json:"relation,omitempty"` // within, after, before, from, until, for
+	Amount       int    `json:"amount,omitempty"`
+	Unit         string `json:"unit,omitempty"` // hour, day, week, month, year
+	BusinessDays bool   `json:"businessDays,omitempty"`
+	Duration     string `json:"duration,omitempty"` // ISO 8601, e.g. P30D
+	Date         string `json:"date,omitempty"`     // YYYY-MM-DD
+	Anchor       string `json:"anchor,omitempty"`   // what a duration runs from
+	AnchorType   string `json:"anchorType,omitempty"`
+	SectionID    string `json:"sectionId"`
+	Section      string `json:"section"`
+	Comma        string `json:"comma,omitempty"`
+	Context      string `json:"context"`
+}
+
+const (
+	KindDuration = "duration"
+	KindDate     = "date"
+)
+
+var units = map[string]struct{ name, iso string }{
+	"or": {"hour", "H"}, "giorn": {"day", "D"}, "settiman": {"week", "W"},
+	"mes": {"month", "M"}, "ann": {"year", "Y"},
+}
+
+var months = map[string]time.Month{
+	"gennaio": time.January, "febbraio": time.February, "marzo": time.March,
+	"aprile": time.April, "maggio": time.May, "giugno": time.June,
+	"luglio": time.July, "agosto": time.August, "settembre": time.September,
+	"ottobre": time.October, "novembre": time.November, "dicembre": time.December,
+}
+
+var (
+	// trenta giorni, 30 giorni, novanta (90) giorni, il quindicesimo giorno
+	durationRe = regexp.MustCompile(`(?i)\b(\d{1,4}|[a-zàèéìòù]+)\s*(?:\(\s*\d+\s*\)\s*)?\b(ore|giorn[oi]|settiman[ae]|mes[ei]|ann[oi])\b(?:\s+(lavorativ[io]|naturali(?:\s+e\s+consecutivi)?|consecutivi))?`)
+	// 31 dicembre 2025, 1° gennaio 2024, 31/12/2025
+	dateRe = regexp.MustCompile(`(?i)\b(\d{1,2}|primo)\s*[°º]?\s+(gennaio|febbraio|marzo|aprile|maggio|giugno|luglio|agosto|settembre|ottobre|novembre|dicembre)\s+(\d{4})\b|\b(\d{1,2})/(\d{1,2})/(\d{4})\b`)
+	// The wording just before an expression, e.g. "entro il termine di"
+	relationRe = regexp.MustCompile(`(?i)\b(entro(?:\s+e\s+non\s+oltre)?|non\s+oltre|nel\s+termine(?:\s+massimo|\s+perentorio)?\s+di|decors[io]|trascors[io]|dopo|oltre|a\s+decorrere\s+da(?:l|lla|ll')?|a\s+partire\s+da(?:l|lla|ll')?|con\s+effetto\s+da(?:l|lla|ll')?|dal|dalla|dall'|fino\s+a(?:l|lla|ll')?|sino\s+a(?:l|lla|ll')?|per(?:\s+(?:un|il)\s+periodo(?:\s+massimo)?\s+di|\s+la\s+durata\s+di)?)(?:\s*(?:(?:il|i|la|le|lo|un|una|almeno|ulteriori|complessivi|termine(?:\s+massimo|\s+perentorio)?\s+di|periodo(?:\s+massimo)?\s+di|massimo\s+di)\b|l'))*\s*$`)
+	// What a duration runs from: "dalla data di entrata in vigore del presente decreto"
+	anchorRe = regexp.MustCompile(`(?i)^\s*(prima\s+(?:della|del|dell'|delle|dei|degli)|successiv[io]\s+a\s+quell[oi]\s+(?:della|del|dell'|delle|dei)|successiv[io]\s+(?:alla|al|all'|alle|ai|agli)|decorrenti\s+(?:dalla|dal|dall'|dalle|dai)|dalla|dal|dall'|dalle|dai|dagli)\s*([^,;.:()]{3,160})`)
+	// Durations that are not time limits: ages and penalties
+	excludedRe = regexp.MustCompile(`(?i)\b(?:età|reclusione|arresto|detenzione|interdizione|sospensione)\b`)
+	commaRe    = regexp.MustCompile(`^[\(\s]*(\d+(?:-?[a-z]+)?)\.\s`)
+)
+
+// Extract returns the time expressions found in doc, in order of appearance,
+// attributed to the nearest enclosing section with an ID.
+func Extract(doc *document.Document) []Deadline {
+	var deadlines []Deadline
+	doc.Walk(func(path []*document.DocumentSection, s *document.DocumentSection) error {
+		id, title := s.ID, s.Title
+		for i := len(path) - 1; i >= 0 && id == ""; i-- {
+			id, title = path[i].ID, path[i].Title
+		}
+		if id == "" {
+			title = ""
+		}
+		for _, block := range s.Content {
+			text := document.PlainText(block)
+			comma := ""
+			if m := commaRe.FindStringSubmatch(text); m != nil {
+				comma = m[1]
+			}
+			for _, d := range Find(text) {
+				d.SectionID, d.Section, d.Comma = id, title, comma
+				deadlines = append(deadlines, d)
+			}
+		}
+		return nil
+	})
+	return deadlines
+}
+
+// Find returns the time expressions in a plain text passage.
+func Find(text string) []Deadline {
+	type match struct {
+		pos int
+		d   Deadline
+	}
+	var found []match
+
+	for _, m := range durationRe.FindAllStringSubmatchIndex(text, -1) {
+		amount, ok := ParseNumber(text[m[2]:m[3]])
+		if !ok || amount == 0 {
+			continue
+		}
+		prefix := sentencePrefix(text, m[0])
+		if excludedRe.MatchString(prefix) {
+			continue
+		}
+
+		unit := units[strings.TrimRight(strings.ToLower(text[m[4]:m[5]]), "eioa")]
+		d := Deadline{
+			Kind:         KindDuration,
+			Amount:       amount,
+			Unit:         unit.name,
+			BusinessDays: m[6] >= 0 && strings.HasPrefix(strings.ToLower(text[m[6]:m[7]]), "lavorativ"),
+			Duration:     isoDuration(amount, unit.iso),
+		}
+
+		start, end := m[0], m[1]
+		if r := relationRe.FindStringSubmatchIndex(prefix); r != nil {
+			start = m[0] - len(prefix) + r[0]
+			d.Relation = relation(prefix[r[2]:r[3]])
+		}
+		if a := anchorRe.FindStringSubmatchIndex(text[end:]); a != nil {
+			if strings.HasPrefix(strings.ToLower(text[end+a[2]:end+a[3]]), "prima") {
+				d.Relation = "before"
+			}
+			d.Anchor = strings.TrimSpace(text[end+a[4] : end+a[5]])
+			d.AnchorType = anchorType(d.Anchor)
+			end += a[1]
+		}
+		d.Text = strings.TrimSpace(text[start:end])
+		d.Context = sentence(text, start, end)
+		found = append(found, match{start, d})
+	}
+
+	citations := citation.Find(text)
+	for _, m := range dateRe.FindAllStringSubmatchIndex(text, -1) {
+		if insideCitation(citations, m[0]) {
+			continue
+		}
+		date, ok := parseDate(text, m)
+		if !ok {
+			continue
+		}
+		d := Deadline{Kind: KindDate, Date: date.Format("2006-01-02")}
+		start := m[0]
+		prefix := sentencePrefix(text, m[0])
+		if r := relationRe.FindStringSubmatchIndex(prefix); r != nil {
+			start = m[0] - len(prefix) + r[0]
+			d.Relation = relation(prefix[r[2]:r[3]])
+		}
+		d.Text = strings.TrimSpace(text[start:m[1]])
+		d.Context = sentence(text, start, m[1])
+		found = append(found, match{start, d})
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].pos < found[j].pos })
+	deadlines := make([]Deadline, len(found))
+	for i, m := range found {
+		deadlines[i] = m.d
+	}
+	return deadlines
+}
+
+// WriteCSV writes deadlines as CSV, one row per expression.
+func WriteCSV(w io.Writer, deadlines []Deadline) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"section_id", "section", "comma", "kind", "relation", "text", "amount", "unit", "business_days", "duration", "date", "anchor", "anchor_type", "context"})
+	for _, d := range deadlines {
+		amount := ""
+		if d.Amount > 0 {
+			amount = strconv.Itoa(d.Amount)
+		}
+		business := ""
+		if d.BusinessDays {
+			business = "true"
+		}
+		cw.Write([]string{d.SectionID, d.Section, d.Comma, d.Kind, d.Relation, d.Text, amount, d.Unit, business, d.Duration, d.Date, d.Anchor, d.AnchorType, d.Context})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func isoDuration(amount int, unit string) string {
+	if unit == "H" {
+		return fmt.Sprintf("PT%dH", amount)
+	}
+	return fmt.Sprintf("P%d%s", amount, unit)
+}
+
+func relation(keyword string) string {
+	keyword = strings.ToLower(keyword)
+	switch {
+	case strings.HasPrefix(keyword, "entro"), strings.HasPrefix(keyword, "non"), strings.HasPrefix(keyword, "nel"):
+		return "within"
+	case strings.HasPrefix(keyword, "decors"), strings.HasPrefix(keyword, "trascors"),
+		strings.HasPrefix(keyword, "dopo"), strings.HasPrefix(keyword, "oltre"):
+		return "after"
+	case strings.HasPrefix(keyword, "fino"), strings.HasPrefix(keyword, "sino"):
+		return "until"
+	case strings.HasPrefix(keyword, "per"):
+		return "for"
+	default:
+		return "from"
+	}
+}
+
+func anchorType(anchor string) string {
+	anchor = strings.ToLower(anchor)
+	switch {
+	case strings.Contains(anchor, "entrata in vigore"):
+		return "entry-into-force"
+	case strings.Contains(anchor, "pubblicazione"):
+		return "publication"
+	case strings.Contains(anchor, "notific"), strings.Contains(anchor, "comunicazione"):
+		return "notification"
+	case strings.Contains(anchor, "ricevimento"), strings.Contains(anchor, "ricezione"):
+		return "receipt"
+	case strings.Contains(anchor, "richiesta"), strings.Contains(anchor, "istanza"), strings.Contains(anchor, "domanda"):
+		return "request"
+	case strings.Contains(anchor, "scadenza"):
+		return "expiry"
+	default:
+		return "other"
+	}
+}
+
+func parseDate(text string, m []int) (time.Time, bool) {
+	var day, month, year int
+	if m[2] >= 0 {
+		day, _ = ParseNumber(text[m[2]:m[3]])
+		month = int(months[strings.ToLower(text[m[4]:m[5]])])
+		year, _ = strconv.Atoi(text[m[6]:m[7]])
+	} else {
+		day, _ = strconv.Atoi(text[m[8]:m[9]])
+		month, _ = strconv.Atoi(text[m[10]:m[11]])
+		year, _ = strconv.Atoi(text[m[12]:m[13]])
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	// Reject 31/02 and the like, which time.Date normalizes
+	if date.Day() != day || int(date.Month()) != month {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// insideCitation tells whether the date at pos is the date of a cited act.
+func insideCitation(citations []citation.Citation, pos int) bool {
+	for _, c := range citations {
+		if pos >= c.Start && pos < c.End {
+			return true
+		}
+	}
+	return false
+}
+
+// sentencePrefix returns up to 80 bytes of text before pos, stopping at the
+// start of the sentence or clause.
+func sentencePrefix(text string, pos int) string {
+	start := max(0, pos-80)
+	for start > 0 && start < pos && !isRuneStart(text[start]) {
+		start++
+	}
+	prefix := text[start:pos]
+	if i := strings.LastIndexAny(prefix, ";.:\n"); i >= 0 {
+		prefix = prefix[i+1:]
+	}
+	return prefix
+}
+
+// sentence returns the sentence around text[start:end].
+func sentence(text string, start, end int) string {
+	from := strings.LastIndexAny(text[:start], ";\n") + 1
+	if i := strings.LastIndex(text[:start], ". "); i+2 > from {
+		from = i + 2
+	}
+	to := len(text)
+	if i := strings.IndexAny(text[end:], ";\n"); i >= 0 {
+		to = end + i
+	}
+	if i := strings.Index(text[end:], ". "); i >= 0 && end+i+1 < to {
+		to = end + i + 1
+	}
+	return strings.TrimSpace(text[from:to])
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,70 @@
+package deadlines
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func TestParseNumber(t *testing.T) {
+	cases := map[string]int{
+		"trenta": 30, "novanta": 90, "ventuno": 21, "trentotto": 38, "centoventi": 120,
+		"centottanta": 180, "trecentosessantacinque": 365, "duemila": 2000,
+		"un": 1, "quindicesimo": 15, "trentesimo": 30, "sesto": 6, "45": 45,
+	}
+	for in, want := range cases {
+		if got, ok := ParseNumber(in); !ok || got != want {
+			t.Errorf("ParseNumber(%q) = %d, %v; want %d", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"alcuni", "presente", ""} {
+		if _, ok := ParseNumber(in); ok {
+			t.Errorf("ParseNumber(%q) should fail", in)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	doc := document.Document{Sections: []document.DocumentSection{{
+		ID:    "art_3",
+		Type:  "article",
+		Title: "Art. 3 - Adempimenti",
+		Content: []string{
+			"1\\. Entro trenta giorni dalla data di entrata in vigore del presente decreto, il Ministro adotta le linee guida.",
+			"2\\. Le domande sono presentate entro il 31 dicembre 2025, ai sensi del [decreto legislativo 22 giugno 2012, n. 83](/uri-res/N2Ls?urn:nir:stato:decreto.legislativo:2012-06-22;83).",
+			"3\\. La comunicazione è inviata almeno 10 giorni lavorativi prima della scadenza; è punito con la reclusione da sei mesi a tre anni chi la omette.",
+		},
+	}}}
+
+	got := Extract(&doc)
+	want := []Deadline{
+		{Kind: KindDuration, Relation: "within", Amount: 30, Unit: "day", Duration: "P30D", Anchor: "data di entrata in vigore del presente decreto", AnchorType: "entry-into-force", Comma: "1",
+			Text: "Entro trenta giorni dalla data di entrata in vigore del presente decreto"},
+		{Kind: KindDate, Relation: "within", Date: "2025-12-31", Comma: "2", Text: "entro il 31 dicembre 2025"},
+		{Kind: KindDuration, Relation: "before", Amount: 10, Unit: "day", BusinessDays: true, Duration: "P10D", Anchor: "scadenza", AnchorType: "expiry", Comma: "3",
+			Text: "10 giorni lavorativi prima della scadenza"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d deadlines, got %d: %+v", len(want), len(got), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.SectionID != "art_3" || g.Context == "" {
+			t.Errorf("deadline %d: missing section or context: %+v", i, g)
+		}
+		g.SectionID, g.Section, g.Context = "", "", ""
+		if g != w {
+			t.Errorf("deadline %d = %+v\nwant %+v", i, g, w)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, got); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 4 {
+		t.Errorf("expected header and 3 rows, got %d lines:\n%s", lines, buf.String())
+	}
+}
@@ -0,0 +1,94 @@
+package deadlines
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// numberWords are the building blocks of Italian cardinal numbers, including
+// the elided forms used in compounds (ventuno, trentotto, centottanta).
+var numberWords = map[string]int{
+	"un": 1, "uno": 1, "una": 1, "due": 2, "tre": 3, "tré": 3, "quattro": 4,
+	"cinque": 5, "sei": 6, "sette": 7, "otto": 8, "nove": 9,
+	"dieci": 10, "undici": 11, "dodici": 12, "tredici": 13, "quattordici": 14,
+	"quindici": 15, "sedici": 16, "diciassette": 17, "diciotto": 18, "diciannove": 19,
+	"venti": 20, "vent": 20, "trenta": 30, "trent": 30, "quaranta": 40, "quarant": 40,
+	"cinquanta": 50, "cinquant": 50, "sessanta": 60, "sessant": 60,
+	"settanta": 70, "settant": 70, "ottanta": 80, "ottant": 80, "novanta": 90, "novant": 90,
+	"cento": 100, "cent": 100, "mille": 1000, "mila": 1000,
+}
+
+var ordinalWords = map[string]int{
+	"primo": 1, "secondo": 2, "terzo": 3, "quarto": 4, "quinto": 5,
+	"sesto": 6, "settimo": 7, "ottavo": 8, "nono": 9, "decimo": 10,
+}
+
+// numberTokens lists numberWords longest first, so that "settanta" is tried
+// before "sette".
+var numberTokens = func() []string {
+	tokens := make([]string, 0, len(numberWords))
+	for w := range numberWords {
+		tokens = append(tokens, w)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if len(tokens[i]) != len(tokens[j]) {
+			return len(tokens[i]) > len(tokens[j])
+		}
+		return tokens[i] < tokens[j]
+	})
+	return tokens
+}()
+
+// ParseNumber reads a number written in digits or Italian words, cardinal
+// ("trenta", "centoventi", "trecentosessantacinque") or ordinal ("quindicesimo").
+func ParseNumber(s string) (int, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, true
+	}
+	if n, ok := ordinalWords[s]; ok {
+		return n, true
+	}
+	if base, ok := strings.CutSuffix(s, "esimo"); ok {
+		// trentesimo → trent, quindicesimo → quindic(i), ventitreesimo → ventitre
+		for _, suffix := range []string{"", "i", "e", "a"} {
+			if n, ok := parseCardinal(base + suffix); ok {
+				return n, true
+			}
+		}
+		return 0, false
+	}
+	return parseCardinal(s)
+}
+
+func parseCardinal(s string) (int, bool) {
+	var parse func(rest string, total, current int) (int, bool)
+	parse = func(rest string, total, current int) (int, bool) {
+		if rest == "" {
+			return total + current, total+current > 0
+		}
+		for _, tok := range numberTokens {
+			if !strings.HasPrefix(rest, tok) {
+				continue
+			}
+			t, c := total, current
+			switch v := numberWords[tok]; {
+			case v == 100:
+				c = max(c, 1) * 100
+			case v == 1000:
+				t, c = t+max(c, 1)*1000, 0
+			default:
+				c += v
+			}
+			if n, ok := parse(rest[len(tok):], t, c); ok {
+				return n, true
+			}
+		}
+		return 0, false
+	}
+	return parse(s, 0, 0)
+}
@@ -0,0 +1,129 @@
+package definitions
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// Definition is a term defined by the act, e.g. point a) of
+// "ai fini del presente decreto si intende per: a) «term»: definition".
+type Definition struct {
+	Term       string   `json:"term"`
+	Aliases    []string `json:"aliases,omitempty"`
+	Definition string   `json:"definition"`
+	SectionID  string   `json:"sectionId"`
+	Section    string   `json:"section"`
+	Point      string   `json:"point,omitempty"`
+	Scope      string   `json:"scope,omitempty"`
+}
+
+var (
+	// Drafting formulas introducing a list of definitions
+	introRe = regexp.MustCompile(`(?i)\bs[ie] intend(?:e|ono)(?:\s+rispettivamente)?\s+per\b|\b(?:valgono|si applicano) le seguenti definizioni\b|\bsi adottano le seguenti definizioni\b`)
+	scopeRe = regexp.MustCompile(`(?i)\bai fini (?:del|della|dei|delle|dell'|degli)\s*([^,:;]+?)\s*,?\s*(?:si\s|valgono|s'intend)`)
+	// a) b) ... aa) z-bis) 1)
+	pointRe = regexp.MustCompile(`^\s*((?:[a-z]{1,2}|\d{1,2})(?:[-\s]?(?:bis|ter|quater|quinquies|sexies|septies|octies|novies|decies))?)\)\s+(.*)$`)
+	// «term»: definition / "term", definition
+	quotedTermRe = regexp.MustCompile(`^(?:per\s+)?(?:(?:il|lo|la|i|gli|le|l')\s*)?[«"“]([^»"”]+)[»"”]((?:\s*(?:,|o|ovvero|oppure|e)\s*[«"“][^»"”]+[»"”])*)\s*[:,]?\s*(.*)$`)
+	aliasRe      = regexp.MustCompile(`[«"“]([^»"”]+)[»"”]`)
+	// term: definition, when the term is not quoted
+	bareTermRe = regexp.MustCompile(`^([^:;«»"]{2,80}?)\s*:\s+(.+)$`)
+	// per «term» si intende ...
+	inlineRe = regexp.MustCompile(`(?i)\bper\s+[«"“]([^»"”]+)[»"”]\s*,?\s*si intend(?:e|ono)\s+(.+?)(?:[;.]\s|$)`)
+	// A content block opening a new comma ends the current list
+	newCommaRe = regexp.MustCompile(`^[\(\s]*\d+[a-z-]*\.\s`)
+)
+
+// Extract returns the definitions found in doc, in order of appearance.
+func Extract(doc *document.Document) []Definition {
+	var defs []Definition
+	doc.Walk(func(_ []*document.DocumentSection, s *document.DocumentSection) error {
+		defs = append(defs, extractSection(s)...)
+		return nil
+	})
+	return defs
+}
+
+func extractSection(s *document.DocumentSection) []Definition {
+	var defs []Definition
+	inList := false
+	scope := ""
+	titled := strings.Contains(strings.ToLower(s.Title), "definizion")
+
+	for _, block := range s.Content {
+		block = document.PlainText(block)
+		if newCommaRe.MatchString(block) {
+			inList = false
+		}
+
+		for _, line := range strings.Split(block, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			if introRe.MatchString(line) {
+				inList = true
+				scope = ""
+				if m := scopeRe.FindStringSubmatch(line); m != nil {
+					scope = strings.TrimSpace(m[1])
+				}
+			}
+
+			if m := pointRe.FindStringSubmatch(line); m != nil && (inList || titled) {
+				if def, ok := parsePoint(m[2]); ok {
+					def.Point = m[1]
+					def.Scope = scope
+					def.SectionID, def.Section = s.ID, s.Title
+					defs = append(defs, def)
+					continue
+				}
+				// Sub-points (numbers under a letter) complete the previous definition
+				if len(defs) > 0 && isDigit(m[1]) {
+					last := &defs[len(defs)-1]
+					last.Definition = cleanDefinition(last.Definition + " " + line)
+				}
+				continue
+			}
+
+			for _, m := range inlineRe.FindAllStringSubmatch(line, -1) {
+				defs = append(defs, Definition{
+					Term:       strings.TrimSpace(m[1]),
+					Definition: cleanDefinition(m[2]),
+					SectionID:  s.ID,
+					Section:    s.Title,
+				})
+			}
+		}
+	}
+	return defs
+}
+
+// parsePoint splits the text of a lettered point into term and definition.
+func parsePoint(text string) (Definition, bool) {
+	text = strings.Trim(text, "() ")
+	if m := quotedTermRe.FindStringSubmatch(text); m != nil && m[3] != "" {
+		def := Definition{Term: strings.TrimSpace(m[1]), Definition: cleanDefinition(m[3])}
+		for _, alias := range aliasRe.FindAllStringSubmatch(m[2], -1) {
+			def.Aliases = append(def.Aliases, strings.TrimSpace(alias[1]))
+		}
+		return def, true
+	}
+	if m := bareTermRe.FindStringSubmatch(text); m != nil && len(strings.Fields(m[1])) <= 8 {
+		return Definition{Term: strings.TrimSpace(m[1]), Definition: cleanDefinition(m[2])}, true
+	}
+	return Definition{}, false
+}
+
+func cleanDefinition(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(s, "))")
+	s = strings.TrimRight(s, ";.,")
+	return strings.TrimSpace(s)
+}
+
+func isDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
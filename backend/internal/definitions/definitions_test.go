@@ -0,0 +1,43 @@
+package definitions
+
+import (
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func TestExtract(t *testing.T) {
+	doc := document.Document{Sections: []document.DocumentSection{{
+		ID:    "art_2",
+		Type:  "article",
+		Title: "Art. 2 - Definizioni",
+		Content: []string{
+			"1\\. Ai fini del presente decreto si intende per:\n\na) «amministrazioni aggiudicatrici»: le amministrazioni dello Stato;\n\nb) «operatore economico» o «impresa», una persona fisica o giuridica;\n\n  1) anche in forma associata;\n\nc) contratto: il contratto di appalto.",
+			"2\\. Le [disposizioni](https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:legge:1990-08-07;241) si applicano anche alle regioni.",
+			"3\\. Per «stazione appaltante» si intende qualsiasi soggetto tenuto al rispetto del codice; il resto non rileva.",
+		},
+	}}}
+
+	defs := Extract(&doc)
+	if len(defs) != 4 {
+		t.Fatalf("expected 4 definitions, got %d: %+v", len(defs), defs)
+	}
+
+	want := []struct{ term, definition, point string }{
+		{"amministrazioni aggiudicatrici", "le amministrazioni dello Stato", "a"},
+		{"operatore economico", "una persona fisica o giuridica 1) anche in forma associata", "b"},
+		{"contratto", "il contratto di appalto", "c"},
+		{"stazione appaltante", "qualsiasi soggetto tenuto al rispetto del codice", ""},
+	}
+	for i, w := range want {
+		if defs[i].Term != w.term || defs[i].Definition != w.definition || defs[i].Point != w.point || defs[i].SectionID != "art_2" {
+			t.Errorf("definition %d = %+v, want %+v", i, defs[i], w)
+		}
+	}
+	if len(defs[1].Aliases) != 1 || defs[1].Aliases[0] != "impresa" {
+		t.Errorf("expected alias «impresa», got %v", defs[1].Aliases)
+	}
+	if defs[0].Scope != "presente decreto" {
+		t.Errorf("unexpected scope %q", defs[0].Scope)
+	}
+}
@@ -0,0 +1,807 @@
+package export
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func init() {
+	builtin.Register(NewExporter(Format{Name: "akn", Label: "Akoma Ntoso XML", MIMEType: "application/akn+xml", Extension: "xml", Aliases: []string{"akomantoso", "xml"}}, AKN))
+}
+
+const aknNamespace = "http://docs.oasis-open.org/legaldocml/ns/akn/3.0"
+
+// aknElements maps the section types of both parsers to AKN hierarchy
+// elements and the prefix of their eIds. Other titled sections become a
+// named <hcontainer>.
+var aknElements = map[string]struct{ element, prefix string }{
+	"libro": {"book", "book"}, "book": {"book", "book"},
+	"parte": {"part", "part"}, "part": {"part", "part"},
+	"titolo": {"title", "title"}, "title": {"title", "title"},
+	"capo": {"chapter", "chp"}, "chapter": {"chapter", "chp"},
+	"sezione": {"section", "sec"}, "section": {"section", "sec"},
+	"articolo": {"article", "art"}, "article": {"article", "art"},
+}
+
+var (
+	// The number opening a heading: "Capo II", "Art. 3-bis", "TITOLO I"
+	aknHeadingNumRe = regexp.MustCompile(`(?i)^(?:art(?:icolo|\.)?|libro|parte|titolo|capo|sezione)\s*([0-9IVXLCDM]+(?:[\s-]*(?:bis|ter|quater|quinquies|sexies|septies|octies|novies|decies))?)\b\.?`)
+	aknItemNumRe    = regexp.MustCompile(`^([a-z]{1,2}(?:-[a-z]+)?|\d+(?:-[a-z]+)?)\)\s+`)
+	aknEIdTokenRe   = regexp.MustCompile(`[^0-9A-Za-z]+`)
+)
+
+// aknWork is the FRBR Work of an act: the parts of its urn:nir.
+type aknWork struct {
+	docType, authority, date, number string
+}
+
+// parseAKNWork reads urn:nir:{authority}:{type}:{date};{number}.
+func parseAKNWork(urn string) (aknWork, bool) {
+	act, _, _ := document.SplitURN(urn)
+	parts := strings.SplitN(strings.TrimPrefix(act, "urn:nir:"), ":", 3)
+	if !strings.HasPrefix(act, "urn:nir:") || len(parts) != 3 {
+		return aknWork{}, false
+	}
+	date, number, _ := strings.Cut(parts[2], ";")
+	if number == "" {
+		number = "0"
+	}
+	return aknWork{docType: parts[1], authority: parts[0], date: date, number: number}, true
+}
+
+// uri is the FRBRuri of the work: /akn/it/act/{type}/{authority}/{date}/{number}.
+func (w aknWork) uri() string {
+	return "/akn/it/act/" + w.docType + "/" + w.authority + "/" + w.date + "/" + w.number
+}
+
+// aknHref is the AKN IRI of an act or of one of its articles and commas.
+func aknHref(urn, article, comma string) string {
+	work, ok := parseAKNWork(urn)
+	if !ok {
+		return ""
+	}
+	return work.uri() + "/!main" + aknFragment(article, comma)
+}
+
+// aknFragment is the "#art_N__para_M" fragment pointing to an article or comma.
+func aknFragment(article, comma string) string {
+	if article == "" {
+		return ""
+	}
+	if comma == "" {
+		return "#art_" + article
+	}
+	return "#art_" + article + "__para_" + comma
+}
+
+// aknWriter prints AKN XML with two-space indentation.
+type aknWriter struct {
+	sb    strings.Builder
+	depth int
+	self  string // act URN of the document
+
+	eIds  map[*document.RenderedSection]string
+	byID  map[string]string // section IDs to eIds, for links
+	taken map[string]bool
+	notes []aknNote
+	refs  []aknRef // references of <meta>
+
+	current *document.RenderedSection // whose content is being written
+}
+
+type aknNote struct {
+	eId   string
+	lines []string
+}
+
+type aknRef struct {
+	element, eId, href, showAs string
+}
+
+// AKN renders doc as an Akoma Ntoso 3.0 act, whichever format it was parsed
+// from: NIR sections become the AKN hierarchy (libro → book, capo →
+// chapter, articolo → article), every element gets an eId following the AKN
+// naming convention (chp_I, art_2bis, art_2bis__para_1__list_1__point_a) and
+// links are rewritten to those eIds and to AKN IRIs of other acts. <meta>
+// carries the FRBR identification derived from the URN, the lifecycle and
+// the modifications read from the source and the update notes. The
+// manifestation is dated at the vigenza, so the same text gives the same XML.
+func AKN(doc *document.Document, opts Options) ([]byte, error) {
+	w := &aknWriter{self: document.ActURN(doc.URN), eIds: map[*document.RenderedSection]string{}, byID: map[string]string{}, taken: map[string]bool{}}
+	sections := doc.Render(opts.MarkdownOptions)
+
+	var preamble, body, attachments []*document.RenderedSection
+	var split func(sections []document.RenderedSection)
+	split = func(sections []document.RenderedSection) {
+		for i := range sections {
+			s := &sections[i]
+			switch {
+			case s.Type == "preamble":
+				preamble = append(preamble, s)
+			case s.Type == "attachments" && s.ID == "":
+				for j := range s.Children {
+					attachments = append(attachments, &s.Children[j])
+				}
+			case aknTransparent(*s):
+				split(s.Children)
+			default:
+				body = append(body, s)
+			}
+		}
+	}
+	split(sections)
+
+	// eIds are known before writing so that links can point forward
+	for _, s := range body {
+		w.assignEIds(s, "")
+	}
+	for _, s := range attachments {
+		w.assignEIds(s, "")
+	}
+
+	work, ok := parseAKNWork(doc.URN)
+	if !ok {
+		work = aknWork{docType: "atto", authority: "stato", date: isoDate(doc.DataGU), number: "0"}
+		if doc.CodiceRedazionale != "" {
+			work.number = doc.CodiceRedazionale
+		}
+		if work.date == "" {
+			work.date = doc.Vigenza
+		}
+	}
+	expressionDate := doc.Vigenza
+	if expressionDate == "" {
+		expressionDate = work.date
+	}
+
+	w.depth = 2
+	if doc.Title != "" {
+		w.open("preface")
+		w.open("longTitle")
+		w.line("p", "<docTitle>"+xmlText(doc.Title)+"</docTitle>")
+		w.close("longTitle")
+		w.close("preface")
+	}
+	if len(preamble) > 0 {
+		w.open("preamble")
+		w.current = nil
+		for _, s := range preamble {
+			for _, c := range s.Content {
+				for _, b := range parseBlocks(c) {
+					w.block(b)
+				}
+			}
+		}
+		w.close("preamble")
+	}
+	w.open("body")
+	for _, s := range body {
+		w.section(s)
+	}
+	w.close("body")
+	if len(attachments) > 0 {
+		w.open("attachments")
+		for i, s := range attachments {
+			name := "att_" + strconv.Itoa(i+1)
+			w.open("attachment", "eId", name)
+			title := plainInline(s.Title)
+			if title == "" {
+				title = "Allegato"
+			}
+			w.open("doc", "name", title)
+			w.open("meta")
+			w.identification(work, expressionDate, "!"+name)
+			w.close("meta")
+			w.open("mainBody")
+			w.current = s
+			for _, c := range s.Content {
+				for _, b := range parseBlocks(c) {
+					w.block(b)
+				}
+			}
+			w.notesOf(s)
+			for i := range s.Children {
+				w.section(&s.Children[i])
+			}
+			w.close("mainBody")
+			w.close("doc")
+			w.close("attachment")
+		}
+		w.close("attachments")
+	}
+	main := w.sb.String()
+
+	w.sb.Reset()
+	w.open("meta")
+	w.identification(work, expressionDate, "!main")
+	if isoDate(doc.DataGU) != "" {
+		w.empty("publication", "date", isoDate(doc.DataGU), "name", "GU", "showAs", "Gazzetta Ufficiale")
+	}
+	w.meta(doc, work)
+	w.close("meta")
+	meta := w.sb.String()
+
+	var out strings.Builder
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	out.WriteString(`<akomaNtoso xmlns="` + aknNamespace + `">` + "\n")
+	out.WriteString(`  <act name="` + xmlAttr(work.docType) + `">` + "\n")
+	out.WriteString(meta)
+	out.WriteString(main)
+	out.WriteString("  </act>\n</akomaNtoso>\n")
+	return []byte(out.String()), nil
+}
+
+// aknTransparent tells the wrappers the parsers leave around the body, which
+// have nothing of their own.
+func aknTransparent(s document.RenderedSection) bool {
+	return s.ID == "" && s.Title == "" && len(s.Content) == 0
+}
+
+// assignEIds gives s and its descendants their eIds under parent. Articles
+// are numbered within the act, the other partitions within their parent.
+func (w *aknWriter) assignEIds(s *document.RenderedSection, parent string) {
+	if aknTransparent(*s) {
+		for i := range s.Children {
+			w.assignEIds(&s.Children[i], parent)
+		}
+		return
+	}
+
+	num, _ := aknHeading(*s)
+	var eId string
+	if el, ok := aknElements[s.Type]; ok && el.element == "article" {
+		article, _ := document.EIdTarget(s.ID)
+		if m := aknHeadingNumRe.FindStringSubmatch(num); article == "" && m != nil {
+			article = strings.ToLower(aknEIdTokenRe.ReplaceAllString(m[1], ""))
+		}
+		eId = w.unique("art_" + article)
+	} else {
+		prefix := "hcontainer"
+		if ok {
+			prefix = el.prefix
+		}
+		token := ""
+		if m := aknHeadingNumRe.FindStringSubmatch(num); m != nil {
+			token = aknEIdTokenRe.ReplaceAllString(m[1], "")
+		}
+		if ok && s.ID != "" && strings.HasPrefix(s.ID, prefix+"_") && !strings.Contains(s.ID, "__") {
+			eId = s.ID // already an AKN eId
+		} else {
+			eId = prefix + "_" + token
+		}
+		if parent != "" && !strings.HasPrefix(eId, parent+"__") {
+			eId = parent + "__" + eId
+		}
+		eId = w.unique(eId)
+	}
+
+	w.eIds[s] = eId
+	if _, seen := w.byID[s.ID]; s.ID != "" && !seen {
+		w.byID[s.ID] = eId
+	}
+	for i := range s.Children {
+		w.assignEIds(&s.Children[i], eId)
+	}
+}
+
+// unique returns eId, numbered when its number is missing ("hcontainer_1")
+// or distinguished when already taken ("art_1-2").
+func (w *aknWriter) unique(eId string) string {
+	if !strings.HasSuffix(eId, "_") && !w.taken[eId] {
+		w.taken[eId] = true
+		return eId
+	}
+	for n := 1; ; n++ {
+		candidate := eId + "-" + strconv.Itoa(n+1)
+		if strings.HasSuffix(eId, "_") {
+			candidate = eId + strconv.Itoa(n)
+		}
+		if !w.taken[candidate] {
+			w.taken[candidate] = true
+			return candidate
+		}
+	}
+}
+
+// aknHeading splits the title of s into the <num> and <heading> of AKN:
+// "Art. 1 - Oggetto" into "Art. 1" and "Oggetto".
+func aknHeading(s document.RenderedSection) (num, heading string) {
+	title := plainInline(s.Title)
+	if num, heading, ok := strings.Cut(title, " - "); ok {
+		return strings.TrimSpace(num), strings.TrimSpace(heading)
+	}
+	if m := aknHeadingNumRe.FindString(title); m != "" {
+		return m, strings.TrimSpace(title[len(m):])
+	}
+	if el, ok := aknElements[s.Type]; ok && el.element == "article" {
+		return title, ""
+	}
+	return "", title
+}
+
+func (w *aknWriter) section(s *document.RenderedSection) {
+	if aknTransparent(*s) {
+		for i := range s.Children {
+			w.section(&s.Children[i])
+		}
+		return
+	}
+
+	eId := w.eIds[s]
+	element, attrs := "hcontainer", []string{"eId", eId, "name", s.Type}
+	if el, ok := aknElements[s.Type]; ok {
+		element, attrs = el.element, []string{"eId", eId}
+	}
+	if s.Status != nil {
+		switch s.Status.Status {
+		case document.StatusRepealed:
+			attrs = append(attrs, "status", "removed")
+		case document.StatusSuspended:
+			attrs = append(attrs, "status", "temporarilyRemoved")
+		}
+	}
+
+	w.open(element, attrs...)
+	w.current = s
+	num, heading := aknHeading(*s)
+	if num != "" {
+		w.line("num", xmlText(num))
+	}
+	if heading != "" {
+		w.line("heading", xmlText(heading))
+	}
+
+	switch {
+	case element == "article":
+		w.articleBody(s, eId)
+	case len(s.Children) == 0:
+		w.open("content")
+		w.blocks(s.Content)
+		w.close("content")
+	case len(s.Content) > 0:
+		w.open("intro")
+		w.blocks(s.Content)
+		w.close("intro")
+	}
+	for i := range s.Children {
+		w.section(&s.Children[i])
+	}
+	w.close(element)
+	w.notesOf(s)
+}
+
+// articleBody writes the commas of an article as <paragraph> elements; text
+// before the first numbered comma is the <intro> of the article.
+func (w *aknWriter) articleBody(s *document.RenderedSection, eId string) {
+	commas := groupCommas(s.Content)
+	numbered := false
+	for _, c := range commas {
+		numbered = numbered || c.Num != ""
+	}
+	if len(commas) == 0 && len(s.Children) == 0 {
+		w.open("content")
+		w.line("p", "")
+		w.close("content")
+		return
+	}
+
+	for _, c := range commas {
+		if c.Num == "" && numbered {
+			w.open("intro")
+			w.blocks(c.Blocks)
+			w.close("intro")
+			continue
+		}
+		paraID := commaID(eId, c.Num)
+		if c.Num == "" {
+			paraID = eId + "__para_1"
+		}
+		w.open("paragraph", "eId", paraID)
+		if c.Num != "" {
+			w.line("num", xmlText(c.Num+"."))
+		}
+		w.paragraphBody(c.Blocks, paraID)
+		w.close("paragraph")
+	}
+}
+
+// aknPoint is an item of a lettered or numbered list, with the numbered
+// items nested under a letter.
+type aknPoint struct {
+	num      string
+	blocks   []block
+	children []aknPoint
+}
+
+// paragraphBody writes the blocks of a comma as <content>, or as a <list>
+// of <point> when it has lettered or numbered items.
+func (w *aknWriter) paragraphBody(content []string, eId string) {
+	var blocks []block
+	for _, c := range content {
+		blocks = append(blocks, parseBlocks(c)...)
+	}
+
+	var intro []block
+	var points []aknPoint
+	topLetter := false
+	for _, b := range blocks {
+		num := ""
+		if b.kind != blockTable {
+			num = aknItemNumRe.FindString(b.text)
+		}
+		isItem := num != "" || b.kind == blockBullet
+		if !isItem {
+			if len(points) == 0 {
+				intro = append(intro, b)
+				continue
+			}
+			last := &points[len(points)-1]
+			if n := len(last.children); n > 0 {
+				last.children[n-1].blocks = append(last.children[n-1].blocks, b)
+			} else {
+				last.blocks = append(last.blocks, b)
+			}
+			continue
+		}
+
+		b.text = strings.TrimSpace(b.text[len(num):])
+		b.kind = blockParagraph
+		letter := num != "" && letterItemRe.MatchString(num)
+		point := aknPoint{num: strings.TrimSpace(num), blocks: []block{b}}
+		if len(points) == 0 {
+			topLetter = letter
+		}
+		if len(points) > 0 && topLetter && !letter {
+			last := &points[len(points)-1]
+			last.children = append(last.children, point)
+			continue
+		}
+		points = append(points, point)
+	}
+
+	if len(points) == 0 {
+		w.open("content")
+		for _, b := range blocks {
+			w.block(b)
+		}
+		w.close("content")
+		return
+	}
+	w.list(eId+"__list_1", intro, points)
+}
+
+func (w *aknWriter) list(eId string, intro []block, points []aknPoint) {
+	w.open("list", "eId", eId)
+	if len(intro) > 0 {
+		w.open("intro")
+		for _, b := range intro {
+			w.block(b)
+		}
+		w.close("intro")
+	}
+	seen := map[string]bool{}
+	for i, p := range points {
+		key := strings.TrimSuffix(p.num, ")")
+		if key == "" || seen[key] {
+			key = strconv.Itoa(i + 1)
+		}
+		seen[key] = true
+		pointID := eId + "__point_" + key
+
+		w.open("point", "eId", pointID)
+		if p.num != "" {
+			w.line("num", xmlText(p.num))
+		}
+		if len(p.children) > 0 {
+			w.list(pointID+"__list_1", p.blocks, p.children)
+		} else {
+			w.open("content")
+			for _, b := range p.blocks {
+				w.block(b)
+			}
+			w.close("content")
+		}
+		w.close("point")
+	}
+	w.close("list")
+}
+
+func (w *aknWriter) blocks(content []string) {
+	for _, c := range content {
+		for _, b := range parseBlocks(c) {
+			w.block(b)
+		}
+	}
+}
+
+// block writes a paragraph-level element: quotes are paragraphs of class
+// "quote", bullets outside lists plain paragraphs.
+func (w *aknWriter) block(b block) {
+	switch b.kind {
+	case blockTable:
+		w.open("table")
+		for i, row := range b.rows {
+			cell := "td"
+			if i == 0 {
+				cell = "th"
+			}
+			w.open("tr")
+			for _, text := range row {
+				w.line(cell, "<p>"+w.inline(text)+"</p>")
+			}
+			w.close("tr")
+		}
+		w.close("table")
+	case blockQuote:
+		w.line("p", w.inline(b.text), "class", "quote")
+	default:
+		w.line("p", w.inline(b.text))
+	}
+}
+
+// inline renders inline Markdown as AKN: links become <ref> and markers of
+// the update notes of the section <noteRef>; emphasis only marks the
+// amended text, still delimited by its (( )).
+func (w *aknWriter) inline(md string) string {
+	var sb strings.Builder
+	for _, r := range parseInline(md) {
+		switch {
+		case r.anchor != "" || r.markStart != 0 || r.comment != 0:
+			continue
+		case r.note != 0 && r.href == "":
+			if eId := w.noteEId(r.note); eId != "" {
+				sb.WriteString(`<noteRef marker="` + strconv.Itoa(r.note) + `" href="#` + xmlAttr(eId) + `"/>`)
+				continue
+			}
+		}
+		if href := w.href(r.href); href != "" {
+			sb.WriteString(`<ref href="` + xmlAttr(href) + `">` + xmlText(r.text) + `</ref>`)
+			continue
+		}
+		sb.WriteString(xmlText(r.text))
+	}
+	return sb.String()
+}
+
+// href rewrites a link target: anchors to eIds, links to Normattiva or to
+// the app to AKN IRIs, or to the eId when they point back to the act.
+func (w *aknWriter) href(link string) string {
+	var urn, article, comma string
+	switch {
+	case link == "":
+		return ""
+	case strings.HasPrefix(link, "#"):
+		id, rest, _ := strings.Cut(link[1:], "__")
+		if eId, ok := w.byID[link[1:]]; ok {
+			return "#" + eId
+		}
+		if eId, ok := w.byID[id]; ok && rest != "" {
+			return "#" + eId + "__" + rest
+		}
+		return link
+	case strings.HasPrefix(link, "/?"):
+		q, _ := url.ParseQuery(link[2:])
+		urn, article, comma = document.SplitURN(q.Get("urn"))
+	default:
+		ref, ok := document.ReferenceFromURL("", link)
+		if !ok {
+			return link
+		}
+		urn, article, comma = ref.URN, ref.Article, ref.Comma
+	}
+	if w.self != "" && document.ActURN(urn) == w.self && article != "" {
+		return aknFragment(article, comma)
+	}
+	if href := aknHref(urn, article, comma); href != "" {
+		return href
+	}
+	return link
+}
+
+// noteEId is the eId of update note n of the section being written, if it
+// has one.
+func (w *aknWriter) noteEId(n int) string {
+	if w.current == nil {
+		return ""
+	}
+	for _, note := range w.current.Notes {
+		if note.Number == n {
+			return noteID(w.eIds[w.current], n)
+		}
+	}
+	return ""
+}
+
+// notesOf records the update notes of s for the <notes> of <meta>.
+func (w *aknWriter) notesOf(s *document.RenderedSection) {
+	for _, note := range s.Notes {
+		w.notes = append(w.notes, aknNote{eId: noteID(w.eIds[s], note.Number), lines: noteLines(note)})
+	}
+}
+
+// identification writes the FRBR Work, Expression and Manifestation of the
+// act, or of its component ("!main", "!att_1").
+func (w *aknWriter) identification(work aknWork, expressionDate, component string) {
+	expression := work.uri() + "/ita@" + expressionDate
+	author := "#" + aknEIdTokenRe.ReplaceAllString(work.authority, "-")
+
+	w.open("identification", "source", "#normaplus")
+	w.open("FRBRWork")
+	w.empty("FRBRthis", "value", work.uri()+"/"+component)
+	w.empty("FRBRuri", "value", work.uri())
+	if w.self != "" {
+		w.empty("FRBRalias", "value", w.self, "name", "urn:nir")
+	}
+	w.empty("FRBRdate", "date", work.date, "name", "Emanazione")
+	w.empty("FRBRauthor", "href", author)
+	w.empty("FRBRcountry", "value", "it")
+	if work.number != "0" {
+		w.empty("FRBRnumber", "value", work.number)
+	}
+	w.empty("FRBRname", "value", work.docType)
+	w.close("FRBRWork")
+	w.open("FRBRExpression")
+	w.empty("FRBRthis", "value", expression+"/"+component)
+	w.empty("FRBRuri", "value", expression)
+	w.empty("FRBRdate", "date", expressionDate, "name", "Vigenza")
+	w.empty("FRBRauthor", "href", author)
+	w.empty("FRBRlanguage", "language", "ita")
+	w.close("FRBRExpression")
+	w.open("FRBRManifestation")
+	w.empty("FRBRthis", "value", expression+"/"+component+".xml")
+	w.empty("FRBRuri", "value", expression+".akn")
+	w.empty("FRBRdate", "date", expressionDate, "name", "Generazione")
+	w.empty("FRBRauthor", "href", "#normaplus")
+	w.close("FRBRManifestation")
+	w.close("identification")
+}
+
+// meta writes the lifecycle, modifications, references and update notes of
+// the act after its identification.
+func (w *aknWriter) meta(doc *document.Document, work aknWork) {
+	if len(doc.Lifecycle) > 0 {
+		w.open("lifecycle", "source", "#normaplus")
+		for i, e := range doc.Lifecycle {
+			eId := e.ID
+			if eId == "" {
+				eId = "evt_" + strconv.Itoa(i+1)
+			}
+			w.empty("eventRef", "eId", eId, "date", e.Date, "source", "#"+w.ref(e.Source, "passiveRef"), "type", e.Type)
+		}
+		w.close("lifecycle")
+	}
+
+	var passive, active []document.Modification
+	for _, m := range doc.Modifications {
+		if m.Direction == document.ModActive {
+			active = append(active, m)
+		} else {
+			passive = append(passive, m)
+		}
+	}
+	if len(passive)+len(active) > 0 {
+		w.open("analysis", "source", "#normaplus")
+		if len(active) > 0 {
+			w.open("activeModifications")
+			for i, m := range active {
+				target := document.Reference{Text: m.TargetURN, URN: m.TargetURN}
+				target.Article, target.Comma = document.EIdTarget(m.Target)
+				w.textualMod(m, "amod_"+strconv.Itoa(i+1), aknFragment(m.Source.Article, m.Source.Comma), "#"+w.ref(target, "activeRef"))
+			}
+			w.close("activeModifications")
+		}
+		if len(passive) > 0 {
+			w.open("passiveModifications")
+			for i, m := range passive {
+				w.textualMod(m, "pmod_"+strconv.Itoa(i+1), "#"+w.ref(m.Source, "passiveRef"), w.href("#"+m.Target))
+			}
+			w.close("passiveModifications")
+		}
+		w.close("analysis")
+	}
+
+	w.open("references", "source", "#normaplus")
+	for _, r := range w.refs {
+		w.empty(r.element, "eId", r.eId, "href", r.href, "showAs", r.showAs)
+	}
+	w.empty("TLCOrganization", "eId", "normaplus", "href", "/ontology/organization/normaplus", "showAs", "NormaPlus")
+	w.empty("TLCOrganization", "eId", aknEIdTokenRe.ReplaceAllString(work.authority, "-"), "href", "/ontology/organization/it/"+work.authority, "showAs", work.authority)
+	w.close("references")
+
+	if len(w.notes) > 0 {
+		w.open("notes", "source", "#normaplus")
+		for _, n := range w.notes {
+			w.open("note", "eId", n.eId)
+			for _, line := range n.lines {
+				w.line("p", w.inline(line))
+			}
+			w.close("note")
+		}
+		w.close("notes")
+	}
+}
+
+func (w *aknWriter) textualMod(m document.Modification, eId, source, destination string) {
+	if m.ID != "" {
+		eId = m.ID
+	}
+	w.open("textualMod", "eId", eId, "type", m.Type)
+	if source != "" {
+		w.empty("source", "href", source)
+	}
+	if destination != "" {
+		w.empty("destination", "href", destination)
+	}
+	if m.Old != "" {
+		w.line("old", xmlText(m.Old))
+	}
+	if m.New != "" {
+		w.line("new", xmlText(m.New))
+	}
+	w.close("textualMod")
+}
+
+// ref returns the eId of the entry of <references> for the act of r, adding
+// it if needed: <original> for the act itself, element for the others.
+func (w *aknWriter) ref(r document.Reference, element string) string {
+	href, prefix := aknHref(r.URN, r.Article, r.Comma), "rp"
+	switch {
+	case w.self != "" && document.ActURN(r.URN) == w.self:
+		element, href, prefix = "original", aknHref(r.URN, "", ""), "ro"
+	case element == "activeRef":
+		prefix = "ra"
+	}
+	if href == "" {
+		href = r.ELI
+	}
+	n := 1
+	for _, existing := range w.refs {
+		if existing.element == element && existing.href == href {
+			return existing.eId
+		}
+		if existing.element == element {
+			n++
+		}
+	}
+	showAs := r.Text
+	if showAs == "" {
+		showAs = href
+	}
+	eId := prefix + strconv.Itoa(n)
+	w.refs = append(w.refs, aknRef{element: element, eId: eId, href: href, showAs: showAs})
+	return eId
+}
+
+// open starts an element; attrs are name, value pairs, empty values skipped.
+func (w *aknWriter) open(tag string, attrs ...string) {
+	w.sb.WriteString(strings.Repeat("  ", w.depth) + "<" + tag + aknAttrs(attrs) + ">\n")
+	w.depth++
+}
+
+func (w *aknWriter) close(tag string) {
+	w.depth--
+	w.sb.WriteString(strings.Repeat("  ", w.depth) + "</" + tag + ">\n")
+}
+
+// line writes an element on one line around inner, already escaped.
+func (w *aknWriter) line(tag, inner string, attrs ...string) {
+	w.sb.WriteString(strings.Repeat("  ", w.depth) + "<" + tag + aknAttrs(attrs) + ">" + inner + "</" + tag + ">\n")
+}
+
+func (w *aknWriter) empty(tag string, attrs ...string) {
+	w.sb.WriteString(strings.Repeat("  ", w.depth) + "<" + tag + aknAttrs(attrs) + "/>\n")
+}
+
+func aknAttrs(attrs []string) string {
+	var sb strings.Builder
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] != "" {
+			sb.WriteString(" " + attrs[i] + `="` + xmlAttr(attrs[i+1]) + `"`)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,370 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/internal/xmlparser"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// aknRule is the content model expected of an element: its children, in
+// order when ordered, those it requires and its required attributes.
+// checkAKN applies the rules to every output as a quick check that needs no
+// tools; TestAKNSchema validates the fixtures against the official schema.
+type aknRule struct {
+	children []string
+	ordered  bool
+	required []string
+	attrs    []string
+	// partitions are the children of a hierarchy element other than <num>,
+	// <heading>, <intro> and <content>; see aknHier
+	partitions []string
+}
+
+// aknHier is the rule of a hierarchy element: <num> and <heading>, then
+// either <content> or an optional <intro> followed by partitions.
+func aknHier(partitions ...string) aknRule {
+	return aknRule{children: append([]string{"num", "heading", "intro", "content"}, partitions...), partitions: partitions, attrs: []string{"eId"}}
+}
+
+var (
+	aknHierChild = []string{"book", "part", "title", "chapter", "section", "article", "hcontainer"}
+	aknBlocks    = []string{"p", "table"}
+	aknFRBR      = []string{"FRBRthis", "FRBRuri", "FRBRalias", "FRBRdate", "FRBRauthor", "FRBRcountry", "FRBRnumber", "FRBRname", "FRBRlanguage"}
+)
+
+// aknRules holds the rules of the elements the serializer produces.
+var aknRules = map[string]aknRule{
+	"akomaNtoso":           {children: []string{"act"}, required: []string{"act"}},
+	"act":                  {children: []string{"meta", "preface", "preamble", "body", "attachments"}, ordered: true, required: []string{"meta", "body"}, attrs: []string{"name"}},
+	"meta":                 {children: []string{"identification", "publication", "lifecycle", "analysis", "references", "notes"}, ordered: true, required: []string{"identification"}},
+	"identification":       {children: []string{"FRBRWork", "FRBRExpression", "FRBRManifestation"}, ordered: true, required: []string{"FRBRWork", "FRBRExpression", "FRBRManifestation"}, attrs: []string{"source"}},
+	"FRBRWork":             {children: aknFRBR, ordered: true, required: []string{"FRBRthis", "FRBRuri", "FRBRdate", "FRBRauthor", "FRBRcountry"}},
+	"FRBRExpression":       {children: aknFRBR, ordered: true, required: []string{"FRBRthis", "FRBRuri", "FRBRdate", "FRBRauthor", "FRBRlanguage"}},
+	"FRBRManifestation":    {children: aknFRBR, ordered: true, required: []string{"FRBRthis", "FRBRuri", "FRBRdate", "FRBRauthor"}},
+	"FRBRthis":             {attrs: []string{"value"}},
+	"FRBRuri":              {attrs: []string{"value"}},
+	"FRBRalias":            {attrs: []string{"value", "name"}},
+	"FRBRdate":             {attrs: []string{"date", "name"}},
+	"FRBRauthor":           {attrs: []string{"href"}},
+	"FRBRcountry":          {attrs: []string{"value"}},
+	"FRBRnumber":           {attrs: []string{"value"}},
+	"FRBRname":             {attrs: []string{"value"}},
+	"FRBRlanguage":         {attrs: []string{"language"}},
+	"publication":          {attrs: []string{"date", "name", "showAs"}},
+	"lifecycle":            {children: []string{"eventRef"}, required: []string{"eventRef"}, attrs: []string{"source"}},
+	"eventRef":             {attrs: []string{"date", "source", "type"}},
+	"analysis":             {children: []string{"activeModifications", "passiveModifications"}, ordered: true, attrs: []string{"source"}},
+	"activeModifications":  {children: []string{"textualMod"}, required: []string{"textualMod"}},
+	"passiveModifications": {children: []string{"textualMod"}, required: []string{"textualMod"}},
+	"textualMod":           {children: []string{"source", "destination", "old", "new"}, ordered: true, required: []string{"source", "destination"}, attrs: []string{"type"}},
+	"source":               {attrs: []string{"href"}},
+	"destination":          {attrs: []string{"href"}},
+	"old":                  {},
+	"new":                  {},
+	"references":           {children: []string{"original", "passiveRef", "activeRef", "TLCOrganization"}, required: []string{"TLCOrganization"}, attrs: []string{"source"}},
+	"original":             {attrs: []string{"eId", "href", "showAs"}},
+	"passiveRef":           {attrs: []string{"eId", "href", "showAs"}},
+	"activeRef":            {attrs: []string{"eId", "href", "showAs"}},
+	"TLCOrganization":      {attrs: []string{"eId", "href", "showAs"}},
+	"notes":                {children: []string{"note"}, required: []string{"note"}, attrs: []string{"source"}},
+	"note":                 {children: []string{"p"}, required: []string{"p"}, attrs: []string{"eId"}},
+	"preface":              {children: []string{"longTitle", "p"}},
+	"longTitle":            {children: []string{"p"}, required: []string{"p"}},
+	"preamble":             {children: aknBlocks},
+	"body":                 {children: aknHierChild},
+	"attachments":          {children: []string{"attachment"}, required: []string{"attachment"}},
+	"attachment":           {children: []string{"doc"}, required: []string{"doc"}},
+	"doc":                  {children: []string{"meta", "mainBody"}, ordered: true, required: []string{"meta", "mainBody"}, attrs: []string{"name"}},
+	"mainBody":             {children: append(slices.Clone(aknBlocks), aknHierChild...)},
+	"article":              aknHier("paragraph", "hcontainer"),
+	"paragraph":            aknHier("list"),
+	"list":                 {children: []string{"intro", "point"}, ordered: true, required: []string{"point"}, attrs: []string{"eId"}},
+	"point":                aknHier("list"),
+	"intro":                {children: aknBlocks},
+	"content":              {children: aknBlocks},
+	"num":                  {},
+	"heading":              {},
+	"table":                {children: []string{"tr"}, required: []string{"tr"}},
+	"tr":                   {children: []string{"th", "td"}},
+	"th":                   {children: []string{"p"}, required: []string{"p"}},
+	"td":                   {children: []string{"p"}, required: []string{"p"}},
+	"p":                    {children: []string{"docTitle", "ref", "noteRef"}},
+	"docTitle":             {},
+	"ref":                  {attrs: []string{"href"}},
+	"noteRef":              {attrs: []string{"href", "marker"}},
+}
+
+func init() {
+	// The levels above the article hold one another and articles
+	for _, name := range []string{"book", "part", "title", "chapter", "section", "hcontainer"} {
+		aknRules[name] = aknHier(aknHierChild...)
+	}
+}
+
+type xmlNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []xmlNode  `xml:",any"`
+}
+
+func (n xmlNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+var aknEIdRe = regexp.MustCompile(`^[a-zA-Z]+_[0-9A-Za-z-]+(__[a-zA-Z]+_[0-9A-Za-z-]+)*$`)
+
+// checkAKN checks the structure of out against aknRules: well-formedness,
+// the AKN namespace, the content model and attributes of every element,
+// hierarchies holding either <content> or partitions, unique eIds, following
+// the naming convention outside <meta>, and local hrefs that resolve.
+func checkAKN(t *testing.T, out []byte) {
+	t.Helper()
+	var root xmlNode
+	if err := xml.Unmarshal(out, &root); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, out)
+	}
+	if root.XMLName.Local != "akomaNtoso" || root.XMLName.Space != aknNamespace {
+		t.Fatalf("unexpected root %v", root.XMLName)
+	}
+
+	eIds := map[string]bool{}
+	var hrefs []string
+	var walk func(n xmlNode, path string)
+	walk = func(n xmlNode, path string) {
+		name := n.XMLName.Local
+		path += "/" + name
+		rule, ok := aknRules[name]
+		if !ok {
+			t.Errorf("%s: unexpected element", path)
+			return
+		}
+		for _, a := range rule.attrs {
+			if n.attr(a) == "" && !(a == "name" && name == "FRBRdate") {
+				t.Errorf("%s: missing attribute %s", path, a)
+			}
+		}
+		if eId := n.attr("eId"); eId != "" {
+			if eIds[eId] {
+				t.Errorf("%s: duplicate eId %q", path, eId)
+			}
+			if !strings.Contains(path, "/meta/") && !aknEIdRe.MatchString(eId) {
+				t.Errorf("%s: invalid eId %q", path, eId)
+			}
+			eIds[eId] = true
+		}
+		for _, a := range []string{"href", "source"} {
+			if v := n.attr(a); strings.HasPrefix(v, "#") {
+				hrefs = append(hrefs, path+" "+v)
+			}
+		}
+
+		last, present := -1, map[string]bool{}
+		for _, c := range n.Children {
+			i := slices.Index(rule.children, c.XMLName.Local)
+			if i < 0 {
+				t.Errorf("%s: unexpected child <%s>", path, c.XMLName.Local)
+				continue
+			}
+			if rule.ordered && i < last {
+				t.Errorf("%s: <%s> out of order", path, c.XMLName.Local)
+			}
+			last = max(last, i)
+			present[c.XMLName.Local] = true
+		}
+		for _, r := range rule.required {
+			if !present[r] {
+				t.Errorf("%s: missing <%s>", path, r)
+			}
+		}
+		if rule.partitions != nil {
+			partitions := false
+			for _, p := range rule.partitions {
+				partitions = partitions || present[p]
+			}
+			if present["content"] == partitions {
+				t.Errorf("%s: needs either <content> or partitions", path)
+			}
+			if present["intro"] && !partitions {
+				t.Errorf("%s: <intro> without partitions", path)
+			}
+			// num, heading, intro, then the body
+			rank := func(name string) int {
+				if i := slices.Index([]string{"num", "heading", "intro"}, name); i >= 0 {
+					return i
+				}
+				return 3
+			}
+			for i := 1; i < len(n.Children); i++ {
+				if rank(n.Children[i].XMLName.Local) < rank(n.Children[i-1].XMLName.Local) {
+					t.Errorf("%s: <%s> out of order", path, n.Children[i].XMLName.Local)
+				}
+			}
+		}
+		for _, c := range n.Children {
+			walk(c, path)
+		}
+	}
+	walk(root, "")
+
+	for _, h := range hrefs {
+		path, target, _ := strings.Cut(h, " #")
+		if !eIds[target] {
+			t.Errorf("%s: href #%s does not resolve", path, target)
+		}
+	}
+}
+
+func parseFixture(t *testing.T, name string) *document.Document {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := document.NewDocument("", "", "", "")
+	if err := xmlparser.FromXML(&doc, data); err != nil {
+		t.Fatal(err)
+	}
+	return &doc
+}
+
+// articleTexts lists the title and plain text of the articles of doc.
+func articleTexts(doc *document.Document) []string {
+	var texts []string
+	doc.Walk(func(_ []*document.DocumentSection, s *document.DocumentSection) error {
+		if s.IsArticle() {
+			text := strings.ReplaceAll(document.PlainText(strings.Join(s.Content, "\n\n")), "> ", "")
+			texts = append(texts, s.ID+" "+s.Title+": "+strings.Join(strings.Fields(text), " "))
+		}
+		return nil
+	})
+	return texts
+}
+
+func TestAKNFromNIR(t *testing.T) {
+	doc := parseFixture(t, "nir.xml")
+	out, err := AKN(doc, Options{MarkdownOptions: document.MarkdownOptions{Links: document.LinkStandalone}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAKN(t, out)
+
+	for _, want := range []string{
+		`<act name="legge">`,
+		`<FRBRthis value="/akn/it/act/legge/stato/1990-08-07/241/!main"/>`,
+		`<FRBRalias value="urn:nir:stato:legge:1990-08-07;241" name="urn:nir"/>`,
+		`<chapter eId="chp_I">`,
+		`<heading>PRINCIPI</heading>`,
+		`<paragraph eId="art_1__para_2">`,
+		`<ref href="#art_3">articolo 3</ref>`,
+		`<ref href="/akn/it/act/decreto.legislativo/stato/2005-03-07/82/!main">codice</ref>`,
+		`<point eId="art_2__para_1__list_1__point_b">`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+
+	// The AKN parser reads the same articles back
+	back := document.NewDocument("", "", "", "")
+	if err := xmlparser.FromXML(&back, out); err != nil {
+		t.Fatal(err)
+	}
+	if back.URN != doc.URN || back.Title != doc.Title {
+		t.Errorf("unexpected act %q %q", back.URN, back.Title)
+	}
+	if got, want := articleTexts(&back), articleTexts(doc); !slices.Equal(got, want) {
+		t.Errorf("articles differ:\n%q\n%q", got, want)
+	}
+}
+
+func TestAKNRoundTrip(t *testing.T) {
+	doc := parseFixture(t, "akn.xml")
+	out, err := AKN(doc, Options{MarkdownOptions: document.MarkdownOptions{Links: document.LinkStandalone}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAKN(t, out)
+
+	back := document.NewDocument("", "", "", "")
+	if err := xmlparser.FromXML(&back, out); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := doc.ToJSON()
+	got, _ := back.ToJSON()
+	if string(got) != string(want) {
+		t.Errorf("round trip differs:\n%s\nwant\n%s", got, want)
+	}
+}
+
+// aknXSD is the official Akoma Ntoso 3.0 schema of OASIS LegalDocML, next
+// to the schemas it imports.
+const aknXSD = "testdata/akn-schema/akomantoso30.xsd"
+
+// TestAKNSchema validates the output for both fixtures against aknXSD with
+// xmllint.
+func TestAKNSchema(t *testing.T) {
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint is not installed")
+	}
+	if _, err := os.Stat(aknXSD); err != nil {
+		t.Skipf("the AKN schema is missing: %v", err)
+	}
+
+	for _, fixture := range []string{"akn.xml", "nir.xml"} {
+		out, err := AKN(parseFixture(t, fixture), Options{MarkdownOptions: document.MarkdownOptions{Links: document.LinkStandalone}})
+		if err != nil {
+			t.Fatalf("%s: %v", fixture, err)
+		}
+		cmd := exec.Command(xmllint, "--noout", "--schema", aknXSD, "-")
+		cmd.Stdin = bytes.NewReader(out)
+		if msg, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("%s: output does not validate: %v\n%s", fixture, err, msg)
+		}
+	}
+}
+
+func TestAKNNotesAndAttachments(t *testing.T) {
+	doc := exportDocument()
+	doc.DataGU = "20230331"
+	doc.Sections = append(doc.Sections, document.DocumentSection{Type: "attachments", Title: "Allegati", Children: []document.DocumentSection{
+		{ID: "allegato-a", Type: "attachment", Title: "Allegato A", Content: []string{"Elenco delle [soglie](#art_1__para_2)."}},
+	}})
+	doc.Sections[0].Children[1].Status = &document.SectionStatus{Status: document.StatusRepealed}
+	out, err := AKN(doc, Options{MarkdownOptions: document.MarkdownOptions{Links: document.LinkStandalone}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAKN(t, out)
+
+	for _, want := range []string{
+		`<publication date="2023-03-31" name="GU" showAs="Gazzetta Ufficiale"/>`,
+		`<chapter eId="chp_I">`,
+		`<noteRef marker="1" href="#art_1__note_1"/>`,
+		`<note eId="art_1__note_1">`,
+		`<article eId="art_2" status="removed">`,
+		`<th><p>Soglia</p></th>`,
+		`<td><p>5.382.000 | euro</p></td>`,
+		`<attachment eId="att_1">`,
+		`<doc name="Allegato A">`,
+		`<FRBRthis value="/akn/it/act/decreto.legislativo/stato/2023-03-31/36/!att_1"/>`,
+		`<p>Elenco delle <ref href="#art_1__para_2">soglie</ref>.</p>`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package export
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// Annotation is a user's comment on a passage of the act, as the viewer
+// stores it: the selected text, the ID of the element the selection started
+// in and some text around it, which tells repeated passages apart.
+type Annotation struct {
+	ID         int
+	Author     string
+	Date       string // YYYY-MM-DD
+	Text       string // selected text
+	Prefix     string // text before the selection
+	Suffix     string // text after the selection
+	LocationID string
+	Comment    string
+}
+
+// Annotations are placed in the rendered Markdown with a highlight and a
+// reference after it, both carrying the annotation number:
+//
+//	<mark id="ann-1">selected text</mark>[^ann-1]
+//
+// The reference doubles as a Markdown footnote reference and ends the range
+// of annotation 1 even when ranges overlap. Annotations whose text can't be
+// found get the reference alone, at the start of their section.
+var (
+	annotationRefRe  = regexp.MustCompile(`\[\^ann-(\d+)\]`)
+	annotationTagRe  = regexp.MustCompile(`^</?(?:span|mark)\b[^>]*>`)
+	annotationLinkRe = regexp.MustCompile(`\[(?:[^\]\\]|\\.)*\]\([^)\s]*\)`)
+	anchorIDRe       = regexp.MustCompile(`<span id="([^"]*)"></span>`)
+)
+
+// annotationPos is the origin of a character of the normalized text: the
+// block it comes from (-1 for headings) and its byte range there.
+type annotationPos struct {
+	block, offset, width int
+}
+
+// annotationBlock is a content block annotations can be placed in.
+type annotationBlock struct {
+	content *string
+	ids     []string // IDs of its section, the ancestors and its anchors
+}
+
+type annotationPlacement struct {
+	ann        Annotation
+	block      int
+	start, end int // -1 for a reference without highlight
+}
+
+// annotate places anns in the content of sections, re-anchoring each one the
+// way the viewer does: the selection is looked for in the text reduced to
+// lowercase letters and digits, and among several occurrences the one
+// matching the prefix and suffix best wins, preferring the section of
+// LocationID. Annotations whose text is gone are placed at the start of
+// their location, and those whose location is not in sections, as happens
+// with partial exports, are left out. It returns the annotations placed, in
+// document order; the number of each is its position plus one.
+func annotate(sections []document.RenderedSection, anns []Annotation) []Annotation {
+	if len(anns) == 0 {
+		return nil
+	}
+
+	var blocks []annotationBlock
+	var text strings.Builder
+	var pos []annotationPos
+	appendText := func(s string, block int) {
+		for i := 0; i < len(s); {
+			rest := s[i:]
+			switch {
+			case rest[0] == '<' && annotationTagRe.MatchString(rest):
+				i += len(annotationTagRe.FindString(rest))
+				continue
+			case strings.HasPrefix(rest, "]("):
+				// Link targets are not part of the text
+				if end := strings.IndexByte(rest, ')'); end > 0 {
+					i += end + 1
+					continue
+				}
+			case rest[0] == '(' && inlineNoteRe.MatchString(rest):
+				i += len(inlineNoteRe.FindString(rest))
+				continue
+			}
+			r, width := utf8.DecodeRuneInString(rest)
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				n, _ := text.WriteRune(unicode.ToLower(r))
+				for range n {
+					pos = append(pos, annotationPos{block, i, width})
+				}
+			}
+			i += width
+		}
+	}
+	known := map[string]bool{"preamble": true} // the act header of the viewer
+	var walk func(s *document.RenderedSection, path []string)
+	walk = func(s *document.RenderedSection, path []string) {
+		if s.ID != "" {
+			path = append(path[:len(path):len(path)], s.ID)
+			known[s.ID] = true
+		}
+		appendText(s.Title, -1)
+		for i := range s.Content {
+			ids := path[:len(path):len(path)]
+			for _, m := range anchorIDRe.FindAllStringSubmatch(s.Content[i], -1) {
+				ids = append(ids, m[1])
+				known[m[1]] = true
+			}
+			blocks = append(blocks, annotationBlock{content: &s.Content[i], ids: ids})
+			appendText(s.Content[i], len(blocks)-1)
+		}
+		for i := range s.Children {
+			walk(&s.Children[i], path)
+		}
+	}
+	for i := range sections {
+		walk(&sections[i], nil)
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+	clean := text.String()
+
+	var placements []annotationPlacement
+	for _, ann := range anns {
+		if ann.LocationID != "" && !known[ann.LocationID] {
+			continue
+		}
+		p, ok := anchorAnnotation(clean, pos, blocks, ann)
+		if !ok {
+			block := firstBlock(blocks, ann.LocationID)
+			p = annotationPlacement{ann: ann, block: block, start: textStart(pos, block, *blocks[block].content), end: -1}
+		}
+		placements = append(placements, p)
+	}
+	sort.SliceStable(placements, func(i, j int) bool {
+		if placements[i].block != placements[j].block {
+			return placements[i].block < placements[j].block
+		}
+		return placements[i].start < placements[j].start
+	})
+
+	// Insert from the end of each block so earlier offsets stay valid. At
+	// the same offset, ranges ending come before ranges starting.
+	type insertion struct {
+		block, offset, opens, number int
+		text                         string
+	}
+	var insertions []insertion
+	placed := make([]Annotation, len(placements))
+	for i, p := range placements {
+		placed[i] = p.ann
+		ref := fmt.Sprintf("[^ann-%d]", i+1)
+		var pieces [][2]int
+		if p.end >= 0 {
+			pieces = markPieces(*blocks[p.block].content, p.start, p.end)
+		}
+		if len(pieces) == 0 {
+			insertions = append(insertions, insertion{p.block, p.start, 1, i + 1, ref + " "})
+			continue
+		}
+		// The first <mark> carries the id, the last one the footnote
+		for k, piece := range pieces {
+			open, end := "<mark>", "</mark>"
+			if k == 0 {
+				open = fmt.Sprintf(`<mark id="ann-%d">`, i+1)
+			}
+			if k == len(pieces)-1 {
+				end += ref
+			}
+			insertions = append(insertions,
+				insertion{p.block, piece[0], 1, i + 1, open},
+				insertion{p.block, piece[1], 0, i + 1, end})
+		}
+	}
+	sort.Slice(insertions, func(i, j int) bool {
+		a, b := insertions[i], insertions[j]
+		if a.block != b.block {
+			return a.block < b.block
+		}
+		if a.offset != b.offset {
+			return a.offset > b.offset
+		}
+		if a.opens != b.opens {
+			return a.opens > b.opens
+		}
+		return a.number > b.number
+	})
+	for _, in := range insertions {
+		content := blocks[in.block].content
+		*content = (*content)[:in.offset] + in.text + (*content)[in.offset:]
+	}
+	return placed
+}
+
+// markPieces splits the range [start, end) of content at the ** delimiters
+// of bold text, so that no <mark> straddles them.
+func markPieces(content string, start, end int) [][2]int {
+	var pieces [][2]int
+	from := start
+	for {
+		i := strings.Index(content[from:end], "**")
+		if i < 0 {
+			break
+		}
+		if i > 0 {
+			pieces = append(pieces, [2]int{from, from + i})
+		}
+		from += i + 2
+	}
+	if from < end {
+		pieces = append(pieces, [2]int{from, end})
+	}
+	return pieces
+}
+
+// anchorAnnotation finds the best occurrence of the text of ann.
+func anchorAnnotation(clean string, pos []annotationPos, blocks []annotationBlock, ann Annotation) (annotationPlacement, bool) {
+	selection := normalizeAnnotationText(ann.Text)
+	if selection == "" {
+		return annotationPlacement{}, false
+	}
+	prefix := normalizeAnnotationText(ann.Prefix)
+	suffix := normalizeAnnotationText(ann.Suffix)
+
+	best, bestScore := -1, -1
+	for from := 0; ; {
+		i := strings.Index(clean[from:], selection)
+		if i < 0 {
+			break
+		}
+		i += from
+		from = i + 1
+		if pos[i].block < 0 {
+			continue
+		}
+		score := 0
+		before, after := clean[:i], clean[i+len(selection):]
+		switch {
+		case prefix != "" && strings.HasSuffix(before, prefix):
+			score += 20
+		case prefix != "" && strings.HasSuffix(before, lastRunes(prefix, 15)):
+			score += 5
+		}
+		switch {
+		case suffix != "" && strings.HasPrefix(after, suffix):
+			score += 20
+		case suffix != "" && strings.HasPrefix(after, firstRunes(suffix, 15)):
+			score += 5
+		}
+		for _, id := range blocks[pos[i].block].ids {
+			if id == ann.LocationID {
+				score += 10
+				break
+			}
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 {
+		return annotationPlacement{}, false
+	}
+
+	first, last := pos[best], pos[best+len(selection)-1]
+	content := *blocks[first.block].content
+	start, end := first.offset, len(content)
+	if last.block == first.block {
+		end = last.offset + last.width
+	}
+	start, end = outsideMarkup(content, start, end)
+	if start >= end {
+		return annotationPlacement{}, false
+	}
+	return annotationPlacement{ann: ann, block: first.block, start: start, end: end}, true
+}
+
+// firstBlock is the first block of the section or anchor with the given ID,
+// or of the document when there is none.
+func firstBlock(blocks []annotationBlock, id string) int {
+	if id != "" {
+		for i, b := range blocks {
+			for _, sid := range b.ids {
+				if sid == id {
+					return i
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// textStart is the offset of the first letter or digit of a content block,
+// past any comma number, where an annotation without a passage goes.
+func textStart(pos []annotationPos, block int, content string) int {
+	start := len(commaNumRe.FindString(content))
+	for _, p := range pos {
+		if p.block == block && p.offset >= start {
+			start = p.offset
+			break
+		}
+	}
+	start, _ = outsideMarkup(content, start, start)
+	return start
+}
+
+// outsideMarkup moves the bounds of a range of content out of links and
+// comma numbers, so that the annotation markup doesn't break them.
+func outsideMarkup(content string, start, end int) (int, int) {
+	for _, link := range annotationLinkRe.FindAllStringIndex(content, -1) {
+		if start > link[0] && start < link[1] {
+			start = link[0]
+		}
+		if end > link[0] && end < link[1] {
+			end = link[1]
+		}
+	}
+	if m := commaNumRe.FindString(content); start < len(m) {
+		start = len(m)
+	}
+	return start, end
+}
+
+// normalizeAnnotationText keeps the letters and digits of s, lowercased.
+func normalizeAnnotationText(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return sb.String()
+}
+
+func firstRunes(s string, n int) string {
+	runes := []rune(s)
+	return string(runes[:min(n, len(runes))])
+}
+
+func lastRunes(s string, n int) string {
+	runes := []rune(s)
+	return string(runes[max(len(runes)-n, 0):])
+}
+
+// renderSections renders doc with the options and annotations of opts,
+// followed by the manifest appendix if any. It returns the annotations
+// placed, numbered by their position plus one.
+func renderSections(doc *document.Document, opts Options) ([]document.RenderedSection, []Annotation) {
+	sections := doc.Render(opts.MarkdownOptions)
+	annotations := annotate(sections, opts.Annotations)
+	if opts.Manifest != nil {
+		sections = append(sections, manifestSection(*opts.Manifest))
+	}
+	return sections, annotations
+}
+
+// annotationComment is the comment of ann or, for a bare highlight, the
+// text it highlights.
+func annotationComment(ann Annotation) string {
+	if comment := strings.TrimSpace(ann.Comment); comment != "" {
+		return comment
+	}
+	return "«" + strings.TrimSpace(ann.Text) + "»"
+}
+
+// annotationLabel is the author and date shown with a comment.
+func annotationLabel(ann Annotation) string {
+	var parts []string
+	if ann.Author != "" {
+		parts = append(parts, ann.Author)
+	}
+	if ann.Date != "" {
+		parts = append(parts, displayDate(ann.Date))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// markdownFootnote is the footnote holding the comment of annotation n;
+// lines after the first are indented to stay in the footnote.
+func markdownFootnote(n int, ann Annotation) string {
+	text := annotationComment(ann)
+	if label := annotationLabel(ann); label != "" {
+		text += " (" + label + ")"
+	}
+	text = strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n    ")
+	return fmt.Sprintf("[^ann-%d]: %s\n\n", n, text)
+}
+
+// annotationRefs lists the annotation numbers referenced in md, in order.
+func annotationRefs(md string) []int {
+	var refs []int
+	for _, m := range annotationRefRe.FindAllStringSubmatch(md, -1) {
+		n, _ := strconv.Atoi(m[1])
+		refs = append(refs, n)
+	}
+	return refs
+}
@@ -0,0 +1,118 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func exportAnnotations() []Annotation {
+	return []Annotation{
+		// Repeated text: the prefix and suffix pick the occurrence in comma 2
+		{ID: 7, Author: "Mario Rossi", Date: "2025-02-03", Text: "contratti", Prefix: "Sono esclusi: a) i ", Suffix: " segreti;", LocationID: "art_1", Comment: "Vedi anche l'art. 3"},
+		// Across the amended text of comma 1
+		{ID: 8, Text: "si applica ai contratti pubblici", Prefix: "Il presente codice ", Suffix: " (1) di cui", LocationID: "art_1", Comment: "Ambito"},
+		// Text no longer in the act: kept at the start of its article
+		{ID: 9, Text: "testo abrogato", LocationID: "art_2", Comment: "Non più vigente"},
+		// Outside the document
+		{ID: 10, Text: "contratti", LocationID: "art_99", Comment: "Altrove"},
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	sections := exportDocument().Render(document.MarkdownOptions{Links: document.LinkStandalone})
+	placed := annotate(sections, exportAnnotations())
+
+	if len(placed) != 3 {
+		t.Fatalf("expected 3 annotations placed, got %d", len(placed))
+	}
+	// Numbered in document order
+	for i, id := range []int{8, 7, 9} {
+		if placed[i].ID != id {
+			t.Errorf("annotation %d: expected ID %d, got %d", i+1, id, placed[i].ID)
+		}
+	}
+
+	art1, art2 := sections[0].Children[0], sections[0].Children[1]
+	for _, want := range []struct{ content, text string }{
+		{art1.Content[0], `**1\.** Il presente codice <mark id="ann-1">si applica ai contratti </mark>**<mark>((pubblici</mark>[^ann-1]))** ((1))`},
+		{art1.Content[1], `a) i <mark id="ann-2">contratti</mark>[^ann-2] <segreti>;`},
+		{art2.Content[0], `**1\.** [^ann-3] Ai fini del codice`},
+	} {
+		if !strings.Contains(want.content, want.text) {
+			t.Errorf("missing %q in\n%s", want.text, want.content)
+		}
+	}
+	for _, r := range parseInline(art1.Content[0]) {
+		if strings.Contains(r.text, "mark>") || (strings.HasPrefix(r.text, "((pubblici") && (!r.bold || !r.highlight)) {
+			t.Errorf("unexpected run %+v", r)
+		}
+	}
+	if runs := parseInline(art1.Content[1]); runs[2].markStart != 2 || !runs[3].highlight || runs[3].text != "contratti" || runs[4].comment != 2 {
+		t.Errorf("unexpected runs %+v", runs)
+	}
+}
+
+func TestExportAnnotations(t *testing.T) {
+	opts := Options{MarkdownOptions: document.MarkdownOptions{Links: document.LinkStandalone}, Annotations: exportAnnotations()}
+
+	out, err := builtin.Export(exportDocument(), "markdown", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<mark id="ann-2">contratti</mark>[^ann-2]`,
+		"[^ann-1]: Ambito\n",
+		"[^ann-2]: Vedi anche l'art. 3 (Mario Rossi, 03-02-2025)\n",
+		"[^ann-3]: Non più vigente\n",
+	} {
+		if !strings.Contains(string(out.Data), want) {
+			t.Errorf("Markdown: missing %q in\n%s", want, out.Data)
+		}
+	}
+
+	html, err := HTML(exportDocument(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<body class="annotated">`,
+		`i <mark class="annotation">contratti</mark><sup class="annotation-ref"><a href="#ann-2">2</a></sup>`,
+		`<p class="margin-note" id="ann-2"><span class="margin-note-num">2</span> Vedi anche l&#39;art. 3<span class="margin-note-author">Mario Rossi, 03-02-2025</span></p>`,
+	} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("HTML: missing %q in\n%s", want, html)
+		}
+	}
+
+	docx, err := DOCX(exportDocument(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := zipParts(t, docx)
+	for _, want := range []string{
+		`<w:commentRangeStart w:id="`,
+		`<w:commentReference w:id="`,
+	} {
+		if n := strings.Count(parts["word/document.xml"], want); n != 3 {
+			t.Errorf("DOCX: expected 3 %q, got %d", want, n)
+		}
+	}
+	comments := parts["word/comments.xml"]
+	if !strings.Contains(comments, `w:author="Mario Rossi" w:initials="MR" w:date="2025-02-03T00:00:00Z"`) || !strings.Contains(comments, "Vedi anche l&#39;art. 3") {
+		t.Errorf("DOCX: unexpected comments\n%s", comments)
+	}
+	if !strings.Contains(parts["word/_rels/document.xml.rels"], `Target="comments.xml"`) || !strings.Contains(parts["[Content_Types].xml"], "/word/comments.xml") {
+		t.Error("DOCX: comments part not declared")
+	}
+
+	pdf, err := PDF(exportDocument(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(pdf, []byte("/BM /Multiply")) {
+		t.Error("PDF: missing highlight")
+	}
+}
@@ -0,0 +1,272 @@
+package export
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// The native renderers read the Markdown held in RenderedSection content
+// blocks. Only the subset the parsers produce is understood: paragraphs,
+// "> " quotes, "- " bullets, pipe tables, links, span anchors, **bold**,
+// backslash escapes, ((n)) note markers and the annotation markup.
+
+type blockKind int
+
+const (
+	blockParagraph blockKind = iota
+	blockQuote
+	blockBullet
+	blockTable
+)
+
+// block is a paragraph-level element of a content block.
+type block struct {
+	kind  blockKind
+	level int        // quote depth or bullet indentation
+	text  string     // inline Markdown
+	rows  [][]string // table cells, header row first
+}
+
+var (
+	tableSeparatorRe = regexp.MustCompile(`^\|(?:\s*:?-{3,}:?\s*\|)+\s*$`)
+	bulletRe         = regexp.MustCompile(`^(\s*)[-*]\s+(.*)$`)
+	quoteRe          = regexp.MustCompile(`^((?:>\s?)+)(.*)$`)
+	commaNumRe       = regexp.MustCompile(`^\*\*\(*(\d+(?:[\s-]?[a-z]+)?)\\?\.\)*\*\*\s*`)
+	// Lettered and numbered items of a comma: "a) ", "b-bis) ", "1) "
+	letterItemRe = regexp.MustCompile(`^[a-z]{1,2}(?:-[a-z]+)?\)\s`)
+	numberItemRe = regexp.MustCompile(`^\d+(?:-[a-z]+)?\)\s`)
+)
+
+// parseBlocks splits a content block into paragraphs, quotes, bullets and tables.
+func parseBlocks(md string) []block {
+	var blocks []block
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, block{kind: blockParagraph, text: strings.Join(para, " ")})
+			para = nil
+		}
+	}
+
+	for _, line := range strings.Split(md, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "|"):
+			flush()
+			if tableSeparatorRe.MatchString(trimmed) {
+				continue
+			}
+			cells := splitCells(trimmed)
+			if n := len(blocks); n > 0 && blocks[n-1].kind == blockTable {
+				blocks[n-1].rows = append(blocks[n-1].rows, cells)
+			} else {
+				blocks = append(blocks, block{kind: blockTable, rows: [][]string{cells}})
+			}
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			m := quoteRe.FindStringSubmatch(trimmed)
+			if text := strings.TrimSpace(m[2]); text != "" {
+				blocks = append(blocks, block{kind: blockQuote, level: strings.Count(m[1], ">"), text: text})
+			}
+		case bulletRe.MatchString(line) && !strings.HasPrefix(trimmed, "**"):
+			flush()
+			m := bulletRe.FindStringSubmatch(line)
+			blocks = append(blocks, block{kind: blockBullet, level: len(m[1]) / 2, text: m[2]})
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// splitCells splits a pipe table row at the unescaped pipes.
+func splitCells(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case row[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(row[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// inline is a run of text with uniform formatting.
+type inline struct {
+	text   string
+	bold   bool
+	href   string // link target
+	note   int    // ((n)) marker referring to update note n
+	anchor string // <span id="..."> target
+	// Annotations: highlighted text, and the start and reference of
+	// annotation n, which carry no text
+	highlight bool
+	markStart int
+	comment   int
+}
+
+var (
+	inlineLinkRe    = regexp.MustCompile(`^\[((?:[^\]\\]|\\.)*)\]\(([^)\s]*)\)`)
+	inlineAnchorRe  = regexp.MustCompile(`^<span id="([^"]*)"></span>`)
+	inlineNoteRe    = regexp.MustCompile(`^\(\((\d+)\)\)`)
+	inlineMarkRe    = regexp.MustCompile(`^<mark id="ann-(\d+)">`)
+	inlineCommentRe = regexp.MustCompile(`^\[\^ann-(\d+)\]`)
+)
+
+// parseInline splits inline Markdown into formatted runs.
+func parseInline(md string) []inline {
+	var runs []inline
+	var text strings.Builder
+	bold := false
+	marks := map[int]bool{} // annotations whose range is open
+	flush := func() {
+		if text.Len() > 0 {
+			runs = append(runs, inline{text: text.String(), bold: bold, highlight: len(marks) > 0})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(md); {
+		rest := md[i:]
+		switch {
+		case strings.HasPrefix(rest, "**"):
+			flush()
+			bold = !bold
+			i += 2
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune(`\.-*_#|>[]()`, rune(rest[1])):
+			text.WriteByte(rest[1])
+			i += 2
+		case rest[0] == '[' && inlineCommentRe.MatchString(rest):
+			flush()
+			m := inlineCommentRe.FindStringSubmatch(rest)
+			n, _ := strconv.Atoi(m[1])
+			delete(marks, n)
+			runs = append(runs, inline{comment: n})
+			i += len(m[0])
+		case rest[0] == '[' && inlineLinkRe.MatchString(rest):
+			flush()
+			m := inlineLinkRe.FindStringSubmatch(rest)
+			for _, r := range parseInline(m[1]) {
+				r.href, r.bold = m[2], r.bold || bold
+				r.highlight = r.highlight || len(marks) > 0
+				runs = append(runs, r)
+			}
+			i += len(m[0])
+		case rest[0] == '<' && inlineMarkRe.MatchString(rest):
+			flush()
+			m := inlineMarkRe.FindStringSubmatch(rest)
+			n, _ := strconv.Atoi(m[1])
+			marks[n] = true
+			runs = append(runs, inline{markStart: n})
+			i += len(m[0])
+		case strings.HasPrefix(rest, "<mark>"), strings.HasPrefix(rest, "</mark>"):
+			// The range stays open until its footnote: pieces split at bold
+			// delimiters need nothing more
+			i += strings.Index(rest, ">") + 1
+		case rest[0] == '<' && inlineAnchorRe.MatchString(rest):
+			flush()
+			m := inlineAnchorRe.FindStringSubmatch(rest)
+			runs = append(runs, inline{anchor: m[1]})
+			i += len(m[0])
+		case rest[0] == '(' && !bold && inlineNoteRe.MatchString(rest):
+			flush()
+			m := inlineNoteRe.FindStringSubmatch(rest)
+			n, _ := strconv.Atoi(m[1])
+			runs = append(runs, inline{text: m[0], note: n})
+			i += len(m[0])
+		default:
+			text.WriteByte(rest[0])
+			i++
+		}
+	}
+	flush()
+	return runs
+}
+
+// comma is a numbered comma of an article: the content block starting with
+// its number and the unnumbered blocks (lists, tables) that follow it.
+type comma struct {
+	Num    string
+	Blocks []string // content blocks, the number removed from the first
+}
+
+// groupCommas groups the content blocks of a section by comma. Blocks before
+// the first number, or in sections without numbered commas, form a comma
+// with an empty Num.
+func groupCommas(content []string) []comma {
+	var commas []comma
+	for _, c := range content {
+		if m := commaNumRe.FindStringSubmatch(c); m != nil {
+			commas = append(commas, comma{Num: m[1], Blocks: []string{c[len(m[0]):]}})
+			continue
+		}
+		if len(commas) == 0 {
+			commas = append(commas, comma{})
+		}
+		commas[len(commas)-1].Blocks = append(commas[len(commas)-1].Blocks, c)
+	}
+	return commas
+}
+
+// commaID is the anchor of a comma, the one links to "#art_5__para_2"
+// resolve to.
+func commaID(sectionID, num string) string {
+	return document.CommaAnchor(sectionID, num)
+}
+
+// noteID is the anchor of an update note of a section.
+func noteID(sectionID string, n int) string {
+	return sectionID + "__note_" + strconv.Itoa(n)
+}
+
+// noteLines splits an update note into its heading and paragraphs.
+func noteLines(note document.UpdateNote) []string {
+	var lines []string
+	if note.Heading != "" {
+		lines = append(lines, note.Heading)
+	}
+	for _, line := range strings.Split(note.Text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// displayDate turns YYYY-MM-DD into DD-MM-YYYY, as the Markdown header does.
+func displayDate(date string) string {
+	if parts := strings.Split(date, "-"); len(parts) == 3 {
+		return parts[2] + "-" + parts[1] + "-" + parts[0]
+	}
+	return date
+}
+
+// safeHref drops link targets other than web, mail and in-document links.
+// Protocol-relative links ("//host/...", or "/\host" to browsers) would
+// leave the site and are dropped too.
+func safeHref(href string) string {
+	lower := strings.ToLower(href)
+	if strings.HasPrefix(lower, "//") || strings.HasPrefix(lower, `/\`) {
+		return ""
+	}
+	for _, prefix := range []string{"http://", "https://", "mailto:", "/", "#"} {
+		if strings.HasPrefix(lower, prefix) {
+			return href
+		}
+	}
+	return ""
+}
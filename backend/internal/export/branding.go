@@ -0,0 +1,111 @@
+package export
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// Branding is the letterhead of exported documents. Header and Footer may
+// use the placeholders {firm}, {date}, {vigenza}, {title} and {urn}; when
+// empty the header shows the firm name and the footer the vigenza and export
+// dates.
+type Branding struct {
+	FirmName string
+	Header   string
+	Footer   string
+	Date     string // export date, YYYY-MM-DD
+}
+
+const (
+	defaultBrandingHeader = "{firm}"
+	defaultBrandingFooter = "Testo vigente al {vigenza} - estratto il {date}"
+)
+
+// IsZero reports whether b adds nothing to the output.
+func (b Branding) IsZero() bool {
+	return b.FirmName == "" && b.Header == "" && b.Footer == ""
+}
+
+// HeaderText is the header line for doc, or "" without branding.
+func (b Branding) HeaderText(doc *document.Document) string {
+	if b.IsZero() {
+		return ""
+	}
+	return b.expand(b.Header, defaultBrandingHeader, doc)
+}
+
+// FooterText is the footer line for doc, or "" without branding.
+func (b Branding) FooterText(doc *document.Document) string {
+	if b.IsZero() {
+		return ""
+	}
+	return b.expand(b.Footer, defaultBrandingFooter, doc)
+}
+
+func (b Branding) expand(text, fallback string, doc *document.Document) string {
+	if text == "" {
+		text = fallback
+	}
+	vigenza := displayDate(doc.Vigenza)
+	if vigenza == "" {
+		vigenza = "-"
+	}
+	text = strings.NewReplacer(
+		"{firm}", b.FirmName,
+		"{date}", displayDate(b.Date),
+		"{vigenza}", vigenza,
+		"{title}", doc.Title,
+		"{urn}", doc.URN,
+	).Replace(text)
+	return strings.TrimSpace(text)
+}
+
+// rgb is a colour of the PDF palette.
+type rgb struct{ r, g, b int }
+
+// pdfTheme holds the colours PDF output takes from the CSS theme.
+type pdfTheme struct {
+	text, muted, accent, rule rgb
+}
+
+var (
+	defaultPDFTheme = pdfTheme{
+		text:   rgb{26, 26, 26},
+		muted:  rgb{95, 99, 104},
+		accent: rgb{29, 78, 137},
+		rule:   rgb{208, 212, 217},
+	}
+	cssColorVarRe = regexp.MustCompile(`--(text|muted|accent|rule)\s*:\s*#([0-9a-fA-F]{6}|[0-9a-fA-F]{3})\b`)
+)
+
+// themeFromCSS reads the --text, --muted, --accent and --rule colours a
+// stylesheet defines, as the default html.css does, so that one theme
+// styles both HTML and PDF output.
+func themeFromCSS(css string) pdfTheme {
+	theme := defaultPDFTheme
+	for _, m := range cssColorVarRe.FindAllStringSubmatch(css, -1) {
+		hex := m[2]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		var c rgb
+		for i, v := range []*int{&c.r, &c.g, &c.b} {
+			n, _ := strconv.ParseUint(hex[2*i:2*i+2], 16, 8)
+			*v = int(n)
+		}
+		switch m[1] {
+		case "text":
+			theme.text = c
+		case "muted":
+			theme.muted = c
+		case "accent":
+			theme.accent = c
+		case "rule":
+			theme.rule = c
+		}
+	}
+	return theme
+}
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBranding(t *testing.T) {
+	doc := exportDocument()
+	b := Branding{FirmName: "Studio Rossi & Associati", Date: "2026-10-18"}
+	if got := b.HeaderText(doc); got != "Studio Rossi & Associati" {
+		t.Errorf("header = %q", got)
+	}
+	if got := b.FooterText(doc); got != "Testo vigente al 01-01-2025 - estratto il 18-10-2026" {
+		t.Errorf("footer = %q", got)
+	}
+	b.Header = "{firm} | {title}"
+	if got := b.HeaderText(doc); got != "Studio Rossi & Associati | DECRETO LEGISLATIVO 31 marzo 2023, n. 36" {
+		t.Errorf("header = %q", got)
+	}
+	if (Branding{Date: "2026-10-18"}).HeaderText(doc) != "" {
+		t.Errorf("a date alone should add no letterhead")
+	}
+
+	opts := Options{Branding: b, Stylesheet: ":root { --accent: #8a1538; }"}
+	html, err := HTML(doc, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<div class="letterhead">Studio Rossi &amp; Associati | DECRETO`,
+		`<footer class="letterhead-footer">Testo vigente al 01-01-2025`,
+		"--accent: #8a1538;",
+		".comma-num", // the theme adds to the default stylesheet
+	} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("missing %q in HTML", want)
+		}
+	}
+
+	out, err := DOCX(doc, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := zipParts(t, out)
+	if !strings.Contains(parts["word/header1.xml"], "Studio Rossi &amp; Associati") || !strings.Contains(parts["word/footer1.xml"], " PAGE ") {
+		t.Errorf("missing letterhead parts:\n%s\n%s", parts["word/header1.xml"], parts["word/footer1.xml"])
+	}
+	if !strings.Contains(parts["word/document.xml"], `<w:headerReference w:type="default" r:id="rIdHeader"/>`) ||
+		!strings.Contains(parts["[Content_Types].xml"], "/word/footer1.xml") {
+		t.Errorf("letterhead parts not referenced")
+	}
+
+	if _, err := HTML(doc, Options{Stylesheet: "p { color: red; }</style><script>alert(1)</script>"}); err == nil {
+		t.Errorf("a stylesheet closing the <style> element was embedded")
+	}
+
+	if theme := themeFromCSS(opts.Stylesheet); theme.accent != (rgb{0x8a, 0x15, 0x38}) || theme.text != defaultPDFTheme.text {
+		t.Errorf("theme = %+v", theme)
+	}
+	if _, err := PDF(doc, opts); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,611 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func init() {
+	builtin.Register(NewExporter(Format{Name: "docx", Label: "Word (DOCX)", MIMEType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", Extension: "docx"}, DOCX))
+}
+
+// Word styles of the document structure, and the outline level each one
+// gives its headings. Named styles let a house template restyle the output
+// by defining styles with the same names.
+var docxHeadings = map[string]struct {
+	style   string
+	outline int
+}{
+	"act":      {"Atto", 0}, // an act of a compilation
+	"book":     {"Libro", 0},
+	"libro":    {"Libro", 0},
+	"part":     {"Parte", 1},
+	"parte":    {"Parte", 1},
+	"title":    {"Titolo", 2},
+	"titolo":   {"Titolo", 2},
+	"chapter":  {"Capo", 3},
+	"capo":     {"Capo", 3},
+	"section":  {"Sezione", 4},
+	"sezione":  {"Sezione", 4},
+	"article":  {"Articolo", 5},
+	"articolo": {"Articolo", 5},
+}
+
+var (
+	docxBookmarkRe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+	docxStyleRe    = regexp.MustCompile(`(?s)<w:style\s[^>]*w:styleId="([^"]+)".*?</w:style>`)
+)
+
+type docxRel struct {
+	id, target string
+}
+
+type docxWriter struct {
+	opts        Options
+	body        strings.Builder
+	footnotes   strings.Builder
+	comments    strings.Builder
+	links       []docxRel
+	footnote    int
+	bookmark    int // last bookmark or comment ID
+	bookmarks   map[string]bool
+	annotations []Annotation
+	commentIDs  map[int]int // comment ID of each annotation number
+}
+
+// DOCX renders doc as a Word document. Headings use the Libro, Parte,
+// Titolo, Capo, Sezione and Articolo paragraph styles and commas the Comma
+// style, so a house template can restyle them; every section and comma is
+// bookmarked with its ID and in-document links point to the bookmarks.
+// Update notes become footnotes at their first marker and annotations Word
+// comments on the text they highlight. With opts.TOC a table of contents
+// field is inserted after the title.
+func DOCX(doc *document.Document, opts Options) ([]byte, error) {
+	w := &docxWriter{opts: opts, bookmarks: map[string]bool{}, commentIDs: map[int]int{}}
+	sections, annotations := renderSections(doc, opts)
+	w.annotations = annotations
+
+	if doc.Title != "" {
+		w.paragraph("TitoloAtto", "", "preamble", []inline{{text: doc.Title}}, "")
+	}
+	if doc.Vigenza != "" {
+		w.paragraph("Vigenza", "", "", []inline{{text: "Testo in vigore al: " + displayDate(doc.Vigenza)}}, "")
+	}
+	if opts.TOC {
+		w.toc()
+	}
+	for _, s := range sections {
+		w.section(s)
+	}
+	return w.archive(doc)
+}
+
+func (w *docxWriter) section(s document.RenderedSection) {
+	if s.Title != "" {
+		style, outline := "Partizione", min(s.Level-1, 8)
+		if h, ok := docxHeadings[s.Type]; ok {
+			style, outline = h.style, h.outline
+		}
+		w.paragraph(style, fmt.Sprintf(`<w:outlineLvl w:val="%d"/>`, outline), s.ID, parseInline(s.Title), s.ID)
+	}
+
+	// The acts of a compilation open with a title page
+	if s.Type == "act" {
+		for _, line := range s.Content {
+			w.paragraph("Frontespizio", "", "", parseInline(line), s.ID)
+		}
+		w.body.WriteString(`<w:p><w:r><w:br w:type="page"/></w:r></w:p>`)
+		for _, child := range s.Children {
+			w.section(child)
+		}
+		return
+	}
+
+	notes := map[int]document.UpdateNote{}
+	for _, n := range s.Notes {
+		notes[n.Number] = n
+	}
+	referenced := map[int]bool{}
+	footnote := func(n int) string {
+		lines := noteLines(notes[n])
+		if len(lines) == 0 {
+			return ""
+		}
+		if referenced[n] {
+			lines = lines[:1]
+		}
+		referenced[n] = true
+		return w.addFootnote(lines, s.ID)
+	}
+
+	for _, c := range groupCommas(s.Content) {
+		bookmark := commaID(s.ID, c.Num)
+		for i, b := range c.Blocks {
+			for j, blk := range parseBlocks(b) {
+				prefix := ""
+				if i == 0 && j == 0 && c.Num != "" {
+					prefix = c.Num + ". "
+				}
+				w.block(blk, prefix, bookmark, s.ID, footnote)
+				bookmark = ""
+			}
+		}
+	}
+
+	for _, n := range s.Notes {
+		if referenced[n.Number] {
+			continue
+		}
+		for _, line := range noteLines(n) {
+			w.paragraph("NotaAggiornamento", "", "", parseInline(line), s.ID)
+		}
+	}
+
+	for _, child := range s.Children {
+		w.section(child)
+	}
+}
+
+// block writes a paragraph-level element; prefix is the comma number, set on
+// the first paragraph of a comma.
+func (w *docxWriter) block(b block, prefix, bookmark, sectionID string, footnote func(int) string) {
+	switch b.kind {
+	case blockTable:
+		w.table(b.rows, sectionID)
+		return
+	case blockQuote:
+		w.paragraphWith("Citazione", fmt.Sprintf(`<w:ind w:left="%d"/>`, 567*b.level), bookmark, w.runs(parseInline(b.text), sectionID, footnote))
+		return
+	case blockBullet:
+		runs := w.run("• ", "") + w.runs(parseInline(b.text), sectionID, footnote)
+		w.paragraphWith("Elenco", fmt.Sprintf(`<w:ind w:left="%d" w:hanging="283"/>`, 567+425*b.level), bookmark, runs)
+		return
+	}
+
+	style := "Comma"
+	switch {
+	case letterItemRe.MatchString(b.text):
+		style = "Lettera"
+	case numberItemRe.MatchString(b.text):
+		style = "Numero"
+	}
+	runs := ""
+	if prefix != "" {
+		runs = w.run(prefix, "NumeroComma")
+	}
+	w.paragraphWith(style, "", bookmark, runs+w.runs(parseInline(b.text), sectionID, footnote))
+}
+
+func (w *docxWriter) paragraph(style, pPr, bookmark string, text []inline, sectionID string) {
+	w.paragraphWith(style, pPr, bookmark, w.runs(text, sectionID, nil))
+}
+
+func (w *docxWriter) paragraphWith(style, pPr, bookmark, runs string) {
+	w.body.WriteString(`<w:p><w:pPr><w:pStyle w:val="` + style + `"/>` + pPr + `</w:pPr>`)
+	if bookmark != "" {
+		w.body.WriteString(w.bookmarked(bookmark, runs))
+	} else {
+		w.body.WriteString(runs)
+	}
+	w.body.WriteString(`</w:p>`)
+}
+
+// bookmarked wraps content in a bookmark; names already taken are skipped.
+func (w *docxWriter) bookmarked(id, content string) string {
+	name := bookmarkName(id)
+	if w.bookmarks[name] {
+		return content
+	}
+	w.bookmarks[name] = true
+	w.bookmark++
+	return fmt.Sprintf(`<w:bookmarkStart w:id="%d" w:name="%s"/>%s<w:bookmarkEnd w:id="%d"/>`, w.bookmark, name, content, w.bookmark)
+}
+
+// bookmarkName adapts an ID to Word's bookmark rules: letters, digits and
+// underscores, starting with a letter, at most 40 characters.
+func bookmarkName(id string) string {
+	name := docxBookmarkRe.ReplaceAllString(id, "_")
+	if name == "" || !(name[0] >= 'A' && name[0] <= 'Z' || name[0] >= 'a' && name[0] <= 'z') {
+		name = "s" + name
+	}
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	return name
+}
+
+// runs renders inline text. Links to IDs in the document point to their
+// bookmark, other links become external hyperlinks; note markers become
+// footnote references when footnote is set and annotations delimit the range
+// of their comment.
+func (w *docxWriter) runs(text []inline, sectionID string, footnote func(int) string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		switch {
+		case r.anchor != "":
+			sb.WriteString(w.bookmarked(r.anchor, ""))
+			continue
+		case r.note != 0 && footnote != nil:
+			if ref := footnote(r.note); ref != "" {
+				sb.WriteString(ref)
+				continue
+			}
+		case r.markStart != 0:
+			fmt.Fprintf(&sb, `<w:commentRangeStart w:id="%d"/>`, w.commentID(r.markStart))
+			continue
+		case r.comment != 0:
+			sb.WriteString(w.commentReference(r.comment))
+			continue
+		}
+
+		style := ""
+		if r.bold {
+			style = "TestoModificato"
+		}
+		href := safeHref(r.href)
+		switch {
+		case strings.HasPrefix(href, "#"):
+			sb.WriteString(`<w:hyperlink w:anchor="` + bookmarkName(href[1:]) + `">` + w.run(r.text, "Collegamento") + `</w:hyperlink>`)
+		case href != "" && !strings.HasPrefix(href, "/"):
+			id := fmt.Sprintf("rIdLink%d", len(w.links)+1)
+			w.links = append(w.links, docxRel{id, href})
+			sb.WriteString(`<w:hyperlink r:id="` + id + `">` + w.run(r.text, "Collegamento") + `</w:hyperlink>`)
+		default:
+			sb.WriteString(w.run(r.text, style))
+		}
+	}
+	return sb.String()
+}
+
+func (w *docxWriter) run(text, style string) string {
+	rPr := ""
+	if style != "" {
+		rPr = `<w:rPr><w:rStyle w:val="` + style + `"/></w:rPr>`
+	}
+	return `<w:r>` + rPr + `<w:t xml:space="preserve">` + xmlText(text) + `</w:t></w:r>`
+}
+
+// addFootnote adds a footnote with the given paragraphs and returns the run
+// referencing it.
+func (w *docxWriter) addFootnote(lines []string, sectionID string) string {
+	w.footnote++
+	fmt.Fprintf(&w.footnotes, `<w:footnote w:id="%d">`, w.footnote)
+	for i, line := range lines {
+		w.footnotes.WriteString(`<w:p><w:pPr><w:pStyle w:val="TestoNota"/></w:pPr>`)
+		if i == 0 {
+			w.footnotes.WriteString(`<w:r><w:rPr><w:rStyle w:val="RifNota"/></w:rPr><w:footnoteRef/></w:r><w:r><w:t xml:space="preserve"> </w:t></w:r>`)
+		}
+		w.footnotes.WriteString(w.runs(parseInline(line), sectionID, nil) + `</w:p>`)
+	}
+	w.footnotes.WriteString(`</w:footnote>`)
+	return fmt.Sprintf(`<w:r><w:rPr><w:rStyle w:val="RifNota"/></w:rPr><w:footnoteReference w:id="%d"/></w:r>`, w.footnote)
+}
+
+// commentID is the ID of the comment of annotation n, shared with the
+// bookmarks since Word wants annotation IDs unique.
+func (w *docxWriter) commentID(n int) int {
+	if id, ok := w.commentIDs[n]; ok {
+		return id
+	}
+	w.bookmark++
+	w.commentIDs[n] = w.bookmark
+	return w.bookmark
+}
+
+// commentReference ends the range of the comment of annotation n, adding the
+// comment; annotations without a range get an empty one.
+func (w *docxWriter) commentReference(n int) string {
+	_, started := w.commentIDs[n]
+	id := w.commentID(n)
+	ref := ""
+	if !started {
+		ref = fmt.Sprintf(`<w:commentRangeStart w:id="%d"/>`, id)
+	}
+	ref += fmt.Sprintf(`<w:commentRangeEnd w:id="%d"/><w:r><w:rPr><w:rStyle w:val="RifCommento"/></w:rPr><w:commentReference w:id="%d"/></w:r>`, id, id)
+	if n > len(w.annotations) {
+		return ref
+	}
+
+	ann := w.annotations[n-1]
+	author := ann.Author
+	if author == "" {
+		author = "Annotazione"
+	}
+	fmt.Fprintf(&w.comments, `<w:comment w:id="%d" w:author="%s" w:initials="%s"`, id, xmlAttr(author), xmlAttr(initials(author)))
+	if date := isoDate(ann.Date); date != "" {
+		w.comments.WriteString(` w:date="` + date + `T00:00:00Z"`)
+	}
+	w.comments.WriteString(`>`)
+	for i, line := range strings.Split(annotationComment(ann), "\n") {
+		w.comments.WriteString(`<w:p><w:pPr><w:pStyle w:val="TestoCommento"/></w:pPr>`)
+		if i == 0 {
+			w.comments.WriteString(`<w:r><w:rPr><w:rStyle w:val="RifCommento"/></w:rPr><w:annotationRef/></w:r>`)
+		}
+		w.comments.WriteString(w.run(strings.TrimRight(line, "\r"), "") + `</w:p>`)
+	}
+	w.comments.WriteString(`</w:comment>`)
+	return ref
+}
+
+// initials are the first letters of the words of name.
+func initials(name string) string {
+	var sb strings.Builder
+	for _, word := range strings.Fields(name) {
+		r, _ := utf8.DecodeRuneInString(word)
+		sb.WriteRune(unicode.ToUpper(r))
+	}
+	return sb.String()
+}
+
+func (w *docxWriter) table(rows [][]string, sectionID string) {
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return
+	}
+	w.body.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="Tabella"/><w:tblW w:w="5000" w:type="pct"/></w:tblPr><w:tblGrid>`)
+	for i := 0; i < cols; i++ {
+		fmt.Fprintf(&w.body, `<w:gridCol w:w="%d"/>`, 9638/cols)
+	}
+	w.body.WriteString(`</w:tblGrid>`)
+	for r, row := range rows {
+		w.body.WriteString(`<w:tr>`)
+		if r == 0 {
+			w.body.WriteString(`<w:trPr><w:tblHeader/></w:trPr>`)
+		}
+		for c := 0; c < cols; c++ {
+			cell := ""
+			if c < len(row) {
+				cell = row[c]
+			}
+			text := parseInline(cell)
+			if r == 0 {
+				for i := range text {
+					text[i].bold = true
+				}
+			}
+			w.body.WriteString(`<w:tc><w:tcPr><w:tcW w:w="0" w:type="auto"/></w:tcPr><w:p><w:pPr><w:pStyle w:val="TestoTabella"/></w:pPr>` + w.runs(text, sectionID, nil) + `</w:p></w:tc>`)
+		}
+		w.body.WriteString(`</w:tr>`)
+	}
+	w.body.WriteString(`</w:tbl>`)
+}
+
+// toc inserts a table of contents field over the outline levels of the
+// headings; Word fills it in when fields are updated on opening.
+func (w *docxWriter) toc() {
+	w.paragraph("IntestazioneIndice", "", "", []inline{{text: "Indice"}}, "")
+	w.body.WriteString(`<w:p><w:r><w:fldChar w:fldCharType="begin" w:dirty="true"/></w:r>` +
+		`<w:r><w:instrText xml:space="preserve"> TOC \o "1-6" \h \z \u </w:instrText></w:r>` +
+		`<w:r><w:fldChar w:fldCharType="separate"/></w:r>` +
+		`<w:r><w:t>Aggiornare il campo per generare l'indice.</w:t></w:r>` +
+		`<w:r><w:fldChar w:fldCharType="end"/></w:r></w:p>`)
+}
+
+func (w *docxWriter) archive(doc *document.Document) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	var rels strings.Builder
+	rels.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rIdStyles" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`<Relationship Id="rIdSettings" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/settings" Target="settings.xml"/>` +
+		`<Relationship Id="rIdFootnotes" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/footnotes" Target="footnotes.xml"/>`)
+	for _, l := range w.links {
+		rels.WriteString(`<Relationship Id="` + l.id + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="` + xmlAttr(l.target) + `" TargetMode="External"/>`)
+	}
+	contentTypes, sectPr := docxContentTypes, docxSectPr
+	var letterhead []struct{ name, content string }
+	if header, footer := w.opts.Branding.HeaderText(doc), w.opts.Branding.FooterText(doc); header != "" || footer != "" {
+		rels.WriteString(`<Relationship Id="rIdHeader" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/header" Target="header1.xml"/>` +
+			`<Relationship Id="rIdFooter" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/footer" Target="footer1.xml"/>`)
+		contentTypes = strings.Replace(contentTypes, "</Types>",
+			`<Override PartName="/word/header1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"/>`+"\n"+
+				`<Override PartName="/word/footer1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footer+xml"/>`+"\n</Types>", 1)
+		sectPr = strings.Replace(sectPr, "<w:sectPr>", `<w:sectPr><w:headerReference w:type="default" r:id="rIdHeader"/><w:footerReference w:type="default" r:id="rIdFooter"/>`, 1)
+		letterhead = []struct{ name, content string }{
+			{"word/header1.xml", xml.Header + `<w:hdr ` + docxNamespaces + `><w:p><w:pPr><w:pStyle w:val="Intestazione"/></w:pPr>` + w.run(header, "") + `</w:p></w:hdr>`},
+			{"word/footer1.xml", xml.Header + `<w:ftr ` + docxNamespaces + `><w:p><w:pPr><w:pStyle w:val="PieDiPagina"/></w:pPr>` + w.run(footer, "") + `<w:r><w:tab/></w:r>` +
+				`<w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText xml:space="preserve"> PAGE </w:instrText></w:r><w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>1</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r>` +
+				`</w:p></w:ftr>`},
+		}
+	}
+	var comments []struct{ name, content string }
+	if w.comments.Len() > 0 {
+		rels.WriteString(`<Relationship Id="rIdComments" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments" Target="comments.xml"/>`)
+		contentTypes = strings.Replace(contentTypes, "</Types>",
+			`<Override PartName="/word/comments.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.comments+xml"/>`+"\n</Types>", 1)
+		comments = []struct{ name, content string }{
+			{"word/comments.xml", xml.Header + `<w:comments ` + docxNamespaces + `>` + w.comments.String() + `</w:comments>`},
+		}
+	}
+	rels.WriteString(`</Relationships>`)
+
+	styles := docxStyles
+	if w.opts.Template != nil {
+		var err error
+		if styles, err = templateStyles(w.opts.Template); err != nil {
+			return nil, err
+		}
+	}
+
+	updateFields := ""
+	if w.opts.TOC {
+		updateFields = `<w:updateFields w:val="true"/>`
+	}
+
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", docxPackageRels},
+		{"docProps/core.xml", fmt.Sprintf(docxCore, xmlText(doc.Title), xmlText(doc.URN), time.Now().UTC().Format(time.RFC3339))},
+		{"word/_rels/document.xml.rels", rels.String()},
+		{"word/document.xml", xml.Header + `<w:document ` + docxNamespaces + `><w:body>` + w.body.String() + sectPr + `</w:body></w:document>`},
+		{"word/styles.xml", styles},
+		{"word/settings.xml", fmt.Sprintf(docxSettings, updateFields)},
+		{"word/footnotes.xml", xml.Header + `<w:footnotes ` + docxNamespaces + `>` + docxFootnoteSeparators + w.footnotes.String() + `</w:footnotes>`},
+	}
+	for _, f := range append(append(files, letterhead...), comments...) {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write([]byte(f.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CheckTemplate returns an error unless data is a DOCX or DOTX usable as a
+// house template.
+func CheckTemplate(data []byte) error {
+	_, err := templateStyles(data)
+	return err
+}
+
+// templateStyles returns the styles of a DOCX or DOTX template, completed
+// with the default definition of any style the output uses and the template
+// lacks.
+func templateStyles(template []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(template), int64(len(template)))
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX template: %w", err)
+	}
+	f, err := zr.Open("word/styles.xml")
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX template: %w", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX template: %w", err)
+	}
+
+	styles := string(data)
+	end := strings.LastIndex(styles, "</w:styles>")
+	if end < 0 {
+		return "", fmt.Errorf("invalid DOCX template: no styles")
+	}
+	defined := map[string]bool{}
+	for _, m := range docxStyleRe.FindAllStringSubmatch(styles, -1) {
+		defined[m[1]] = true
+	}
+	var missing strings.Builder
+	for _, m := range docxStyleRe.FindAllStringSubmatch(docxStyles, -1) {
+		if !defined[m[1]] {
+			missing.WriteString(m[0])
+		}
+	}
+	return styles[:end] + missing.String() + styles[end:], nil
+}
+
+// xmlText escapes s for XML character data, dropping characters XML forbids.
+func xmlText(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || r >= 0x20 && r != 0xFFFE && r != 0xFFFF {
+			return r
+		}
+		return -1
+	}, s)))
+	return buf.String()
+}
+
+func xmlAttr(s string) string {
+	return strings.ReplaceAll(xmlText(s), `"`, "&quot;")
+}
+
+const docxNamespaces = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
+<Override PartName="/word/settings.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.settings+xml"/>
+<Override PartName="/word/footnotes.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footnotes+xml"/>
+<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>
+</Types>`
+
+const docxPackageRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>
+</Relationships>`
+
+const docxCore = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+<dc:title>%s</dc:title>
+<dc:identifier>%s</dc:identifier>
+<dc:language>it-IT</dc:language>
+<dcterms:created xsi:type="dcterms:W3CDTF">%s</dcterms:created>
+</cp:coreProperties>`
+
+const docxSettings = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:settings xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+%s<w:defaultTabStop w:val="708"/>
+<w:footnotePr><w:footnote w:id="-1"/><w:footnote w:id="0"/></w:footnotePr>
+<w:compat><w:compatSetting w:name="compatibilityMode" w:uri="http://schemas.microsoft.com/office/word" w:val="15"/></w:compat>
+</w:settings>`
+
+const docxFootnoteSeparators = `<w:footnote w:type="separator" w:id="-1"><w:p><w:r><w:separator/></w:r></w:p></w:footnote>` +
+	`<w:footnote w:type="continuationSeparator" w:id="0"><w:p><w:r><w:continuationSeparator/></w:r></w:p></w:footnote>`
+
+// A4 with 2 cm margins
+const docxSectPr = `<w:sectPr><w:footnotePr><w:numFmt w:val="decimal"/></w:footnotePr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1134" w:right="1134" w:bottom="1134" w:left="1134" w:header="709" w:footer="709" w:gutter="0"/></w:sectPr>`
+
+const docxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:docDefaults>
+<w:rPrDefault><w:rPr><w:rFonts w:ascii="Times New Roman" w:hAnsi="Times New Roman" w:cs="Times New Roman"/><w:sz w:val="24"/><w:lang w:val="it-IT"/></w:rPr></w:rPrDefault>
+<w:pPrDefault><w:pPr><w:spacing w:after="120" w:line="276" w:lineRule="auto"/><w:jc w:val="both"/></w:pPr></w:pPrDefault>
+</w:docDefaults>
+<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>
+<w:style w:type="paragraph" w:styleId="TitoloAtto"><w:name w:val="Titolo atto"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:jc w:val="center"/><w:spacing w:after="240"/></w:pPr><w:rPr><w:b/><w:sz w:val="32"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Vigenza"><w:name w:val="Vigenza"/><w:basedOn w:val="Normal"/><w:qFormat/><w:pPr><w:jc w:val="center"/><w:spacing w:after="360"/></w:pPr><w:rPr><w:i/><w:color w:val="5F6368"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Atto"><w:name w:val="Atto"/><w:basedOn w:val="Normal"/><w:next w:val="Frontespizio"/><w:qFormat/><w:pPr><w:keepNext/><w:pageBreakBefore/><w:jc w:val="center"/><w:spacing w:before="3600" w:after="480"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:color w:val="1D4E89"/><w:sz w:val="36"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Frontespizio"><w:name w:val="Frontespizio"/><w:basedOn w:val="Normal"/><w:qFormat/><w:pPr><w:jc w:val="center"/><w:spacing w:after="120"/></w:pPr><w:rPr><w:i/><w:color w:val="5F6368"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Libro"><w:name w:val="Libro"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:pageBreakBefore/><w:jc w:val="center"/><w:spacing w:before="480" w:after="240"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:caps/><w:sz w:val="30"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Parte"><w:name w:val="Parte"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:jc w:val="center"/><w:spacing w:before="480" w:after="240"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:b/><w:caps/><w:sz w:val="28"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Titolo"><w:name w:val="Titolo"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:jc w:val="center"/><w:spacing w:before="360" w:after="200"/><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:b/><w:caps/><w:sz w:val="26"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Capo"><w:name w:val="Capo"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:jc w:val="center"/><w:spacing w:before="360" w:after="200"/><w:outlineLvl w:val="3"/></w:pPr><w:rPr><w:b/><w:sz w:val="26"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Sezione"><w:name w:val="Sezione"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:jc w:val="center"/><w:spacing w:before="240" w:after="160"/><w:outlineLvl w:val="4"/></w:pPr><w:rPr><w:b/><w:i/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Partizione"><w:name w:val="Partizione"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:spacing w:before="240" w:after="160"/></w:pPr><w:rPr><w:b/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Articolo"><w:name w:val="Articolo"/><w:basedOn w:val="Normal"/><w:next w:val="Comma"/><w:qFormat/><w:pPr><w:keepNext/><w:spacing w:before="240" w:after="120"/><w:jc w:val="left"/><w:outlineLvl w:val="5"/></w:pPr><w:rPr><w:b/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Comma"><w:name w:val="Comma"/><w:basedOn w:val="Normal"/><w:qFormat/></w:style>
+<w:style w:type="paragraph" w:styleId="Lettera"><w:name w:val="Lettera"/><w:basedOn w:val="Comma"/><w:qFormat/><w:pPr><w:ind w:left="567"/></w:pPr></w:style>
+<w:style w:type="paragraph" w:styleId="Numero"><w:name w:val="Numero"/><w:basedOn w:val="Comma"/><w:qFormat/><w:pPr><w:ind w:left="1134"/></w:pPr></w:style>
+<w:style w:type="paragraph" w:styleId="Elenco"><w:name w:val="Elenco"/><w:basedOn w:val="Comma"/><w:qFormat/></w:style>
+<w:style w:type="paragraph" w:styleId="Citazione"><w:name w:val="Citazione"/><w:basedOn w:val="Comma"/><w:qFormat/><w:rPr><w:i/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="NotaAggiornamento"><w:name w:val="Nota aggiornamento"/><w:basedOn w:val="Normal"/><w:qFormat/><w:pPr><w:pBdr><w:left w:val="single" w:sz="12" w:space="8" w:color="D0D4D9"/></w:pBdr><w:ind w:left="284"/><w:spacing w:after="60"/></w:pPr><w:rPr><w:color w:val="5F6368"/><w:sz w:val="20"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="TestoNota"><w:name w:val="footnote text"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:rPr><w:sz w:val="18"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="TestoTabella"><w:name w:val="Testo tabella"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:after="0"/><w:jc w:val="left"/></w:pPr><w:rPr><w:sz w:val="20"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="IntestazioneIndice"><w:name w:val="TOC Heading"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="240" w:after="120"/></w:pPr><w:rPr><w:b/><w:sz w:val="28"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Intestazione"><w:name w:val="header"/><w:basedOn w:val="Normal"/><w:pPr><w:pBdr><w:bottom w:val="single" w:sz="8" w:space="4" w:color="1D4E89"/></w:pBdr><w:spacing w:after="0"/><w:jc w:val="left"/></w:pPr><w:rPr><w:b/><w:color w:val="1D4E89"/><w:sz w:val="18"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="PieDiPagina"><w:name w:val="footer"/><w:basedOn w:val="Normal"/><w:pPr><w:tabs><w:tab w:val="right" w:pos="9638"/></w:tabs><w:spacing w:after="0"/><w:jc w:val="left"/></w:pPr><w:rPr><w:color w:val="5F6368"/><w:sz w:val="16"/></w:rPr></w:style>
+<w:style w:type="character" w:default="1" w:styleId="DefaultParagraphFont"><w:name w:val="Default Paragraph Font"/><w:uiPriority w:val="1"/><w:semiHidden/></w:style>
+<w:style w:type="character" w:styleId="NumeroComma"><w:name w:val="Numero comma"/><w:basedOn w:val="DefaultParagraphFont"/><w:qFormat/><w:rPr><w:b/></w:rPr></w:style>
+<w:style w:type="character" w:styleId="TestoModificato"><w:name w:val="Testo modificato"/><w:basedOn w:val="DefaultParagraphFont"/><w:qFormat/><w:rPr><w:b/></w:rPr></w:style>
+<w:style w:type="character" w:styleId="Collegamento"><w:name w:val="Hyperlink"/><w:basedOn w:val="DefaultParagraphFont"/><w:rPr><w:color w:val="1D4E89"/><w:u w:val="single"/></w:rPr></w:style>
+<w:style w:type="character" w:styleId="RifNota"><w:name w:val="footnote reference"/><w:basedOn w:val="DefaultParagraphFont"/><w:rPr><w:vertAlign w:val="superscript"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="TestoCommento"><w:name w:val="annotation text"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:rPr><w:sz w:val="20"/></w:rPr></w:style>
+<w:style w:type="character" w:styleId="RifCommento"><w:name w:val="annotation reference"/><w:basedOn w:val="DefaultParagraphFont"/><w:rPr><w:sz w:val="16"/></w:rPr></w:style>
+<w:style w:type="table" w:default="1" w:styleId="TableNormal"><w:name w:val="Normal Table"/><w:semiHidden/><w:tblPr><w:tblInd w:w="0" w:type="dxa"/><w:tblCellMar><w:top w:w="0" w:type="dxa"/><w:left w:w="108" w:type="dxa"/><w:bottom w:w="0" w:type="dxa"/><w:right w:w="108" w:type="dxa"/></w:tblCellMar></w:tblPr></w:style>
+<w:style w:type="table" w:styleId="Tabella"><w:name w:val="Tabella"/><w:basedOn w:val="TableNormal"/><w:tblPr><w:tblBorders><w:top w:val="single" w:sz="4" w:space="0" w:color="A0A4A9"/><w:left w:val="single" w:sz="4" w:space="0" w:color="A0A4A9"/><w:bottom w:val="single" w:sz="4" w:space="0" w:color="A0A4A9"/><w:right w:val="single" w:sz="4" w:space="0" w:color="A0A4A9"/><w:insideH w:val="single" w:sz="4" w:space="0" w:color="A0A4A9"/><w:insideV w:val="single" w:sz="4" w:space="0" w:color="A0A4A9"/></w:tblBorders></w:tblPr></w:style>
+</w:styles>`
@@ -0,0 +1,93 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// zipParts unzips a DOCX or EPUB and checks every part is well-formed XML.
+func zipParts(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		dec := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %v", f.Name, err)
+			}
+		}
+		parts[f.Name] = string(content)
+	}
+	return parts
+}
+
+func TestDOCX(t *testing.T) {
+	out, err := DOCX(exportDocument(), Options{MarkdownOptions: document.MarkdownOptions{Links: document.LinkStandalone}, TOC: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := zipParts(t, out)
+	body := parts["word/document.xml"]
+
+	for _, want := range []string{
+		`<w:pStyle w:val="Capo"/><w:outlineLvl w:val="3"/>`,
+		`<w:pStyle w:val="Articolo"/><w:outlineLvl w:val="5"/>`,
+		`w:name="capo_I"`,
+		`w:name="art_1__para_1"`,
+		`<w:rStyle w:val="NumeroComma"/></w:rPr><w:t xml:space="preserve">1. </w:t>`,
+		`<w:rStyle w:val="TestoModificato"/></w:rPr><w:t xml:space="preserve">((pubblici))</w:t>`,
+		`<w:footnoteReference w:id="1"/>`,
+		`<w:hyperlink w:anchor="art_2">`,
+		`<w:pStyle w:val="Lettera"/>`,
+		`i contratti &lt;segreti&gt;;`,
+		`<w:tblHeader/>`,
+		`5.382.000 | euro`,
+		` TOC \o "1-6" \h \z \u `,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in document.xml", want)
+		}
+	}
+	if !strings.Contains(parts["word/footnotes.xml"], "ha disposto la modifica dell&#39;art. 1, comma 1.") {
+		t.Errorf("update note not in footnotes:\n%s", parts["word/footnotes.xml"])
+	}
+	if strings.Contains(body, "NotaAggiornamento") {
+		t.Errorf("referenced note should only be a footnote")
+	}
+	if !strings.Contains(parts["word/styles.xml"], `w:styleId="Comma"`) {
+		t.Errorf("missing Comma style")
+	}
+
+	// A house template keeps its own styles and gets the missing ones
+	var tmpl bytes.Buffer
+	zw := zip.NewWriter(&tmpl)
+	fw, _ := zw.Create("word/styles.xml")
+	fw.Write([]byte(`<?xml version="1.0"?><w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:style w:type="paragraph" w:styleId="Articolo"><w:name w:val="Articolo"/><w:rPr><w:color w:val="FF0000"/></w:rPr></w:style></w:styles>`))
+	zw.Close()
+	out, err = DOCX(exportDocument(), Options{Template: tmpl.Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	styles := zipParts(t, out)["word/styles.xml"]
+	if strings.Count(styles, `w:styleId="Articolo"`) != 1 || !strings.Contains(styles, "FF0000") || !strings.Contains(styles, `w:styleId="Capo"`) {
+		t.Errorf("template styles not merged:\n%s", styles)
+	}
+}
@@ -0,0 +1,308 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func init() {
+	builtin.Register(NewExporter(Format{Name: "jsonld", Label: "JSON-LD (ELI)", MIMEType: "application/ld+json", Extension: "jsonld"}, JSONLD))
+	builtin.Register(NewExporter(Format{Name: "turtle", Label: "Turtle (ELI)", MIMEType: "text/turtle; charset=utf-8", Extension: "ttl", Aliases: []string{"ttl"}}, Turtle))
+}
+
+const (
+	eliNamespace = "http://data.europa.eu/eli/ontology#"
+	xsdNamespace = "http://www.w3.org/2001/XMLSchema#"
+	// eliItalian is the EU authority table entry for Italian, the value of
+	// eli:language.
+	eliItalian = "http://publications.europa.eu/resource/authority/language/ITA"
+)
+
+// errNoURN is returned for documents the ELI graph cannot identify, such as
+// compilations.
+var errNoURN = errors.New("linked data needs the URN of the act")
+
+// eliValue is the object of a triple: an IRI, or a literal with an optional
+// datatype ("xsd:date") or language.
+type eliValue struct {
+	iri, literal, datatype, lang string
+}
+
+// eliNode is a subject of the graph with its properties in insertion order;
+// values repeated for the same property are dropped.
+type eliNode struct {
+	id    string
+	types []string
+	props []string // property names, in order
+	vals  map[string][]eliValue
+}
+
+func newELINode(id string, types ...string) *eliNode {
+	return &eliNode{id: id, types: types, vals: map[string][]eliValue{}}
+}
+
+func (n *eliNode) add(prop string, v eliValue) {
+	if v.iri == "" && v.literal == "" {
+		return
+	}
+	if v.iri == n.id {
+		return // an article citing itself
+	}
+	for _, old := range n.vals[prop] {
+		if old == v {
+			return
+		}
+	}
+	if _, ok := n.vals[prop]; !ok {
+		n.props = append(n.props, prop)
+	}
+	n.vals[prop] = append(n.vals[prop], v)
+}
+
+func (n *eliNode) link(prop, iri string) { n.add(prop, eliValue{iri: iri}) }
+
+func (n *eliNode) date(prop, date string) {
+	if date = isoDate(date); date != "" {
+		n.add(prop, eliValue{literal: date, datatype: "xsd:date"})
+	}
+}
+
+// eliIRI is the IRI of an act or of one of its articles: its URN on the
+// Normattiva resolver, as Normattiva links them.
+func eliIRI(urn, article string) string {
+	return document.Reference{URN: urn, Article: article}.URL()
+}
+
+// eliExpressionIRI is the IRI of the text of work at the vigenza of the
+// document, or of the original text without one.
+func eliExpressionIRI(work, vigenza string) string {
+	if vigenza = isoDate(vigenza); vigenza != "" {
+		return work + "!vig=" + vigenza
+	}
+	return work + "@originale"
+}
+
+// eliTarget is the IRI of the target of ref within the act urn: the
+// resolver IRI for Italian acts, the ELI for EU acts.
+func eliTarget(urn string, ref document.Reference) string {
+	switch {
+	case ref.Kind == document.RefSameAct || (ref.URN != "" && document.ActURN(ref.URN) == document.ActURN(urn)):
+		return eliIRI(urn, ref.Article)
+	case ref.URN != "":
+		return eliIRI(ref.URN, ref.Article)
+	}
+	return ref.ELI
+}
+
+// eliGraph describes doc with the ELI ontology: the act and each of its
+// articles as a eli:LegalResource, realized by a eli:LegalExpression in
+// Italian at the vigenza of the document. Resources carry their dates,
+// eli:has_part/eli:is_part_of, the acts and articles they cite and the
+// modifications recorded in the metadata and in the update notes.
+func eliGraph(doc *document.Document) ([]*eliNode, error) {
+	if !strings.HasPrefix(doc.URN, "urn:") {
+		return nil, errNoURN
+	}
+	urn, _, _ := document.SplitURN(doc.URN)
+	actIRI := eliIRI(urn, "")
+	act := newELINode(actIRI, "eli:LegalResource")
+	expr := newELINode(eliExpressionIRI(actIRI, doc.Vigenza), "eli:LegalExpression")
+	nodes := []*eliNode{act, expr}
+
+	if work, ok := parseAKNWork(urn); ok {
+		act.date("eli:date_document", work.date)
+		if work.number != "0" {
+			act.add("eli:number", eliValue{literal: work.number})
+		}
+	}
+	act.add("eli:id_local", eliValue{literal: doc.CodiceRedazionale})
+	act.date("eli:date_publication", doc.DataGU)
+	act.date("eli:version_date", doc.Vigenza)
+	act.link("eli:is_realized_by", expr.id)
+	expr.link("eli:realizes", act.id)
+	expr.link("eli:language", eliItalian)
+	expr.add("eli:title", eliValue{literal: strings.TrimSpace(doc.Title), lang: "it"})
+
+	// The act cites the other acts its articles cite
+	cite := func(n *eliNode, ref document.Reference) {
+		sameAct := ref.Kind == document.RefSameAct || (ref.URN != "" && document.ActURN(ref.URN) == document.ActURN(urn))
+		if n != act {
+			n.link("eli:cites", eliTarget(urn, ref))
+		}
+		switch {
+		case sameAct:
+		case ref.URN != "":
+			act.link("eli:cites", eliIRI(ref.URN, ""))
+		default:
+			act.link("eli:cites", ref.ELI)
+		}
+	}
+
+	articles := map[string]*eliNode{} // by number
+	err := doc.Walk(func(path []*document.DocumentSection, s *document.DocumentSection) error {
+		if !s.IsArticle() {
+			for _, ref := range s.References {
+				cite(act, ref)
+			}
+			return nil
+		}
+		num, _ := document.EIdTarget(s.ID)
+		if num == "" {
+			return document.SkipSection
+		}
+		art := newELINode(eliIRI(urn, num), "eli:LegalResource", "eli:LegalResourceSubdivision")
+		artExpr := newELINode(eliExpressionIRI(art.id, doc.Vigenza), "eli:LegalExpression")
+		nodes = append(nodes, art, artExpr)
+		articles[num] = art
+
+		act.link("eli:has_part", art.id)
+		art.link("eli:is_part_of", act.id)
+		if s.IsActive() {
+			art.link("eli:in_force", eliNamespace+"InForce-inForce")
+		} else {
+			art.link("eli:in_force", eliNamespace+"InForce-notInForce")
+		}
+		art.link("eli:is_realized_by", artExpr.id)
+		artExpr.link("eli:realizes", art.id)
+		artExpr.link("eli:language", eliItalian)
+		artExpr.add("eli:title", eliValue{literal: plainInline(s.Title), lang: "it"})
+
+		for _, ref := range s.References {
+			cite(art, ref)
+		}
+		for _, note := range s.Updates {
+			if len(note.Amending) > 0 && note.Amending[0].URN != "" {
+				art.link("eli:amended_by", eliIRI(note.Amending[0].URN, ""))
+				act.link("eli:amended_by", eliIRI(note.Amending[0].URN, ""))
+			}
+		}
+		return document.SkipSection
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range doc.Modifications {
+		repeal := m.Type == "repeal"
+		article, _ := document.EIdTarget(m.Target)
+		switch m.Direction {
+		case document.ModActive:
+			if m.TargetURN == "" {
+				continue
+			}
+			prop := "eli:amends"
+			if repeal {
+				prop = "eli:repeals"
+			}
+			target := eliIRI(m.TargetURN, article)
+			act.link(prop, eliIRI(m.TargetURN, ""))
+			if m.Source.Article != "" {
+				if art := articles[m.Source.Article]; art != nil {
+					art.link(prop, target)
+				}
+			}
+		case document.ModPassive:
+			if m.Source.URN == "" {
+				continue
+			}
+			prop := "eli:amended_by"
+			if repeal {
+				prop = "eli:repealed_by"
+			}
+			source := eliIRI(m.Source.URN, "")
+			act.link(prop, source)
+			if art := articles[article]; art != nil {
+				art.link(prop, source)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// JSONLD renders doc as JSON-LD with the ELI ontology; see eliGraph.
+func JSONLD(doc *document.Document, opts Options) ([]byte, error) {
+	nodes, err := eliGraph(doc)
+	if err != nil {
+		return nil, err
+	}
+	graph := make([]map[string]any, 0, len(nodes))
+	for _, n := range nodes {
+		obj := map[string]any{"@id": n.id, "@type": n.types}
+		for _, prop := range n.props {
+			var vals []any
+			for _, v := range n.vals[prop] {
+				switch {
+				case v.iri != "":
+					vals = append(vals, map[string]string{"@id": v.iri})
+				case v.datatype != "":
+					vals = append(vals, map[string]string{"@value": v.literal, "@type": v.datatype})
+				case v.lang != "":
+					vals = append(vals, map[string]string{"@value": v.literal, "@language": v.lang})
+				default:
+					vals = append(vals, v.literal)
+				}
+			}
+			if len(vals) == 1 {
+				obj[prop] = vals[0]
+			} else {
+				obj[prop] = vals
+			}
+		}
+		graph = append(graph, obj)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(map[string]any{
+		"@context": map[string]string{"eli": eliNamespace, "xsd": xsdNamespace},
+		"@graph":   graph,
+	})
+	return buf.Bytes(), err
+}
+
+// Turtle renders doc as RDF Turtle with the ELI ontology; see eliGraph.
+func Turtle(doc *document.Document, opts Options) ([]byte, error) {
+	nodes, err := eliGraph(doc)
+	if err != nil {
+		return nil, err
+	}
+	var sb strings.Builder
+	sb.WriteString("@prefix eli: <" + eliNamespace + "> .\n")
+	sb.WriteString("@prefix xsd: <" + xsdNamespace + "> .\n")
+	for _, n := range nodes {
+		sb.WriteString("\n" + turtleIRI(n.id) + "\n    a " + strings.Join(n.types, ", "))
+		for _, prop := range n.props {
+			terms := make([]string, 0, len(n.vals[prop]))
+			for _, v := range n.vals[prop] {
+				terms = append(terms, turtleTerm(v))
+			}
+			sb.WriteString(" ;\n    " + prop + " " + strings.Join(terms, ", "))
+		}
+		sb.WriteString(" .\n")
+	}
+	return []byte(sb.String()), nil
+}
+
+var turtleIRIEscaper = strings.NewReplacer(" ", "%20", "<", "%3C", ">", "%3E", `"`, "%22", "{", "%7B", "}", "%7D", "|", "%7C", "^", "%5E", "`", "%60", `\`, "%5C")
+
+func turtleIRI(iri string) string { return "<" + turtleIRIEscaper.Replace(iri) + ">" }
+
+var turtleStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+func turtleTerm(v eliValue) string {
+	switch {
+	case v.iri != "":
+		return turtleIRI(v.iri)
+	case v.datatype != "":
+		return `"` + turtleStringEscaper.Replace(v.literal) + `"^^` + v.datatype
+	case v.lang != "":
+		return `"` + turtleStringEscaper.Replace(v.literal) + `"@` + v.lang
+	}
+	return `"` + turtleStringEscaper.Replace(v.literal) + `"`
+}
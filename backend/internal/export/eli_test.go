@@ -0,0 +1,119 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func TestJSONLD(t *testing.T) {
+	doc := parseFixture(t, "akn.xml")
+	doc.DataGU, doc.Vigenza = "20230331", "2025-01-01"
+	out, err := JSONLD(doc, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ld struct {
+		Context map[string]string `json:"@context"`
+		Graph   []map[string]any  `json:"@graph"`
+	}
+	if err := json.Unmarshal(out, &ld); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out)
+	}
+	if ld.Context["eli"] != eliNamespace {
+		t.Errorf("context = %v", ld.Context)
+	}
+	nodes := map[string]map[string]any{}
+	for _, n := range ld.Graph {
+		nodes[n["@id"].(string)] = n
+	}
+
+	const act = "https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:decreto.legislativo:2023-03-31;36"
+	ids := func(v any) []string {
+		var out []string
+		switch v := v.(type) {
+		case map[string]any:
+			out = append(out, v["@id"].(string))
+		case []any:
+			for _, e := range v {
+				out = append(out, e.(map[string]any)["@id"].(string))
+			}
+		}
+		return out
+	}
+	has := func(id, prop, want string) {
+		t.Helper()
+		for _, got := range ids(nodes[id][prop]) {
+			if got == want {
+				return
+			}
+		}
+		t.Errorf("%s %s = %v, want %s", id, prop, nodes[id][prop], want)
+	}
+
+	if nodes[act] == nil {
+		t.Fatalf("no act node in %s", out)
+	}
+	if d := nodes[act]["eli:date_publication"].(map[string]any); d["@value"] != "2023-03-31" || d["@type"] != "xsd:date" {
+		t.Errorf("date_publication = %v", d)
+	}
+	has(act, "eli:has_part", act+"~art1")
+	has(act, "eli:is_realized_by", act+"!vig=2025-01-01")
+	has(act, "eli:cites", "https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:legge:1990-08-07;241")
+	has(act, "eli:amends", "https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:legge:1990-08-07;241")
+	has(act, "eli:amended_by", "https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:decreto.legislativo:2024-12-31;209")
+	has(act+"~art1", "eli:is_part_of", act)
+	has(act+"~art1", "eli:cites", act+"~art2")
+	has(act+"~art2", "eli:amends", "https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:legge:1990-08-07;241~art3")
+	has(act+"~art1!vig=2025-01-01", "eli:language", eliItalian)
+	has(act+"~art1!vig=2025-01-01", "eli:realizes", act+"~art1")
+	if title := nodes[act+"~art1!vig=2025-01-01"]["eli:title"].(map[string]any); title["@value"] != "Art. 1 - Principio del risultato" || title["@language"] != "it" {
+		t.Errorf("article title = %v", title)
+	}
+}
+
+func TestTurtle(t *testing.T) {
+	doc := parseFixture(t, "nir.xml")
+	out, err := Turtle(doc, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ttl := string(out)
+	for _, want := range []string{
+		"@prefix eli: <http://data.europa.eu/eli/ontology#> .",
+		"<https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:legge:1990-08-07;241>\n    a eli:LegalResource ;",
+		`eli:date_document "1990-08-07"^^xsd:date`,
+		"eli:is_part_of <https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:legge:1990-08-07;241> ;",
+		"eli:cites <https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:decreto.legislativo:2005-03-07;82>",
+		"eli:language <http://publications.europa.eu/resource/authority/language/ITA>",
+	} {
+		if !strings.Contains(ttl, want) {
+			t.Errorf("missing %q in:\n%s", want, ttl)
+		}
+	}
+	// Every statement is closed
+	for _, stmt := range strings.Split(strings.TrimSpace(ttl), "\n\n")[1:] {
+		if !strings.HasSuffix(stmt, " .") {
+			t.Errorf("unterminated statement:\n%s", stmt)
+		}
+	}
+}
+
+func TestTurtleTerm(t *testing.T) {
+	if got := turtleTerm(eliValue{literal: "Legge \"Bassanini\"\nbis", lang: "it"}); got != `"Legge \"Bassanini\"\nbis"@it` {
+		t.Errorf("literal = %s", got)
+	}
+	if got := turtleTerm(eliValue{iri: "http://example.org/a b"}); got != "<http://example.org/a%20b>" {
+		t.Errorf("iri = %s", got)
+	}
+}
+
+func TestELINeedsURN(t *testing.T) {
+	doc := document.NewDocument("", "", "", "")
+	if _, err := JSONLD(&doc, Options{}); !errors.Is(err, errNoURN) {
+		t.Errorf("err = %v, want errNoURN", err)
+	}
+}
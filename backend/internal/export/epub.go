@@ -0,0 +1,290 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func init() {
+	builtin.Register(NewExporter(Format{Name: "epub", Label: "EPUB", MIMEType: "application/epub+zip", Extension: "epub"}, EPUB))
+}
+
+var epubHrefRe = regexp.MustCompile(`href="#([^"]*)"`)
+
+// epubFile is a content document of the package: one per top-level section.
+type epubFile struct {
+	name    string
+	title   string
+	section document.RenderedSection
+}
+
+// EPUB renders doc as an EPUB 3 package: a title page, one XHTML file per
+// top-level section and a nav document following the section hierarchy.
+// Links between sections point into the file holding their target.
+func EPUB(doc *document.Document, opts Options) ([]byte, error) {
+	var files []epubFile
+	var collect func(s document.RenderedSection)
+	collect = func(s document.RenderedSection) {
+		// Untitled wrappers around the real units don't get a file of their own
+		if s.Title == "" && len(s.Content) == 0 && len(s.Notes) == 0 {
+			for _, child := range s.Children {
+				collect(child)
+			}
+			return
+		}
+		title := plainInline(s.Title)
+		if title == "" && s.Type == "preamble" {
+			title = "Preambolo"
+		}
+		files = append(files, epubFile{name: fmt.Sprintf("s%03d.xhtml", len(files)+1), title: title, section: s})
+	}
+	sections, annotations := renderSections(doc, opts)
+	for _, s := range sections {
+		collect(s)
+	}
+
+	// File holding each ID, to point links across files
+	location := map[string]string{}
+	for _, f := range files {
+		epubTargets(f.section, func(id string) {
+			if _, ok := location[id]; !ok {
+				location[id] = f.name
+			}
+		})
+	}
+
+	stylesheet := defaultStylesheet
+	if opts.Stylesheet != "" {
+		stylesheet += "\n" + opts.Stylesheet
+	}
+	title := doc.Title
+	if title == "" {
+		title = doc.Name
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	// The mimetype must come first, uncompressed
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mw.Write([]byte("application/epub+zip")); err != nil {
+		return nil, err
+	}
+
+	parts := []struct{ name, content string }{
+		{"META-INF/container.xml", epubContainer},
+		{"OEBPS/style.css", stylesheet},
+		{"OEBPS/title.xhtml", epubTitlePage(doc, title, opts.Branding)},
+		{"OEBPS/nav.xhtml", epubNav(files)},
+		{"OEBPS/content.opf", epubPackage(doc, title, files)},
+	}
+	for _, f := range files {
+		var body bytes.Buffer
+		if err := htmlTemplate.ExecuteTemplate(&body, "section", htmlSectionOf(f.section, annotations)); err != nil {
+			return nil, err
+		}
+		content := epubHrefRe.ReplaceAllStringFunc(body.String(), func(m string) string {
+			id := epubHrefRe.FindStringSubmatch(m)[1]
+			if file, ok := location[id]; ok && file != f.name {
+				return `href="` + file + `#` + id + `"`
+			}
+			return m
+		})
+		parts = append(parts, struct{ name, content string }{"OEBPS/" + f.name, epubXHTML(f.title, content)})
+	}
+
+	for _, p := range parts {
+		fw, err := zw.Create(p.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write([]byte(p.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// epubTargets calls fn with every ID s and its descendants render: sections,
+// commas, update notes and anchors in the text.
+func epubTargets(s document.RenderedSection, fn func(id string)) {
+	if s.ID != "" {
+		fn(s.ID)
+		for _, c := range groupCommas(s.Content) {
+			if id := commaID(s.ID, c.Num); id != "" {
+				fn(id)
+			}
+		}
+		for _, note := range s.Notes {
+			fn(noteID(s.ID, note.Number))
+		}
+	}
+	for _, content := range s.Content {
+		for _, r := range parseInline(content) {
+			if r.anchor != "" {
+				fn(r.anchor)
+			}
+		}
+	}
+	for _, child := range s.Children {
+		epubTargets(child, fn)
+	}
+}
+
+func epubXHTML(title, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="it" lang="it">
+<head>
+<meta charset="utf-8"/>
+<title>` + xmlText(title) + `</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+` + body + `
+</body>
+</html>
+`
+}
+
+func epubTitlePage(doc *document.Document, title string, branding Branding) string {
+	var sb strings.Builder
+	if header := branding.HeaderText(doc); header != "" {
+		sb.WriteString(`<div class="letterhead">` + xmlText(header) + "</div>\n")
+	}
+	sb.WriteString(`<header class="act-header" id="preamble">`)
+	sb.WriteString("\n<h1>" + xmlText(title) + "</h1>")
+	if doc.Vigenza != "" {
+		sb.WriteString("\n<p class=\"vigenza\">Testo in vigore al: " + displayDate(doc.Vigenza) + "</p>")
+	}
+	if gu := isoDate(doc.DataGU); gu != "" {
+		sb.WriteString("\n<p class=\"vigenza\">Gazzetta Ufficiale del " + displayDate(gu) + "</p>")
+	}
+	if doc.URN != "" {
+		sb.WriteString("\n<p class=\"vigenza\">" + xmlText(doc.URN) + "</p>")
+	}
+	sb.WriteString("\n</header>")
+	if footer := branding.FooterText(doc); footer != "" {
+		sb.WriteString("\n<footer class=\"letterhead-footer\">" + xmlText(footer) + "</footer>")
+	}
+	return epubXHTML(title, sb.String())
+}
+
+// epubNav builds the nav document: a nested list of the titled sections.
+func epubNav(files []epubFile) string {
+	var sb strings.Builder
+	sb.WriteString(`<nav epub:type="toc" id="toc">` + "\n<h1>Indice</h1>\n<ol>\n")
+	sb.WriteString(`<li><a href="title.xhtml">Frontespizio</a></li>` + "\n")
+	for _, f := range files {
+		if f.section.Title == "" {
+			sb.WriteString(`<li><a href="` + f.name + `">` + xmlText(f.title) + "</a>")
+			if children := epubNavList(f.name, f.section.Children); children != "" {
+				sb.WriteString("<ol>" + children + "</ol>")
+			}
+			sb.WriteString("</li>\n")
+			continue
+		}
+		sb.WriteString(epubNavList(f.name, []document.RenderedSection{f.section}) + "\n")
+	}
+	sb.WriteString("</ol>\n</nav>")
+	return epubXHTML("Indice", sb.String())
+}
+
+// epubNavList renders the entries for sections, without the surrounding <ol>
+// at the top of a file; untitled sections pass their children up.
+func epubNavList(file string, sections []document.RenderedSection) string {
+	var sb strings.Builder
+	for _, s := range sections {
+		if s.Title == "" {
+			sb.WriteString(epubNavList(file, s.Children))
+			continue
+		}
+		href := file
+		if s.ID != "" {
+			href += "#" + s.ID
+		}
+		sb.WriteString(`<li><a href="` + xmlAttr(href) + `">` + xmlText(plainInline(s.Title)) + "</a>")
+		if children := epubNavList(file, s.Children); children != "" {
+			sb.WriteString("<ol>" + children + "</ol>")
+		}
+		sb.WriteString("</li>")
+	}
+	return sb.String()
+}
+
+func epubPackage(doc *document.Document, title string, files []epubFile) string {
+	// The identifier names the expression: the act at its vigenza date
+	identifier := doc.URN
+	if identifier == "" {
+		identifier = "urn:normattiva:" + doc.CodiceRedazionale
+	}
+	if doc.Vigenza != "" {
+		identifier += "!vig=" + doc.Vigenza
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="pub-id" xml:lang="it">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:identifier id="pub-id">` + xmlText(identifier) + `</dc:identifier>
+<dc:title>` + xmlText(title) + `</dc:title>
+<dc:language>it</dc:language>
+`)
+	if doc.URN != "" {
+		sb.WriteString(`<dc:source>` + xmlText(doc.URN) + "</dc:source>\n")
+	}
+	if gu := isoDate(doc.DataGU); gu != "" {
+		sb.WriteString(`<dc:date>` + gu + "</dc:date>\n")
+		sb.WriteString(`<meta property="dcterms:issued">` + gu + "</meta>\n")
+	}
+	if doc.Vigenza != "" {
+		sb.WriteString(`<meta property="dcterms:valid">` + xmlText(doc.Vigenza) + "</meta>\n")
+	}
+	sb.WriteString(`<meta property="dcterms:modified">` + time.Now().UTC().Format("2006-01-02T15:04:05Z") + "</meta>\n")
+	sb.WriteString(`</metadata>
+<manifest>
+<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+<item id="css" href="style.css" media-type="text/css"/>
+<item id="title" href="title.xhtml" media-type="application/xhtml+xml"/>
+`)
+	for _, f := range files {
+		sb.WriteString(`<item id="` + strings.TrimSuffix(f.name, ".xhtml") + `" href="` + f.name + `" media-type="application/xhtml+xml"/>` + "\n")
+	}
+	sb.WriteString("</manifest>\n<spine>\n<itemref idref=\"title\"/>\n")
+	for _, f := range files {
+		sb.WriteString(`<itemref idref="` + strings.TrimSuffix(f.name, ".xhtml") + `"/>` + "\n")
+	}
+	sb.WriteString("</spine>\n</package>\n")
+	return sb.String()
+}
+
+// isoDate normalizes YYYYMMDD and YYYY-MM-DD dates to YYYY-MM-DD, or returns
+// "" for anything else.
+func isoDate(date string) string {
+	if t, err := time.Parse("2006-01-02", date); err == nil {
+		return t.Format("2006-01-02")
+	}
+	if t, err := time.Parse("20060102", date); err == nil {
+		return t.Format("2006-01-02")
+	}
+	return ""
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func TestEPUB(t *testing.T) {
+	doc := exportDocument()
+	doc.DataGU = "20230331"
+	doc.Sections = append(doc.Sections, document.DocumentSection{
+		ID: "capo_II", Type: "chapter", Title: "Capo II - Disposizioni finali", Children: []document.DocumentSection{
+			{ID: "art_3", Type: "article", Title: "Art. 3 - Rinvio", Content: []string{"1\\. Resta fermo l'[articolo 1, comma 1](#art_1__para_1)."}},
+		},
+	})
+	out, err := EPUB(doc, Options{MarkdownOptions: document.MarkdownOptions{Links: document.LinkStandalone}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := zr.File[0]; first.Name != "mimetype" || first.Method != zip.Store {
+		t.Fatalf("mimetype must be the first, stored entry: %s", first.Name)
+	}
+	files := zipParts(t, out)
+
+	opf := files["OEBPS/content.opf"]
+	for _, want := range []string{
+		`<dc:identifier id="pub-id">urn:nir:stato:decreto.legislativo:2023-03-31;36!vig=2025-01-01</dc:identifier>`,
+		`<dc:title>DECRETO LEGISLATIVO 31 marzo 2023, n. 36</dc:title>`,
+		`<meta property="dcterms:issued">2023-03-31</meta>`,
+		`<meta property="dcterms:valid">2025-01-01</meta>`,
+		`<item id="s002" href="s002.xhtml" media-type="application/xhtml+xml"/>`,
+		`properties="nav"`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("missing %q in content.opf:\n%s", want, opf)
+		}
+	}
+
+	nav := files["OEBPS/nav.xhtml"]
+	for _, want := range []string{
+		`<li><a href="s001.xhtml#capo_I">Capo I - Principi</a><ol><li><a href="s001.xhtml#art_1">Art. 1 - Oggetto</a></li>`,
+		`<a href="s002.xhtml#art_3">Art. 3 - Rinvio</a>`,
+	} {
+		if !strings.Contains(nav, want) {
+			t.Errorf("missing %q in nav:\n%s", want, nav)
+		}
+	}
+
+	if s := files["OEBPS/s002.xhtml"]; !strings.Contains(s, `href="s001.xhtml#art_1__para_1"`) {
+		t.Errorf("cross-file link not rewritten:\n%s", s)
+	}
+	if s := files["OEBPS/s001.xhtml"]; !strings.Contains(s, `<a href="#art_2">articolo 2</a>`) {
+		t.Errorf("same-file link should stay relative:\n%s", s)
+	}
+}
@@ -0,0 +1,126 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// ErrUnsupportedFormat is returned for formats no exporter is registered for.
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// Format describes an export format.
+type Format struct {
+	Name      string   `json:"name"` // value of the format parameter
+	Label     string   `json:"label"`
+	MIMEType  string   `json:"mimeType"`
+	Extension string   `json:"extension"`
+	Aliases   []string `json:"aliases,omitempty"`
+}
+
+// Output is an exported document.
+type Output struct {
+	Data      []byte
+	MIMEType  string
+	Extension string
+}
+
+// Exporter converts a document to one format.
+type Exporter interface {
+	Format() Format
+	Export(doc *document.Document, opts Options) (*Output, error)
+}
+
+// RenderFunc renders a document to bytes; NewExporter turns one into an
+// Exporter.
+type RenderFunc func(doc *document.Document, opts Options) ([]byte, error)
+
+type funcExporter struct {
+	format Format
+	render RenderFunc
+}
+
+// NewExporter returns an Exporter for format rendered by render.
+func NewExporter(format Format, render RenderFunc) Exporter {
+	return &funcExporter{format: format, render: render}
+}
+
+func (e *funcExporter) Format() Format { return e.format }
+
+func (e *funcExporter) Export(doc *document.Document, opts Options) (*Output, error) {
+	data, err := e.render(doc, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Output{Data: data, MIMEType: e.format.MIMEType, Extension: e.format.Extension}, nil
+}
+
+// Registry holds the exporters by format name and alias.
+type Registry struct {
+	mu        sync.RWMutex
+	exporters map[string]Exporter
+	aliases   map[string]string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{exporters: map[string]Exporter{}, aliases: map[string]string{}}
+}
+
+// Register adds e under its format name and aliases, replacing any exporter
+// registered for the same name.
+func (r *Registry) Register(e Exporter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := e.Format()
+	r.exporters[f.Name] = e
+	for _, alias := range f.Aliases {
+		r.aliases[alias] = f.Name
+	}
+}
+
+// Lookup returns the exporter for a format name or alias.
+func (r *Registry) Lookup(name string) (Exporter, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if canonical, ok := r.aliases[name]; ok {
+		name = canonical
+	}
+	e, ok := r.exporters[name]
+	return e, ok
+}
+
+// Formats lists the registered formats by name.
+func (r *Registry) Formats() []Format {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	formats := make([]Format, 0, len(r.exporters))
+	for _, e := range r.exporters {
+		formats = append(formats, e.Format())
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i].Name < formats[j].Name })
+	return formats
+}
+
+// Export converts doc with the exporter registered for format.
+func (r *Registry) Export(doc *document.Document, format string, opts Options) (*Output, error) {
+	e, ok := r.Lookup(format)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	return e.Export(doc, opts)
+}
+
+// builtin holds the native exporters, registered by the file implementing
+// each format; every Service starts from them.
+var builtin = NewRegistry()
+
+func (r *Registry) merge(from *Registry) {
+	from.mu.RLock()
+	defer from.mu.RUnlock()
+	for _, e := range from.exporters {
+		r.Register(e)
+	}
+}
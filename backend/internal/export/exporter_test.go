@@ -0,0 +1,47 @@
+package export
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func TestRegistry(t *testing.T) {
+	s := NewService()
+	var names []string
+	for _, f := range s.Formats() {
+		names = append(names, f.Name)
+	}
+	list := strings.Join(names, ",")
+	for _, want := range []string{"docx", "epub", "html", "markdown", "pdf"} {
+		if !strings.Contains(list, want) {
+			t.Errorf("format %s not registered: %s", want, list)
+		}
+	}
+
+	out, err := s.ExportDocument(exportDocument(), "md", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Extension != "md" || !strings.HasPrefix(out.MIMEType, "text/markdown") || !strings.Contains(string(out.Data), "# Capo I - Principi") {
+		t.Errorf("unexpected markdown output: %s %s\n%s", out.Extension, out.MIMEType, out.Data)
+	}
+
+	if _, err := s.ExportDocument(exportDocument(), "wordperfect", Options{}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+
+	// Services register formats of their own without touching the others
+	s.Register(NewExporter(Format{Name: "txt", MIMEType: "text/plain", Extension: "txt"}, func(doc *document.Document, opts Options) ([]byte, error) {
+		return []byte(doc.Title), nil
+	}))
+	out, err = s.ExportDocument(exportDocument(), "txt", Options{})
+	if err != nil || string(out.Data) != "DECRETO LEGISLATIVO 31 marzo 2023, n. 36" {
+		t.Errorf("custom exporter: %v %q", err, out)
+	}
+	if _, ok := NewService().registry.Lookup("txt"); ok {
+		t.Errorf("custom format leaked into other services")
+	}
+}
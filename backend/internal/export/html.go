@@ -0,0 +1,330 @@
+package export
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func init() {
+	builtin.Register(NewExporter(Format{Name: "html", Label: "HTML", MIMEType: "text/html; charset=utf-8", Extension: "html"}, HTML))
+}
+
+// Options controls the native renderers. The embedded MarkdownOptions are
+// the same apparatus, links and inactive settings /api/document reads.
+type Options struct {
+	document.MarkdownOptions
+	// Stylesheet is a CSS theme applied after the default stylesheet of HTML
+	// and EPUB output; its colour custom properties also style PDF output.
+	Stylesheet string
+	// Template is a DOCX or DOTX house template whose styles replace the
+	// default Word styles of DOCX output.
+	Template []byte
+	// TOC adds a table of contents: a field in DOCX output, a list of links
+	// to the sections in HTML, PDF and Markdown output. EPUB output always
+	// has one.
+	TOC bool
+	// Branding adds a letterhead to HTML, PDF, DOCX and EPUB output.
+	Branding Branding
+	// Annotations are highlighted in the text with their comment: footnotes
+	// in Markdown, Word comments in DOCX and margin notes elsewhere.
+	Annotations []Annotation
+	// Chunks sizes the records of JSONL output.
+	Chunks ChunkOptions
+	// Manifest, when set, is appended as an attestation of the sources of
+	// the text.
+	Manifest *Manifest
+}
+
+//go:embed html.css
+var defaultStylesheet string
+
+// CheckStylesheet returns an error unless css can be embedded in a <style>
+// element: CSS needs no "<", and "</style>" would end the element early.
+func CheckStylesheet(css string) error {
+	if strings.Contains(css, "<") {
+		return errors.New(`the stylesheet cannot contain "<"`)
+	}
+	return nil
+}
+
+var htmlTemplate = template.Must(template.New("document").Parse(`<!DOCTYPE html>
+<html lang="it">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+{{- if .URN}}
+<meta name="dc.identifier" content="{{.URN}}">
+{{- end}}
+<style>
+{{.Stylesheet}}
+</style>
+{{- if .Manifest}}
+<script type="application/json" id="integrity-manifest">{{.Manifest}}</script>
+{{- end}}
+</head>
+<body{{if .Annotated}} class="annotated"{{end}}>
+{{- if .Letterhead}}
+<div class="letterhead">{{.Letterhead}}</div>
+{{- end}}
+<header class="act-header" id="preamble">
+{{- if .Vigenza}}
+<p class="vigenza">Testo in vigore al: {{.Vigenza}}</p>
+{{- end}}
+{{- if .Title}}
+<h1>{{.Title}}</h1>
+{{- end}}
+</header>
+<main>
+{{- range .Sections}}
+{{template "section" .}}
+{{- end}}
+</main>
+{{- if .Footer}}
+<footer class="letterhead-footer">{{.Footer}}</footer>
+{{- end}}
+</body>
+</html>
+{{define "section" -}}
+{{if .Article}}<article{{else}}<section{{end}}{{if .ID}} id="{{.ID}}"{{end}} class="{{.Class}}">
+{{- if .Title}}
+<h{{.Heading}}>{{.Title}}</h{{.Heading}}>
+{{- end}}
+{{- range .Commas}}
+<div class="comma"{{if .ID}} id="{{.ID}}"{{end}}>
+{{- if .Annotations}}
+<aside class="margin-notes">
+{{- range .Annotations}}
+<p class="margin-note" id="ann-{{.Num}}"><span class="margin-note-num">{{.Num}}</span> {{.Comment}}{{if .Label}}<span class="margin-note-author">{{.Label}}</span>{{end}}</p>
+{{- end}}
+</aside>
+{{- end}}
+{{- if .Num}}<span class="comma-num">{{.Num}}.</span>{{end}}
+{{- range .Blocks}}
+{{.}}
+{{- end}}
+</div>
+{{- end}}
+{{- if .Notes}}
+<aside class="notes">
+{{- range .Notes}}
+<div class="note" id="{{.ID}}">
+{{- range .Lines}}
+<p>{{.}}</p>
+{{- end}}
+</div>
+{{- end}}
+</aside>
+{{- end}}
+{{- range .Children}}
+{{template "section" .}}
+{{- end}}
+{{if .Article}}</article>{{else}}</section>{{end}}
+{{- end}}`))
+
+type htmlDocument struct {
+	Title, URN, Vigenza string
+	Letterhead, Footer  string
+	Stylesheet          template.CSS
+	Annotated           bool
+	Manifest            template.JS // JSON
+	Sections            []htmlSection
+}
+
+type htmlSection struct {
+	Article  bool // <article> rather than <section>
+	ID       string
+	Class    string
+	Title    template.HTML
+	Heading  int
+	Commas   []htmlComma
+	Notes    []htmlNote
+	Children []htmlSection
+}
+
+type htmlComma struct {
+	ID, Num     string
+	Annotations []htmlAnnotation // margin notes
+	Blocks      []template.HTML
+}
+
+type htmlAnnotation struct {
+	Num            int
+	Comment, Label string
+}
+
+type htmlNote struct {
+	ID    string
+	Lines []template.HTML
+}
+
+// HTML renders doc as a standalone HTML page: an <article> per article and a
+// <section> per structural unit, each with its ID, commas numbered and
+// anchored, update notes in an <aside>, and a stylesheet suited to print.
+func HTML(doc *document.Document, opts Options) ([]byte, error) {
+	data := htmlDocument{
+		Title:      doc.Title,
+		URN:        doc.URN,
+		Vigenza:    displayDate(doc.Vigenza),
+		Stylesheet: template.CSS(defaultStylesheet),
+		Letterhead: opts.Branding.HeaderText(doc),
+		Footer:     opts.Branding.FooterText(doc),
+	}
+	if opts.Stylesheet != "" {
+		if err := CheckStylesheet(opts.Stylesheet); err != nil {
+			return nil, err
+		}
+		data.Stylesheet = template.CSS(defaultStylesheet + "\n" + opts.Stylesheet)
+	}
+	if opts.Manifest != nil {
+		manifest, err := json.Marshal(opts.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		data.Manifest = template.JS(manifest)
+	}
+	sections, annotations := renderSections(doc, opts)
+	data.Annotated = len(annotations) > 0
+	for _, s := range withTOC(sections, opts) {
+		data.Sections = append(data.Sections, htmlSectionOf(s, annotations))
+	}
+
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// htmlSectionOf prepares s for the section template; annotations are those
+// placed by renderSections, shown as margin notes next to their comma.
+func htmlSectionOf(s document.RenderedSection, annotations []Annotation) htmlSection {
+	h := htmlSection{
+		ID:      s.ID,
+		Class:   s.Type,
+		Title:   htmlInline(s.Title, ""),
+		Heading: min(s.Level+1, 6),
+	}
+	h.Article = s.Type == "article" || s.Type == "articolo"
+	if s.Status != nil && s.Status.Status != document.StatusInForce {
+		h.Class += " " + string(s.Status.Status)
+	}
+
+	for _, c := range groupCommas(s.Content) {
+		hc := htmlComma{ID: commaID(s.ID, c.Num), Num: c.Num}
+		for _, b := range c.Blocks {
+			hc.Blocks = append(hc.Blocks, htmlBlocks(b, s.ID))
+			for _, n := range annotationRefs(b) {
+				if n <= len(annotations) {
+					ann := annotations[n-1]
+					hc.Annotations = append(hc.Annotations, htmlAnnotation{Num: n, Comment: annotationComment(ann), Label: annotationLabel(ann)})
+				}
+			}
+		}
+		h.Commas = append(h.Commas, hc)
+	}
+	for _, note := range s.Notes {
+		hn := htmlNote{ID: noteID(s.ID, note.Number)}
+		for _, line := range noteLines(note) {
+			hn.Lines = append(hn.Lines, htmlInline(line, s.ID))
+		}
+		h.Notes = append(h.Notes, hn)
+	}
+	for _, child := range s.Children {
+		h.Children = append(h.Children, htmlSectionOf(child, annotations))
+	}
+	return h
+}
+
+// htmlBlocks renders a content block.
+func htmlBlocks(md, sectionID string) template.HTML {
+	var sb strings.Builder
+	blocks := parseBlocks(md)
+	for i := 0; i < len(blocks); i++ {
+		b := blocks[i]
+		switch b.kind {
+		case blockParagraph:
+			sb.WriteString("<p>" + string(htmlInline(b.text, sectionID)) + "</p>")
+		case blockQuote:
+			// Consecutive quoted lines form one quotation
+			sb.WriteString(strings.Repeat("<blockquote>", b.level))
+			for ; i < len(blocks) && blocks[i].kind == blockQuote && blocks[i].level == b.level; i++ {
+				sb.WriteString("<p>" + string(htmlInline(blocks[i].text, sectionID)) + "</p>")
+			}
+			i--
+			sb.WriteString(strings.Repeat("</blockquote>", b.level))
+		case blockBullet:
+			sb.WriteString("<ul>")
+			for ; i < len(blocks) && blocks[i].kind == blockBullet; i++ {
+				sb.WriteString(fmt.Sprintf(`<li class="level-%d">`, blocks[i].level) + string(htmlInline(blocks[i].text, sectionID)) + "</li>")
+			}
+			i--
+			sb.WriteString("</ul>")
+		case blockTable:
+			sb.WriteString("<table>")
+			for r, row := range b.rows {
+				cell := "td"
+				if r == 0 {
+					cell = "th"
+					sb.WriteString("<thead>")
+				} else if r == 1 {
+					sb.WriteString("<tbody>")
+				}
+				sb.WriteString("<tr>")
+				for _, c := range row {
+					sb.WriteString("<" + cell + ">" + string(htmlInline(c, sectionID)) + "</" + cell + ">")
+				}
+				sb.WriteString("</tr>")
+				if r == 0 {
+					sb.WriteString("</thead>")
+				}
+			}
+			if len(b.rows) > 1 {
+				sb.WriteString("</tbody>")
+			}
+			sb.WriteString("</table>")
+		}
+	}
+	return template.HTML(sb.String())
+}
+
+// htmlInline renders inline Markdown, escaping the text. Note markers link
+// to the update notes of the section and annotation references to their
+// margin note.
+func htmlInline(md, sectionID string) template.HTML {
+	var sb strings.Builder
+	for _, r := range parseInline(md) {
+		text := template.HTMLEscapeString(r.text)
+		switch {
+		case r.anchor != "":
+			sb.WriteString(`<span id="` + template.HTMLEscapeString(r.anchor) + `"></span>`)
+			continue
+		case r.note != 0 && sectionID != "":
+			sb.WriteString(`<sup class="note-ref"><a href="#` + template.HTMLEscapeString(noteID(sectionID, r.note)) + `">` + text + `</a></sup>`)
+			continue
+		case r.markStart != 0:
+			continue
+		case r.comment != 0:
+			sb.WriteString(fmt.Sprintf(`<sup class="annotation-ref"><a href="#ann-%d">%d</a></sup>`, r.comment, r.comment))
+			continue
+		}
+		if r.bold {
+			text = "<strong>" + text + "</strong>"
+		}
+		if href := safeHref(r.href); href != "" {
+			text = `<a href="` + template.HTMLEscapeString(href) + `">` + text + `</a>`
+		}
+		if r.highlight {
+			text = `<mark class="annotation">` + text + `</mark>`
+		}
+		sb.WriteString(text)
+	}
+	return template.HTML(sb.String())
+}
@@ -0,0 +1,79 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func exportDocument() *document.Document {
+	comma1 := "1\\. Il presente codice si applica ai contratti ((pubblici)) ((1)) di cui all'[articolo 2](https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:decreto.legislativo:2023-03-31;36~art2)."
+	amended := strings.Index(comma1, "pubblici")
+	return &document.Document{
+		Title:   "DECRETO LEGISLATIVO 31 marzo 2023, n. 36",
+		URN:     "urn:nir:stato:decreto.legislativo:2023-03-31;36",
+		Vigenza: "2025-01-01",
+		Sections: []document.DocumentSection{
+			{ID: "capo_I", Type: "chapter", Title: "Capo I - Principi", Children: []document.DocumentSection{
+				{ID: "art_1", Type: "article", Title: "Art. 1 - Oggetto", Content: []string{
+					comma1,
+					"2\\. Sono esclusi:\n\na) i contratti <segreti>;\n\nb) le concessioni.",
+					"| Soglia | Importo |\n| --- | --- |\n| lavori | 5.382.000 \\| euro |",
+				}, Modified: []document.ModifiedSpan{{Content: 0, Start: amended, End: amended + len("pubblici"), Text: "pubblici", Note: 1}},
+					Updates: []document.UpdateNote{{Number: 1, Heading: "AGGIORNAMENTO (1)", Text: "Il D.Lgs. 31 dicembre 2024, n. 209 ha disposto la modifica dell'art. 1, comma 1."}}},
+				{ID: "art_2", Type: "article", Title: "Art. 2 - Definizioni", Content: []string{"1\\. Ai fini del codice si applicano le definizioni dell'allegato I.1."}},
+			}},
+		},
+	}
+}
+
+func TestHTML(t *testing.T) {
+	doc := exportDocument()
+	out, err := HTML(doc, Options{MarkdownOptions: document.MarkdownOptions{Links: document.LinkStandalone}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(out)
+
+	for _, want := range []string{
+		`<section id="capo_I" class="chapter">`,
+		`<h2>Capo I - Principi</h2>`,
+		`<article id="art_1" class="article">`,
+		`<h3>Art. 1 - Oggetto</h3>`,
+		`<div class="comma" id="art_1__para_1"><span class="comma-num">1.</span>`,
+		`<strong>((pubblici))</strong> <sup class="note-ref"><a href="#art_1__note_1">((1))</a></sup>`,
+		`<a href="#art_2">articolo 2</a>`,
+		`<p>a) i contratti &lt;segreti&gt;;</p>`,
+		`<th>Soglia</th>`,
+		`<td>5.382.000 | euro</td>`,
+		`<div class="note" id="art_1__note_1">`,
+		`Testo in vigore al: 01-01-2025`,
+		`@media print`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("missing %q in\n%s", want, html)
+		}
+	}
+
+	out, _ = HTML(doc, Options{MarkdownOptions: document.MarkdownOptions{HideApparatus: true}})
+	if strings.Contains(string(out), "((") || strings.Contains(string(out), `class="notes"`) {
+		t.Errorf("apparatus should be hidden:\n%s", out)
+	}
+}
+
+func TestSafeHref(t *testing.T) {
+	for href, want := range map[string]string{
+		"https://www.normattiva.it/": "https://www.normattiva.it/",
+		"mailto:info@example.com":    "mailto:info@example.com",
+		"/?urn=urn:nir:stato:legge":  "/?urn=urn:nir:stato:legge",
+		"#art_1__para_2":             "#art_1__para_2",
+		"javascript:alert(1)":        "",
+		"//evil.example/x":           "",
+		`/\evil.example/x`:           "",
+	} {
+		if got := safeHref(href); got != want {
+			t.Errorf("safeHref(%q) = %q, want %q", href, got, want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func init() {
+	builtin.Register(NewExporter(Format{Name: "jsonl", Label: "JSON Lines (chunks)", MIMEType: "application/x-ndjson", Extension: "jsonl", Aliases: []string{"ndjson"}}, JSONL))
+}
+
+// ChunkOptions controls the records of JSONL output.
+type ChunkOptions struct {
+	// Unit is "comma", the default, for a record per comma or "article" for
+	// a record per article.
+	Unit string
+	// Size is the maximum length of the text of a record, in characters;
+	// longer units are split at word boundaries. 0 means no limit.
+	Size int
+	// Overlap is how many characters a chunk repeats from the end of the
+	// previous chunk of the same unit.
+	Overlap int
+}
+
+// jsonlRecord is a line of JSONL output.
+type jsonlRecord struct {
+	ID         string           `json:"id"`
+	SectionID  string           `json:"sectionId,omitempty"`
+	Comma      string           `json:"comma,omitempty"`
+	Path       []jsonlPathEntry `json:"path"`
+	URN        string           `json:"urn,omitempty"` // with the ~artN-comM fragment
+	Act        string           `json:"act"`
+	Vigenza    string           `json:"vigenza,omitempty"`
+	Status     string           `json:"status,omitempty"`
+	Text       string           `json:"text"`
+	Chunk      int              `json:"chunk"`
+	Chunks     int              `json:"chunks"`
+	References []jsonlReference `json:"references,omitempty"`
+}
+
+type jsonlPathEntry struct {
+	Type  string `json:"type"`
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title"`
+}
+
+type jsonlReference struct {
+	Text      string `json:"text"`
+	URN       string `json:"urn,omitempty"` // act URN, without fragment
+	Article   string `json:"article,omitempty"`
+	Comma     string `json:"comma,omitempty"`
+	SectionID string `json:"sectionId,omitempty"` // target in the same act
+}
+
+// JSONL renders doc as JSON Lines for retrieval pipelines: a record per
+// comma, or per article with opts.Chunks.Unit, carrying the plain text, the
+// path of headings above it, its URN and the references it makes. Texts
+// longer than opts.Chunks.Size are split into overlapping chunks.
+func JSONL(doc *document.Document, opts Options) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	act := doc.Title
+	if act == "" {
+		act = doc.Name
+	}
+	var walk func(s document.RenderedSection, path []jsonlPathEntry) error
+	walk = func(s document.RenderedSection, path []jsonlPathEntry) error {
+		if s.Title != "" {
+			path = append(path[:len(path):len(path)], jsonlPathEntry{Type: s.Type, ID: s.ID, Title: plainInline(s.Title)})
+		}
+		article := ""
+		if s.Type == "article" || s.Type == "articolo" {
+			article, _ = document.EIdTarget(s.ID)
+		}
+
+		var units []comma
+		if opts.Chunks.Unit == "article" {
+			units = []comma{{Blocks: s.Content}}
+		} else {
+			units = groupCommas(s.Content)
+			// Put the numbers back: they belong to the text
+			for i, c := range units {
+				if c.Num != "" {
+					units[i].Blocks = append([]string{c.Num + ". " + c.Blocks[0]}, c.Blocks[1:]...)
+				}
+			}
+		}
+
+		for _, unit := range units {
+			text := document.PlainText(strings.Join(unit.Blocks, "\n\n"))
+			if text == "" {
+				continue
+			}
+			rec := jsonlRecord{
+				ID:        s.ID,
+				SectionID: s.ID,
+				Comma:     unit.Num,
+				Path:      path,
+				URN:       jsonlURN(doc.URN, article, unit.Num),
+				Act:       act,
+				Vigenza:   doc.Vigenza,
+			}
+			if id := commaID(s.ID, unit.Num); id != "" {
+				rec.ID = id
+			}
+			if rec.Path == nil {
+				rec.Path = []jsonlPathEntry{}
+			}
+			if s.Status != nil {
+				rec.Status = string(s.Status.Status)
+			}
+			refs := jsonlReferences(doc.URN, unit.Blocks)
+			chunks := chunkText(text, opts.Chunks.Size, opts.Chunks.Overlap)
+			for i, chunk := range chunks {
+				r := rec
+				r.Text, r.Chunk, r.Chunks = chunk, i, len(chunks)
+				if len(chunks) > 1 {
+					r.ID += "#" + strconv.Itoa(i)
+				}
+				for _, ref := range refs {
+					if strings.Contains(chunk, ref.Text) {
+						r.References = append(r.References, ref)
+					}
+				}
+				if err := enc.Encode(r); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, child := range s.Children {
+			if err := walk(child, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, s := range doc.Render(opts.MarkdownOptions) {
+		if err := walk(s, nil); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonlURN is the URN of an article or comma of the act.
+func jsonlURN(urn, article, num string) string {
+	if urn == "" || article == "" {
+		return urn
+	}
+	urn += "~art" + article
+	if num != "" {
+		urn += "-com" + strings.NewReplacer(" ", "", "-", "").Replace(num)
+	}
+	return urn
+}
+
+// jsonlReferences lists the links of blocks, whatever the link mode: links
+// to normattiva.it, app routes and anchors within the act, whose URN is urn.
+func jsonlReferences(urn string, blocks []string) []jsonlReference {
+	var refs []jsonlReference
+	seen := map[jsonlReference]bool{}
+	for _, b := range blocks {
+		for _, r := range parseInline(b) {
+			if r.href == "" {
+				continue
+			}
+			ref := jsonlReference{Text: strings.TrimSpace(r.text)}
+			switch {
+			case strings.HasPrefix(r.href, "#"):
+				ref.URN, ref.SectionID = urn, r.href[1:]
+				ref.Article, ref.Comma = document.EIdTarget(ref.SectionID)
+			case strings.HasPrefix(r.href, "/?"):
+				q, _ := url.ParseQuery(r.href[2:])
+				ref.URN, ref.Article, ref.Comma = document.SplitURN(q.Get("urn"))
+			default:
+				parsed, ok := document.ReferenceFromURL(r.text, r.href)
+				if !ok {
+					continue
+				}
+				ref.URN, ref.Article, ref.Comma = parsed.URN, parsed.Article, parsed.Comma
+			}
+			if ref.Text == "" || seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// chunkText splits text into pieces of at most size characters, breaking
+// between words, each starting overlap characters before the end of the
+// previous one. size 0 keeps text whole.
+func chunkText(text string, size, overlap int) []string {
+	runes := []rune(text)
+	if size <= 0 || len(runes) <= size {
+		return []string{text}
+	}
+	overlap = min(max(overlap, 0), size/2)
+
+	var chunks []string
+	for start := 0; start < len(runes); {
+		end := min(start+size, len(runes))
+		if end < len(runes) {
+			// Back up to the last space, unless the word fills the chunk
+			for i := end; i > start+size/2; i-- {
+				if unicode.IsSpace(runes[i]) {
+					end = i
+					break
+				}
+			}
+		}
+		chunks = append(chunks, strings.TrimSpace(string(runes[start:end])))
+		if end == len(runes) {
+			break
+		}
+		next := end - overlap
+		// Start the overlap at a word
+		for next > start && next < end && !unicode.IsSpace(runes[next-1]) {
+			next++
+		}
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
@@ -0,0 +1,91 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func jsonlRecords(t *testing.T, out []byte) []jsonlRecord {
+	t.Helper()
+	var records []jsonlRecord
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		var rec jsonlRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestJSONL(t *testing.T) {
+	out, err := JSONL(exportDocument(), Options{MarkdownOptions: document.MarkdownOptions{Links: document.LinkStandalone, HideApparatus: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := jsonlRecords(t, out)
+	if len(records) != 3 {
+		t.Fatalf("expected a record per comma, got %d:\n%s", len(records), out)
+	}
+
+	rec := records[0]
+	if rec.ID != "art_1__para_1" || rec.SectionID != "art_1" || rec.Comma != "1" || rec.Vigenza != "2025-01-01" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	if rec.URN != "urn:nir:stato:decreto.legislativo:2023-03-31;36~art1-com1" {
+		t.Errorf("unexpected URN %q", rec.URN)
+	}
+	if len(rec.Path) != 2 || rec.Path[0].ID != "capo_I" || rec.Path[1].Title != "Art. 1 - Oggetto" {
+		t.Errorf("unexpected path %+v", rec.Path)
+	}
+	if rec.Text != "1. Il presente codice si applica ai contratti pubblici di cui all'articolo 2." {
+		t.Errorf("unexpected text %q", rec.Text)
+	}
+	if len(rec.References) != 1 || rec.References[0] != (jsonlReference{Text: "articolo 2", URN: "urn:nir:stato:decreto.legislativo:2023-03-31;36", Article: "2", SectionID: "art_2"}) {
+		t.Errorf("unexpected references %+v", rec.References)
+	}
+	if !strings.Contains(records[1].Text, "| lavori | 5.382.000 | euro |") || strings.Contains(records[1].Text, `\`) {
+		t.Errorf("unexpected text %q", records[1].Text)
+	}
+
+	// Articles split into overlapping chunks
+	out, err = JSONL(exportDocument(), Options{MarkdownOptions: document.MarkdownOptions{Links: document.LinkNormattiva}, Chunks: ChunkOptions{Unit: "article", Size: 60, Overlap: 15}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records = jsonlRecords(t, out)
+	last := records[len(records)-1]
+	if last.ID != "art_2#1" || last.Chunk != 1 || last.Chunks != 2 || last.URN != "urn:nir:stato:decreto.legislativo:2023-03-31;36~art2" {
+		t.Errorf("unexpected chunk %+v", last)
+	}
+	for i, rec := range records {
+		if n := len([]rune(rec.Text)); n > 60 {
+			t.Errorf("chunk %s has %d characters", rec.ID, n)
+		}
+		if i > 0 && rec.Chunk > 0 {
+			if first := strings.Fields(rec.Text)[0]; !strings.Contains(records[i-1].Text, first) {
+				t.Errorf("chunk %s does not overlap %q", rec.ID, records[i-1].Text)
+			}
+		}
+	}
+	if refs := records[1].References; len(refs) != 1 || refs[0].URN != "urn:nir:stato:decreto.legislativo:2023-03-31;36" || refs[0].Article != "2" {
+		t.Errorf("unexpected references %+v", refs)
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	if got := chunkText("uno due", 0, 0); len(got) != 1 {
+		t.Errorf("unexpected chunks %q", got)
+	}
+	got := chunkText("alfa beta gamma delta epsi", 12, 5)
+	want := []string{"alfa beta", "beta gamma", "gamma delta", "delta epsi"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
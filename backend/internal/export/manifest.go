@@ -0,0 +1,132 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// Manifest attests the text an export relies on: for each act, what was
+// exported and the Integrity record of the text as fetched. With
+// Options.Manifest, exports carry it as a readable appendix, and HTML also
+// as JSON; Bundle ships it as JSON next to the exported file.
+type Manifest struct {
+	Acts   []ManifestAct   `json:"acts"`
+	Export *ManifestExport `json:"export,omitempty"`
+}
+
+// ManifestAct is an act in a Manifest. Integrity is nil for documents
+// fetched before integrity records were kept.
+type ManifestAct struct {
+	Title             string              `json:"title"`
+	URN               string              `json:"urn,omitempty"`
+	CodiceRedazionale string              `json:"codiceRedazionale,omitempty"`
+	DataGU            string              `json:"dataGU,omitempty"`
+	Vigenza           string              `json:"vigenza,omitempty"`
+	Sections          []string            `json:"sections,omitempty"` // of a partial export
+	Integrity         *document.Integrity `json:"integrity,omitempty"`
+}
+
+// ManifestExport identifies the exported file of a bundle, whose hash it
+// could not carry itself.
+type ManifestExport struct {
+	File       string `json:"file"`
+	MIMEType   string `json:"mimeType"`
+	Hash       string `json:"hash"` // document.HashBytes of the file
+	ExportedAt string `json:"exportedAt"`
+}
+
+// ManifestActOf describes doc, reduced to sections if any, for a Manifest.
+func ManifestActOf(doc *document.Document, sections []string) ManifestAct {
+	title := doc.Title
+	if title == "" {
+		title = doc.Name
+	}
+	return ManifestAct{
+		Title:             title,
+		URN:               doc.URN,
+		CodiceRedazionale: doc.CodiceRedazionale,
+		DataGU:            doc.DataGU,
+		Vigenza:           doc.Vigenza,
+		Sections:          sections,
+		Integrity:         doc.Integrity,
+	}
+}
+
+// manifestSection is the appendix listing the acts of m with their hashes,
+// sources and fetch times.
+func manifestSection(m Manifest) document.RenderedSection {
+	content := []string{"Impronte SHA-256 del testo canonico di ciascun atto e dell'XML ricevuto da Normattiva, " +
+		"archiviato e verificabile con /api/integrity/verify?hash=<impronta>."}
+	for _, act := range m.Acts {
+		lines := []string{"**" + act.Title + "**", ""}
+		item := func(label, value string) {
+			if value != "" {
+				lines = append(lines, "- "+label+": "+value)
+			}
+		}
+		item("URN", act.URN)
+		item("Codice redazionale", act.CodiceRedazionale)
+		if act.Vigenza != "" {
+			item("Testo vigente al", displayDate(act.Vigenza))
+		}
+		item("Parti incluse", strings.Join(act.Sections, ", "))
+		if in := act.Integrity; in != nil {
+			item("Impronta del testo", in.ContentHash)
+			item("Fonte", in.SourceURL)
+			item("Scaricato il", manifestTime(in.FetchedAt))
+			item("Impronta dell'XML originale", in.RawHash)
+		} else {
+			item("Impronta del testo", "non disponibile (atto scaricato prima dell'archiviazione delle fonti)")
+		}
+		content = append(content, strings.Join(lines, "\n"))
+	}
+	return document.RenderedSection{ID: "integrity", Type: "appendix", Title: "Attestazione di integrità", Level: 1, Content: content}
+}
+
+// manifestTime shows an RFC 3339 time as DD-MM-YYYY hh:mm:ss UTC.
+func manifestTime(t string) string {
+	parsed, err := time.Parse(time.RFC3339, t)
+	if err != nil {
+		return t
+	}
+	return parsed.UTC().Format("02-01-2006 15:04:05") + " UTC"
+}
+
+// Bundle zips out as file together with m as manifest.json, completed with
+// the hash of the file.
+func (m Manifest) Bundle(out *Output, file string) (*Output, error) {
+	m.Export = &ManifestExport{
+		File:       file,
+		MIMEType:   out.MIMEType,
+		Hash:       document.HashBytes(out.Data),
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{file, out.Data}, {"manifest.json", manifest}} {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return &Output{Data: buf.Bytes(), MIMEType: "application/zip", Extension: "zip"}, nil
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func manifestFixture(t *testing.T) (*document.Document, Manifest) {
+	doc := parseFixture(t, "akn.xml")
+	doc.Vigenza = "2025-01-01"
+	doc.Integrity = &document.Integrity{
+		ContentHash: doc.ContentHash(),
+		SourceURL:   "https://www.normattiva.it/do/atto/caricaAKN?codiceRedaz=23G00044&dataGU=20230331&dataVigenza=20250101",
+		FetchedAt:   "2025-01-01T09:30:00Z",
+		RawHash:     document.HashBytes([]byte("<xml/>")),
+	}
+	return doc, Manifest{Acts: []ManifestAct{ManifestActOf(doc, []string{"art_1"})}}
+}
+
+func TestManifestAppendix(t *testing.T) {
+	doc, m := manifestFixture(t)
+	md := string(markdown(doc, Options{Manifest: &m}))
+	appendix := md[strings.Index(md, "Attestazione di integrità"):]
+	for _, want := range []string{
+		"- Impronta del testo: " + doc.Integrity.ContentHash,
+		"- Fonte: " + doc.Integrity.SourceURL,
+		"- Scaricato il: 01-01-2025 09:30:00 UTC",
+		"- Impronta dell'XML originale: " + doc.Integrity.RawHash,
+		"- Testo vigente al: 01-01-2025",
+		"- Parti incluse: art_1",
+	} {
+		if !strings.Contains(appendix, want) {
+			t.Errorf("appendix lacks %q:\n%s", want, appendix)
+		}
+	}
+	if strings.Contains(string(markdown(doc, Options{})), "Attestazione di integrità") {
+		t.Error("appendix without a manifest")
+	}
+
+	// Acts fetched before integrity records say so
+	m.Acts[0].Integrity = nil
+	if md := string(markdown(doc, Options{Manifest: &m})); !strings.Contains(md, "Impronta del testo: non disponibile") {
+		t.Errorf("missing integrity not reported:\n%s", md)
+	}
+}
+
+func TestManifestHTML(t *testing.T) {
+	doc, m := manifestFixture(t)
+	out, err := HTML(doc, Options{Manifest: &m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := regexp.MustCompile(`<script type="application/json" id="integrity-manifest">(.*?)</script>`).FindSubmatch(out)
+	if match == nil {
+		t.Fatalf("no embedded manifest in:\n%s", out)
+	}
+	var got Manifest
+	if err := json.Unmarshal(match[1], &got); err != nil {
+		t.Fatalf("embedded manifest: %v\n%s", err, match[1])
+	}
+	if len(got.Acts) != 1 || *got.Acts[0].Integrity != *doc.Integrity {
+		t.Errorf("embedded manifest = %+v", got)
+	}
+	if !bytes.Contains(out, []byte(`id="integrity"`)) {
+		t.Error("no appendix section")
+	}
+}
+
+func TestManifestBundle(t *testing.T) {
+	_, m := manifestFixture(t)
+	file := &Output{Data: []byte("# Testo"), MIMEType: "text/markdown; charset=utf-8", Extension: "md"}
+	out, err := m.Bundle(file, "document_23G00044.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.MIMEType != "application/zip" || out.Extension != "zip" || m.Export != nil {
+		t.Errorf("bundle = %s %s, manifest export %+v", out.MIMEType, out.Extension, m.Export)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(out.Data), int64(len(out.Data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	if string(files["document_23G00044.md"]) != "# Testo" {
+		t.Errorf("files = %v", files)
+	}
+	var got Manifest
+	if err := json.Unmarshal(files["manifest.json"], &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Export == nil || got.Export.File != "document_23G00044.md" || got.Export.Hash != document.HashBytes(file.Data) || got.Export.ExportedAt == "" {
+		t.Errorf("export = %+v", got.Export)
+	}
+	if len(got.Acts) != 1 || got.Acts[0].Integrity == nil || got.Acts[0].Integrity.ContentHash != m.Acts[0].Integrity.ContentHash {
+		t.Errorf("acts = %+v", got.Acts)
+	}
+}
@@ -0,0 +1,491 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func init() {
+	builtin.Register(NewExporter(Format{Name: "pdf", Label: "PDF", MIMEType: "application/pdf", Extension: "pdf"}, PDF))
+}
+
+// Page layout of PDF output, in millimetres and points.
+const (
+	pdfFont       = "go"
+	pdfMargin     = 20.0
+	pdfBodySize   = 10.5
+	pdfNoteSize   = 8.5
+	pdfLineHeight = 5.2
+	pdfNotesWidth = 42.0 // margin notes column, when there are annotations
+)
+
+// Structural units are centered headings; anything else is a run-in heading
+// like articles.
+var pdfStructural = map[string]bool{
+	"book": true, "libro": true, "part": true, "parte": true,
+	"title": true, "titolo": true, "chapter": true, "capo": true,
+	"section": true, "sezione": true,
+	"toc": true, "appendix": true,
+}
+
+type pdfWriter struct {
+	pdf         *fpdf.Fpdf
+	theme       pdfTheme
+	links       map[string]int // internal link of each section, comma, note and anchor ID
+	annotations []Annotation
+	notePage    int     // page of the last margin note
+	noteBottom  float64 // and where it ends
+}
+
+// PDF renders doc as an A4 PDF with the Go fonts embedded. Pages after the
+// first carry the act title and vigenza date in the header and the page
+// number in the footer; with opts.Branding every page carries the letterhead
+// instead. The outline follows the section tree, and links to sections,
+// commas and update notes jump within the document. Annotations are
+// highlighted, with their comments in a column of margin notes. Colours come
+// from the custom properties of opts.Stylesheet, when set.
+func PDF(doc *document.Document, opts Options) ([]byte, error) {
+	sections, annotations := renderSections(doc, opts)
+	sections = withTOC(sections, opts)
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(pdfFont, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", gobold.TTF)
+	pdf.AddUTF8FontFromBytes(pdfFont, "I", goitalic.TTF)
+	pdf.AddUTF8FontFromBytes(pdfFont, "BI", gobolditalic.TTF)
+	rightMargin := pdfMargin
+	if len(annotations) > 0 {
+		rightMargin += pdfNotesWidth + 5
+	}
+	pdf.SetMargins(pdfMargin, pdfMargin+5, rightMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle(doc.Title, true)
+	pdf.SetSubject(doc.URN, true)
+	pdf.SetLang("it-IT")
+	pdf.AliasNbPages("{nb}")
+
+	w := &pdfWriter{pdf: pdf, theme: themeFromCSS(opts.Stylesheet), links: map[string]int{}, annotations: annotations}
+	for _, s := range sections {
+		w.register(s)
+	}
+
+	vigenza := ""
+	if doc.Vigenza != "" {
+		vigenza = "Testo in vigore al: " + displayDate(doc.Vigenza)
+	}
+	// The letterhead replaces the act title in the header and shares the
+	// footer with the page number
+	header, footer := opts.Branding.HeaderText(doc), opts.Branding.FooterText(doc)
+	pdf.SetHeaderFunc(func() {
+		left := header
+		if left == "" {
+			if pdf.PageNo() == 1 {
+				return
+			}
+			left = doc.Title
+		}
+		pageWidth, _ := pdf.GetPageSize()
+		width := pageWidth - 2*pdfMargin
+		pdf.SetFont(pdfFont, "I", 8)
+		w.textColor(w.theme.muted)
+		if header != "" {
+			pdf.SetFont(pdfFont, "B", 8)
+			w.textColor(w.theme.accent)
+		}
+		pdf.SetXY(pdfMargin, pdfMargin-8)
+		right := pdf.GetStringWidth(vigenza) + 4
+		pdf.CellFormat(width-right, 5, w.fit(left, width-right-2), "", 0, "L", false, 0, "")
+		pdf.SetFont(pdfFont, "I", 8)
+		w.textColor(w.theme.muted)
+		pdf.CellFormat(right, 5, vigenza, "", 1, "R", false, 0, "")
+		pdf.SetDrawColor(w.theme.rule.r, w.theme.rule.g, w.theme.rule.b)
+		pdf.Line(pdfMargin, pdfMargin-2.5, pageWidth-pdfMargin, pdfMargin-2.5)
+		w.textColor(w.theme.text)
+		pdf.SetY(pdfMargin + 5)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin + 5)
+		pdf.SetFont(pdfFont, "", 8)
+		w.textColor(w.theme.muted)
+		page := fmt.Sprintf("Pagina %d di {nb}", pdf.PageNo())
+		if footer != "" {
+			pageWidth, _ := pdf.GetPageSize()
+			right := pdf.GetStringWidth(page) + 4
+			width := pageWidth - 2*pdfMargin - right
+			pdf.CellFormat(width, 5, w.fit(footer, width-2), "", 0, "L", false, 0, "")
+			pdf.CellFormat(right, 5, page, "", 0, "R", false, 0, "")
+		} else {
+			pdf.CellFormat(0, 5, page, "", 0, "C", false, 0, "")
+		}
+		w.textColor(w.theme.text)
+	})
+
+	pdf.AddPage()
+	w.textColor(w.theme.text)
+	if doc.Title != "" {
+		pdf.SetFont(pdfFont, "B", 15)
+		pdf.MultiCell(0, 7, doc.Title, "", "C", false)
+		pdf.Ln(2)
+	}
+	if vigenza != "" {
+		pdf.SetFont(pdfFont, "I", pdfBodySize)
+		w.textColor(w.theme.muted)
+		pdf.MultiCell(0, pdfLineHeight, vigenza, "", "C", false)
+		w.textColor(w.theme.text)
+	}
+	pdf.Ln(6)
+
+	for _, s := range sections {
+		w.section(s, 0)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// register creates the internal links of s before rendering, so that links
+// to later sections resolve.
+func (w *pdfWriter) register(s document.RenderedSection) {
+	add := func(id string) {
+		if id != "" && w.links[id] == 0 {
+			w.links[id] = w.pdf.AddLink()
+		}
+	}
+	add(s.ID)
+	for _, c := range groupCommas(s.Content) {
+		add(commaID(s.ID, c.Num))
+	}
+	for _, content := range s.Content {
+		for _, r := range parseInline(content) {
+			add(r.anchor)
+		}
+	}
+	if s.ID != "" {
+		for _, note := range s.Notes {
+			add(noteID(s.ID, note.Number))
+		}
+	}
+	for _, child := range s.Children {
+		w.register(child)
+	}
+}
+
+// target marks the current position as the destination of id.
+func (w *pdfWriter) target(id string) {
+	if link := w.links[id]; link != 0 {
+		w.pdf.SetLink(link, -1, -1)
+	}
+}
+
+// section renders s; outline is the outline level of its heading, counting
+// only the ancestors that have one.
+func (w *pdfWriter) section(s document.RenderedSection, outline int) {
+	pdf := w.pdf
+	if s.Type == "act" {
+		w.titlePage(s, outline)
+		for _, child := range s.Children {
+			w.section(child, outline+1)
+		}
+		return
+	}
+	if s.Title != "" {
+		// Keep the heading with the text that follows it
+		_, pageHeight := pdf.GetPageSize()
+		if pdf.GetY() > pageHeight-pdfMargin-25 {
+			pdf.AddPage()
+		}
+		pdf.Ln(3)
+		pdf.Bookmark(plainInline(s.Title), outline, -1)
+		w.target(s.ID)
+		if pdfStructural[s.Type] {
+			pdf.SetFont(pdfFont, "B", 12)
+			pdf.MultiCell(0, 6, strings.ToUpper(plainInline(s.Title)), "", "C", false)
+		} else {
+			pdf.SetFont(pdfFont, "B", 11)
+			pdf.MultiCell(0, 6, plainInline(s.Title), "", "L", false)
+		}
+		pdf.Ln(1)
+		outline++
+	} else {
+		w.target(s.ID)
+	}
+
+	for _, c := range groupCommas(s.Content) {
+		w.target(commaID(s.ID, c.Num))
+		for i, b := range c.Blocks {
+			for j, blk := range parseBlocks(b) {
+				prefix := ""
+				if i == 0 && j == 0 && c.Num != "" {
+					prefix = c.Num + ". "
+				}
+				w.block(blk, prefix, s.ID)
+			}
+		}
+	}
+
+	for _, note := range s.Notes {
+		pdf.Ln(1)
+		w.target(noteID(s.ID, note.Number))
+		for i, line := range noteLines(note) {
+			style := ""
+			if i == 0 {
+				style = "B"
+			}
+			w.textColor(w.theme.muted)
+			w.paragraph(parseInline(line), "", s.ID, 4, pdfNoteSize, style)
+			w.textColor(w.theme.text)
+		}
+	}
+
+	for _, child := range s.Children {
+		w.section(child, outline)
+	}
+}
+
+// titlePage gives an act of a compilation a title page, its content listing
+// vigenza and source, and starts the act on the next page.
+func (w *pdfWriter) titlePage(s document.RenderedSection, outline int) {
+	pdf := w.pdf
+	pdf.AddPage()
+	_, pageHeight := pdf.GetPageSize()
+	pdf.SetY(pageHeight / 3)
+	pdf.Bookmark(plainInline(s.Title), outline, -1)
+	w.target(s.ID)
+	pdf.SetFont(pdfFont, "B", 16)
+	w.textColor(w.theme.accent)
+	pdf.MultiCell(0, 8, plainInline(s.Title), "", "C", false)
+	pdf.Ln(4)
+	pdf.SetFont(pdfFont, "I", pdfBodySize)
+	w.textColor(w.theme.muted)
+	for _, line := range s.Content {
+		pdf.MultiCell(0, pdfLineHeight, plainInline(line), "", "C", false)
+	}
+	w.textColor(w.theme.text)
+	pdf.AddPage()
+}
+
+func (w *pdfWriter) block(b block, prefix, sectionID string) {
+	switch b.kind {
+	case blockTable:
+		w.table(b.rows)
+	case blockQuote:
+		w.paragraph(parseInline(b.text), "", sectionID, 8*float64(b.level), pdfBodySize, "I")
+	case blockBullet:
+		w.paragraph(parseInline(b.text), "• ", sectionID, 6+6*float64(b.level), pdfBodySize, "")
+	default:
+		indent := 0.0
+		switch {
+		case letterItemRe.MatchString(b.text):
+			indent = 6
+		case numberItemRe.MatchString(b.text):
+			indent = 12
+		}
+		w.paragraph(parseInline(b.text), prefix, sectionID, indent, pdfBodySize, "")
+	}
+}
+
+// paragraph writes flowing text indented from the left margin; prefix is
+// printed in bold before it.
+func (w *pdfWriter) paragraph(text []inline, prefix, sectionID string, indent, size float64, style string) {
+	pdf := w.pdf
+	height := pdfLineHeight * size / pdfBodySize
+	pdf.SetLeftMargin(pdfMargin + indent)
+	pdf.SetX(pdfMargin + indent)
+	if prefix != "" {
+		pdf.SetFont(pdfFont, "B", size)
+		pdf.Write(height, prefix)
+	}
+	for _, r := range text {
+		switch {
+		case r.anchor != "":
+			w.target(r.anchor)
+			continue
+		case r.note != 0:
+			pdf.SetFont(pdfFont, style, size)
+			pdf.SubWrite(height, r.text, size*0.7, 3, w.links[noteID(sectionID, r.note)], "")
+			continue
+		case r.markStart != 0:
+			continue
+		case r.comment != 0:
+			pdf.SetFont(pdfFont, "B", size)
+			w.textColor(w.theme.accent)
+			pdf.SubWrite(height, strconv.Itoa(r.comment), size*0.7, 3, 0, "")
+			w.textColor(w.theme.text)
+			w.marginNote(r.comment, pdf.GetY())
+			continue
+		}
+
+		runStyle := style
+		if r.bold && !strings.Contains(runStyle, "B") {
+			runStyle = "B" + runStyle
+		}
+		pdf.SetFont(pdfFont, runStyle, size)
+		x, y, page := pdf.GetX(), pdf.GetY(), pdf.PageNo()
+		href := safeHref(r.href)
+		switch {
+		case strings.HasPrefix(href, "#") && w.links[href[1:]] != 0:
+			w.textColor(w.theme.accent)
+			pdf.WriteLinkID(height, r.text, w.links[href[1:]])
+			w.textColor(w.theme.text)
+		case href != "" && !strings.HasPrefix(href, "/") && !strings.HasPrefix(href, "#"):
+			w.textColor(w.theme.accent)
+			pdf.WriteLinkString(height, r.text, href)
+			w.textColor(w.theme.text)
+		default:
+			pdf.Write(height, r.text)
+		}
+		if r.highlight {
+			w.highlight(x, y, page, height)
+		}
+	}
+	pdf.Ln(height + 1.2)
+	pdf.SetLeftMargin(pdfMargin)
+}
+
+// highlight marks the text written since x, y on page, in lines of the
+// given height, with a highlighter stroke.
+func (w *pdfWriter) highlight(x, y float64, page int, height float64) {
+	pdf := w.pdf
+	left, top, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	endX, endY := pdf.GetXY()
+	if pdf.PageNo() != page {
+		x, y = left, top
+	}
+	pdf.SetFillColor(255, 229, 138)
+	pdf.SetAlpha(0.7, "Multiply")
+	for line := y; line < endY+height/2; line += height {
+		from, to := left, pageWidth-right
+		if line == y {
+			from = x
+		}
+		if line > endY-height/2 {
+			to = endX
+		}
+		if to > from {
+			pdf.Rect(from, line, to-from, height, "F")
+		}
+	}
+	pdf.SetAlpha(1, "Normal")
+}
+
+// marginNote prints the comment of annotation n in the notes column, at y
+// or below the previous note.
+func (w *pdfWriter) marginNote(n int, y float64) {
+	if n > len(w.annotations) {
+		return
+	}
+	pdf := w.pdf
+	ann := w.annotations[n-1]
+	x, current := pdf.GetXY()
+	auto, breakMargin := pdf.GetAutoPageBreak()
+	pdf.SetAutoPageBreak(false, breakMargin)
+	if w.notePage == pdf.PageNo() {
+		y = max(y, w.noteBottom+1.5)
+	}
+
+	pageWidth, _ := pdf.GetPageSize()
+	left := pageWidth - pdfMargin - pdfNotesWidth
+	height := pdfLineHeight * 0.75
+	pdf.SetXY(left, y)
+	pdf.SetFont(pdfFont, "B", pdfNoteSize-1)
+	w.textColor(w.theme.accent)
+	pdf.CellFormat(4, height, strconv.Itoa(n), "", 0, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "", pdfNoteSize-1)
+	w.textColor(w.theme.muted)
+	pdf.MultiCell(pdfNotesWidth-4, height, annotationComment(ann), "", "L", false)
+	if label := annotationLabel(ann); label != "" {
+		pdf.SetX(left + 4)
+		pdf.SetFont(pdfFont, "I", pdfNoteSize-1.5)
+		pdf.MultiCell(pdfNotesWidth-4, height, label, "", "L", false)
+	}
+	w.notePage, w.noteBottom = pdf.PageNo(), pdf.GetY()
+
+	w.textColor(w.theme.text)
+	pdf.SetAutoPageBreak(auto, breakMargin)
+	pdf.SetXY(x, current)
+}
+
+// table draws a ruled table with equal columns, the header row in bold.
+func (w *pdfWriter) table(rows [][]string) {
+	pdf := w.pdf
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return
+	}
+	pageWidth, pageHeight := pdf.GetPageSize()
+	_, _, right, _ := pdf.GetMargins()
+	width := (pageWidth - pdfMargin - right) / float64(cols)
+	height := pdfLineHeight * 0.9
+
+	pdf.SetDrawColor(w.theme.muted.r, w.theme.muted.g, w.theme.muted.b)
+	for r, row := range rows {
+		style := ""
+		if r == 0 {
+			style = "B"
+		}
+		pdf.SetFont(pdfFont, style, pdfBodySize-1)
+		cells := make([]string, cols)
+		lines := 1
+		for c := range cells {
+			if c < len(row) {
+				cells[c] = plainInline(row[c])
+			}
+			lines = max(lines, len(pdf.SplitText(cells[c], width-2)))
+		}
+		rowHeight := float64(lines)*height + 2
+		if pdf.GetY()+rowHeight > pageHeight-pdfMargin {
+			pdf.AddPage()
+		}
+		y := pdf.GetY()
+		for c, cell := range cells {
+			x := pdfMargin + float64(c)*width
+			pdf.Rect(x, y, width, rowHeight, "D")
+			pdf.SetXY(x+1, y+1)
+			pdf.MultiCell(width-2, height, cell, "", "L", false)
+		}
+		pdf.SetXY(pdfMargin, y+rowHeight)
+	}
+	pdf.Ln(2)
+}
+
+func (w *pdfWriter) textColor(c rgb) {
+	w.pdf.SetTextColor(c.r, c.g, c.b)
+}
+
+// fit shortens s with an ellipsis to fit width in the current font.
+func (w *pdfWriter) fit(s string, width float64) string {
+	if w.pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && w.pdf.GetStringWidth(string(runes)+"…") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimSpace(string(runes)) + "…"
+}
+
+// plainInline is the text of inline Markdown without formatting.
+func plainInline(md string) string {
+	var sb strings.Builder
+	for _, r := range parseInline(md) {
+		sb.WriteString(r.text)
+	}
+	return sb.String()
+}
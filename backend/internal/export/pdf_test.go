@@ -0,0 +1,34 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func TestPDF(t *testing.T) {
+	doc := exportDocument()
+	doc.Title = "DECRETO LEGISLATIVO 31 marzo 2023, n. 36 - Codice dei contratti pubblici, attività e città"
+	out, err := PDF(doc, Options{MarkdownOptions: document.MarkdownOptions{Links: document.LinkStandalone}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out, []byte("%PDF-")) {
+		t.Fatalf("not a PDF: %q", out[:min(len(out), 20)])
+	}
+	for _, want := range []string{
+		"/Type /Outlines",
+		"/FontFile2",     // embedded TrueType fonts
+		"/Subtype /Link", // link annotations
+		"/Dest [",        // internal links
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	// One outline entry per heading: capo_I, art_1, art_2
+	if n := bytes.Count(out, []byte("/Title (")); n != 3+1 { // and the document title
+		t.Errorf("expected 3 outline entries, got %d", n-1)
+	}
+}
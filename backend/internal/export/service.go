@@ -4,55 +4,91 @@ import (
 	"bytes"
 	"fmt"
 	"os/exec"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
 )
 
-type Service struct {
-	pandocPath string
+func init() {
+	builtin.Register(NewExporter(Format{Name: "markdown", Label: "Markdown", MIMEType: "text/markdown; charset=utf-8", Extension: "md", Aliases: []string{"md"}},
+		func(doc *document.Document, opts Options) ([]byte, error) {
+			return markdown(doc, opts), nil
+		}))
 }
 
-func NewService() *Service {
-	path, _ := exec.LookPath("pandoc")
-	return &Service{
-		pandocPath: path,
+// markdown renders doc as Markdown, with the annotations of opts highlighted
+// and their comments as footnotes.
+func markdown(doc *document.Document, opts Options) []byte {
+	sections, annotations := renderSections(doc, opts)
+	md := doc.RenderedMarkdown(withTOC(sections, opts))
+	for i, ann := range annotations {
+		md = append(md, markdownFootnote(i+1, ann)...)
 	}
+	return md
 }
 
-func (s *Service) Export(mdContent string, format string) ([]byte, string, error) {
-	if s.pandocPath == "" {
-		return nil, "", fmt.Errorf("pandoc not found on system")
-	}
+// pandocFormats are the formats offered through pandoc when it is installed,
+// for which there is no native exporter.
+var pandocFormats = []Format{
+	{Name: "odt", Label: "OpenDocument (pandoc)", MIMEType: "application/vnd.oasis.opendocument.text", Extension: "odt"},
+	{Name: "rtf", Label: "RTF (pandoc)", MIMEType: "application/rtf", Extension: "rtf"},
+	{Name: "latex", Label: "LaTeX (pandoc)", MIMEType: "application/x-latex", Extension: "tex", Aliases: []string{"tex"}},
+}
 
-	var outputFormat string
-	var contentType string
-
-	switch format {
-	case "pdf":
-		outputFormat = "pdf"
-		contentType = "application/pdf"
-	case "docx":
-		outputFormat = "docx"
-		contentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
-	case "html":
-		outputFormat = "html"
-		contentType = "text/html"
-	case "markdown", "md":
-		return []byte(mdContent), "text/markdown", nil
-	default:
-		return nil, "", fmt.Errorf("unsupported format: %s", format)
-	}
+// PandocExporter converts the Markdown rendering of a document with pandoc.
+type PandocExporter struct {
+	Path   string // pandoc executable
+	To     string // pandoc output format
+	Output Format
+}
+
+func (p *PandocExporter) Format() Format { return p.Output }
+
+func (p *PandocExporter) Export(doc *document.Document, opts Options) (*Output, error) {
+	md := markdown(doc, opts)
 
-	cmd := exec.Command(s.pandocPath, "--from", "markdown", "--to", outputFormat, "-o", "-")
-	cmd.Stdin = bytes.NewBufferString(mdContent)
+	cmd := exec.Command(p.Path, "--from", "markdown", "--to", p.To, "--standalone", "-o", "-")
+	cmd.Stdin = bytes.NewReader(md)
 
 	var out bytes.Buffer
 	var stderr bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
-	if err != nil {
-		return nil, "", fmt.Errorf("pandoc error: %v, stderr: %s", err, stderr.String())
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("pandoc error: %v, stderr: %s", err, stderr.String())
 	}
+	return &Output{Data: out.Bytes(), MIMEType: p.Output.MIMEType, Extension: p.Output.Extension}, nil
+}
+
+type Service struct {
+	registry *Registry
+}
+
+// NewService returns a service with the native exporters and, when pandoc is
+// installed, the formats pandoc adds.
+func NewService() *Service {
+	s := &Service{registry: NewRegistry()}
+	s.registry.merge(builtin)
+	if path, err := exec.LookPath("pandoc"); err == nil {
+		for _, f := range pandocFormats {
+			s.Register(&PandocExporter{Path: path, To: f.Name, Output: f})
+		}
+	}
+	return s
+}
+
+// Register adds an exporter to the service, replacing any for the same format.
+func (s *Service) Register(e Exporter) {
+	s.registry.Register(e)
+}
+
+// Formats lists the formats the service can export.
+func (s *Service) Formats() []Format {
+	return s.registry.Formats()
+}
 
-	return out.Bytes(), contentType, nil
+// ExportDocument converts doc to format; unknown formats return
+// ErrUnsupportedFormat.
+func (s *Service) ExportDocument(doc *document.Document, format string, opts Options) (*Output, error) {
+	return s.registry.Export(doc, format, opts)
 }
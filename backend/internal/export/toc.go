@@ -0,0 +1,35 @@
+package export
+
+import (
+	"strings"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// tocSection is a table of contents for sections, as a rendered section of
+// nested links to the titled sections, for the formats without a native one.
+func tocSection(sections []document.RenderedSection) document.RenderedSection {
+	escape := strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`)
+	var lines []string
+	var walk func(sections []document.RenderedSection, depth int)
+	walk = func(sections []document.RenderedSection, depth int) {
+		for _, s := range sections {
+			if s.Title == "" || s.ID == "" {
+				walk(s.Children, depth)
+				continue
+			}
+			lines = append(lines, strings.Repeat("  ", depth)+"- ["+escape.Replace(plainInline(s.Title))+"](#"+s.ID+")")
+			walk(s.Children, depth+1)
+		}
+	}
+	walk(sections, 0)
+	return document.RenderedSection{ID: "toc", Type: "toc", Title: "Indice", Level: 1, Content: []string{strings.Join(lines, "\n")}}
+}
+
+// withTOC puts a table of contents before sections when opts asks for one.
+func withTOC(sections []document.RenderedSection, opts Options) []document.RenderedSection {
+	if !opts.TOC || len(sections) == 0 {
+		return sections
+	}
+	return append([]document.RenderedSection{tocSection(sections)}, sections...)
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func TestCompilation(t *testing.T) {
+	base := exportDocument()
+	decree := &document.Document{Title: "DECRETO 1 luglio 2024", URN: "urn:nir:stato:decreto:2024-07-01;1", Vigenza: "2024-07-01",
+		Sections: []document.DocumentSection{{ID: "art_1", Type: "article", Title: "Art. 1",
+			Content: []string{"1\\. In attuazione dell'[articolo 1](https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:decreto.legislativo:2023-03-31;36~art1) del codice."}}}}
+	doc := document.Compile("Raccolta appalti", []document.CompiledAct{{Document: base}, {Document: decree}})
+	opts := Options{MarkdownOptions: document.MarkdownOptions{Links: document.LinkStandalone}, TOC: true}
+
+	html, err := HTML(doc, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<section id="toc" class="toc">`,
+		`<li class="level-0"><a href="#act1">DECRETO LEGISLATIVO 31 marzo 2023, n. 36</a></li><li class="level-1"><a href="#act1__capo_I">Capo I - Principi</a></li>`,
+		`<section id="act2" class="act">`,
+		`<p>Testo in vigore al: 01-07-2024</p>`,
+		`<article id="act1__art_1" class="article">`,
+		`<a href="#act1__art_1">articolo 1</a>`,
+		`<section id="sources" class="appendix">`,
+	} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("HTML: missing %q in\n%s", want, html)
+		}
+	}
+
+	docx, err := DOCX(doc, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := zipParts(t, docx)["word/document.xml"]
+	for _, want := range []string{`<w:pStyle w:val="Atto"/>`, `<w:pStyle w:val="Frontespizio"/>`, `<w:hyperlink w:anchor="act1__art_1">`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("DOCX: missing %q", want)
+		}
+	}
+
+	pdf, err := PDF(doc, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Outline: TOC, 2 acts, capo_I, 2 + 1 articles, sources, and the title
+	if n := bytes.Count(pdf, []byte("/Title (")); n != 8+1 {
+		t.Errorf("expected 8 outline entries, got %d", n-1)
+	}
+}
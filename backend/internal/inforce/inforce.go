@@ -0,0 +1,300 @@
+package inforce
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gterranova/normaplus/backend/internal/citation"
+	"github.com/gterranova/normaplus/backend/internal/deadlines"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// VacatioLegis is the ordinary number of days between publication in the
+// Gazzetta Ufficiale and entry into force (art. 73 Cost., art. 10 preleggi).
+const VacatioLegis = 15
+
+// How a date was derived
+const (
+	RuleOrdinaryVacatio     = "ordinary-vacatio"       // publication + 15 days
+	RuleDayAfterPublication = "day-after-publication"  // publication + 1 day
+	RulePublicationDay      = "publication-day"        // on the day of publication
+	RuleAfterPublication    = "after-publication"      // publication + stated period
+	RuleAfterEntryIntoForce = "after-entry-into-force" // act entry into force + stated period
+	RuleExplicitDate        = "explicit-date"          // calendar date stated by the clause
+	RuleUnresolved          = "unresolved"             // clause found, date not computable
+)
+
+const (
+	ordinaryVacatioBasis = "art. 73, terzo comma, Cost.; art. 10 disp. prel. c.c."
+	eventPublication     = "publication"
+	eventEntryIntoForce  = "entry-into-force"
+	dateLayout           = "2006-01-02"
+)
+
+// Explanation records how an entry-into-force date was derived.
+type Explanation struct {
+	Rule      string `json:"rule"`
+	From      string `json:"from,omitempty"`      // date the period is counted from
+	FromEvent string `json:"fromEvent,omitempty"` // publication or entry-into-force
+	Offset    string `json:"offset,omitempty"`    // ISO 8601 duration added to From
+	Clause    string `json:"clause,omitempty"`    // text of the provision applied
+	SectionID string `json:"sectionId,omitempty"` // section holding the clause
+	Basis     string `json:"basis,omitempty"`     // legal basis of a default rule
+}
+
+// EntryIntoForce is the date from which the act, or some of its articles, apply.
+type EntryIntoForce struct {
+	Date        string      `json:"date,omitempty"`
+	Articles    []string    `json:"articles,omitempty"`   // article numbers, for article-level dates
+	SectionIDs  []string    `json:"sectionIds,omitempty"` // their section IDs, when found
+	Explanation Explanation `json:"explanation"`
+}
+
+// Result holds the act-level date and the article-level dates stated by the act.
+type Result struct {
+	Publication string           `json:"publication"`
+	Act         EntryIntoForce   `json:"act"`
+	Articles    []EntryIntoForce `json:"articles,omitempty"`
+}
+
+var (
+	// Wording of entry-into-force and effectiveness clauses
+	clauseRe  = regexp.MustCompile(`(?i)\b(?:entra(?:no)?\s+in\s+vigore|acquista(?:no)?\s+efficacia|ha(?:nno)?\s+effetto|si\s+applica(?:no)?\s+(?:a\s+decorrere|a\s+partire|dal|dall'|dalla))`)
+	inForceRe = regexp.MustCompile(`(?i)\bentra(?:no)?\s+in\s+vigore\b`)
+	// "il presente decreto entra in vigore": the clause concerns the whole act
+	wholeActRe    = regexp.MustCompile(`(?i)\b(?:il\s+presente|la\s+presente|le\s+presenti|i\s+presenti)\s+(?:decreto|legge|regolamento|codice|testo|disposizioni|norme|atto)\b`)
+	articlesRe    = regexp.MustCompile(`(?i)\b(?:articol[oi]|artt?\.)\s*(\d+(?:-?[a-z]+)?(?:\s*(?:,|e|ed)\s*\d+(?:-?[a-z]+)?)*)`)
+	thisArticleRe = regexp.MustCompile(`(?i)\bpresente\s+articolo\b`)
+	articleNumRe  = regexp.MustCompile(`\d+(?:-?[a-z]+)?`)
+	// What may separate "articoli 3 e 5" from the act they belong to
+	ofActRe       = regexp.MustCompile(`(?i)^\s*,?\s*(?:del|della|dello|dell')\s*$`)
+	dayAfterRe    = regexp.MustCompile(`(?i)\bil\s+giorno\s+successivo\s+a\s+quello\s+(?:della|dell')\s*(?:sua\s+|loro\s+)?pubblicazione`)
+	sentenceEndRe = regexp.MustCompile(`[.;]\s+|\n+`)
+	sameDayRe     = regexp.MustCompile(`(?i)\b(?:(?:giorno\s+stesso|stesso\s+giorno|medesimo\s+giorno|data)\s+(?:della|di)\s+(?:sua\s+|loro\s+)?pubblicazione|all'atto\s+della\s+(?:sua\s+)?pubblicazione)`)
+)
+
+// Calculate derives the entry-into-force dates of doc from its publication
+// date (DataGU) and the clauses in its text. It only uses the parsed document.
+func Calculate(doc *document.Document) (*Result, error) {
+	pub, err := parseDate(doc.DataGU)
+	if err != nil {
+		return nil, fmt.Errorf("invalid publication date %q: %w", doc.DataGU, err)
+	}
+
+	result := &Result{Publication: pub.Format(dateLayout)}
+	articles := collectArticles(doc)
+	anchors := doc.ArticleAnchors()
+
+	var clauses []clause
+	for i, a := range articles {
+		for _, block := range a.Content {
+			for _, sentence := range sentences(document.PlainText(block)) {
+				if !clauseRe.MatchString(sentence) {
+					continue
+				}
+				c := clause{text: sentence, section: a}
+				switch {
+				case thisArticleRe.MatchString(sentence):
+					c.articles = []string{articleNumber(a.ID)}
+				case !wholeActRe.MatchString(sentence):
+					var other bool
+					if c.articles, other = mentionedArticles(sentence); other && len(c.articles) == 0 {
+						continue // the clause is about the articles of another act
+					}
+				}
+				// Act-level clauses are the "entra in vigore" of the final article
+				// or of an article about entry into force
+				c.act = len(c.articles) == 0 && inForceRe.MatchString(sentence) &&
+					(i == len(articles)-1 || strings.Contains(strings.ToLower(a.Title), "vigore"))
+				if c.act || len(c.articles) > 0 {
+					clauses = append(clauses, c)
+				}
+			}
+		}
+	}
+
+	// Act level first: article dates may be counted from it
+	var actDate time.Time
+	for _, c := range clauses {
+		if !c.act {
+			continue
+		}
+		if date, exp := resolve(c, pub, time.Time{}); !date.IsZero() {
+			actDate = date
+			result.Act = EntryIntoForce{Date: date.Format(dateLayout), Explanation: exp}
+			break
+		}
+	}
+	if actDate.IsZero() {
+		actDate = pub.AddDate(0, 0, VacatioLegis)
+		result.Act = EntryIntoForce{
+			Date: actDate.Format(dateLayout),
+			Explanation: Explanation{
+				Rule:      RuleOrdinaryVacatio,
+				From:      pub.Format(dateLayout),
+				FromEvent: eventPublication,
+				Offset:    fmt.Sprintf("P%dD", VacatioLegis),
+				Basis:     ordinaryVacatioBasis,
+			},
+		}
+	}
+
+	for _, c := range clauses {
+		if c.act {
+			continue
+		}
+		e := EntryIntoForce{Articles: c.articles}
+		for _, n := range c.articles {
+			if id, ok := anchors[n]; ok {
+				e.SectionIDs = append(e.SectionIDs, id)
+			}
+		}
+		date, exp := resolve(c, pub, actDate)
+		if !date.IsZero() {
+			e.Date = date.Format(dateLayout)
+		}
+		e.Explanation = exp
+		result.Articles = append(result.Articles, e)
+	}
+
+	return result, nil
+}
+
+type clause struct {
+	text     string
+	section  *document.DocumentSection
+	articles []string
+	act      bool
+}
+
+// resolve computes the date stated by a clause. actDate is the act's entry
+// into force, zero while it is being computed.
+func resolve(c clause, pub, actDate time.Time) (time.Time, Explanation) {
+	exp := Explanation{Clause: c.text, SectionID: c.section.ID}
+
+	switch {
+	case dayAfterRe.MatchString(c.text):
+		exp.Rule, exp.From, exp.FromEvent, exp.Offset = RuleDayAfterPublication, pub.Format(dateLayout), eventPublication, "P1D"
+		return pub.AddDate(0, 0, 1), exp
+	case sameDayRe.MatchString(c.text):
+		exp.Rule, exp.From, exp.FromEvent = RulePublicationDay, pub.Format(dateLayout), eventPublication
+		return pub, exp
+	}
+
+	for _, d := range deadlines.Find(c.text) {
+		switch {
+		case d.Kind == deadlines.KindDate:
+			date, _ := time.Parse(dateLayout, d.Date)
+			exp.Rule = RuleExplicitDate
+			return date, exp
+		case d.AnchorType == "publication":
+			exp.Rule, exp.From, exp.FromEvent, exp.Offset = RuleAfterPublication, pub.Format(dateLayout), eventPublication, d.Duration
+			return addPeriod(pub, d), exp
+		case d.AnchorType == "entry-into-force" && !actDate.IsZero():
+			exp.Rule, exp.From, exp.FromEvent, exp.Offset = RuleAfterEntryIntoForce, actDate.Format(dateLayout), eventEntryIntoForce, d.Duration
+			return addPeriod(actDate, d), exp
+		}
+	}
+
+	exp.Rule = RuleUnresolved
+	return time.Time{}, exp
+}
+
+func addPeriod(from time.Time, d deadlines.Deadline) time.Time {
+	switch d.Unit {
+	case "week":
+		return from.AddDate(0, 0, 7*d.Amount)
+	case "month":
+		return addMonths(from, d.Amount)
+	case "year":
+		return addMonths(from, 12*d.Amount)
+	case "hour":
+		return from.Add(time.Duration(d.Amount) * time.Hour)
+	default:
+		return from.AddDate(0, 0, d.Amount)
+	}
+}
+
+// addMonths adds n months, ending on the last day of the month when it has no
+// day matching from's (art. 2963 c.c.): 31 March + 6 months is 30 September.
+func addMonths(from time.Time, n int) time.Time {
+	first := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location()).AddDate(0, n, 0)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(from.Day(), last)-1)
+}
+
+// collectArticles lists the article sections of the act in document order.
+func collectArticles(doc *document.Document) []*document.DocumentSection {
+	var articles []*document.DocumentSection
+	doc.Walk(func(_ []*document.DocumentSection, s *document.DocumentSection) error {
+		if s.IsArticle() {
+			articles = append(articles, s)
+		}
+		return nil
+	})
+	return articles
+}
+
+// mentionedArticles returns the numbers of the articles of this act the
+// sentence mentions. Articles followed by the citation of an act, as in
+// "gli articoli 3 e 5 del decreto legislativo n. 50 del 2016", belong to
+// that act: they are left out, and other reports them.
+func mentionedArticles(sentence string) (articles []string, other bool) {
+	cited := citation.Find(sentence)
+	for _, m := range articlesRe.FindAllStringSubmatchIndex(sentence, -1) {
+		if citesAct(sentence, m[1], cited) {
+			other = true
+			continue
+		}
+		for _, n := range articleNumRe.FindAllString(sentence[m[2]:m[3]], -1) {
+			articles = append(articles, strings.ToLower(strings.ReplaceAll(n, "-", "")))
+		}
+	}
+	return articles, other
+}
+
+// citesAct reports whether one of cited starts right after offset end of
+// sentence.
+func citesAct(sentence string, end int, cited []citation.Citation) bool {
+	for _, c := range cited {
+		if c.Start >= end && ofActRe.MatchString(sentence[end:c.Start]) {
+			return true
+		}
+	}
+	return false
+}
+
+func articleNumber(id string) string {
+	article, _ := document.EIdTarget(id)
+	return article
+}
+
+// sentences splits a content block at semicolons, line breaks and full stops
+// followed by a capital letter, so that "art. 5" and "n. 83" stay whole.
+func sentences(text string) []string {
+	var out []string
+	start := 0
+	for _, m := range sentenceEndRe.FindAllStringIndex(text, -1) {
+		if text[m[0]] == '.' && (m[1] == len(text) || !unicode.IsUpper(rune(text[m[1]]))) {
+			continue
+		}
+		if part := strings.TrimSpace(text[start:m[0]]); part != "" {
+			out = append(out, part)
+		}
+		start = m[1]
+	}
+	if part := strings.TrimSpace(text[start:]); part != "" {
+		out = append(out, part)
+	}
+	return out
+}
+
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(dateLayout, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("20060102", s)
+}
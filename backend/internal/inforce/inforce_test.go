@@ -0,0 +1,98 @@
+package inforce
+
+import (
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func act(dataGU string, articles ...document.DocumentSection) *document.Document {
+	return &document.Document{DataGU: dataGU, Sections: articles}
+}
+
+func article(id, title string, content ...string) document.DocumentSection {
+	return document.DocumentSection{ID: id, Type: "article", Title: title, Content: content}
+}
+
+func TestCalculate(t *testing.T) {
+	cases := []struct {
+		name string
+		doc  *document.Document
+		date string
+		rule string
+	}{
+		{"ordinary vacatio", act("2023-03-31",
+			article("art_1", "Art. 1", "1\\. Oggetto del decreto.")), "2023-04-15", RuleOrdinaryVacatio},
+		{"day after publication", act("2024-02-29",
+			article("art_1", "Art. 1", "1\\. Oggetto."),
+			article("art_2", "Art. 2 - Entrata in vigore", "1\\. Il presente decreto entra in vigore il giorno successivo a quello della sua pubblicazione nella Gazzetta Ufficiale della Repubblica italiana e sarà presentato alle Camere per la conversione in legge.")),
+			"2024-03-01", RuleDayAfterPublication},
+		{"stated vacatio", act("20230331",
+			article("art_1", "Art. 1", "1\\. Oggetto."),
+			article("art_2", "Art. 2", "1\\. La presente legge entra in vigore il trentesimo giorno successivo a quello della sua pubblicazione nella Gazzetta Ufficiale.")),
+			"2023-04-30", RuleAfterPublication},
+		{"explicit date", act("2023-03-31",
+			article("art_1", "Art. 1", "1\\. Oggetto."),
+			article("art_2", "Art. 2", "1\\. Il presente decreto entra in vigore il 1° luglio 2023.")),
+			"2023-07-01", RuleExplicitDate},
+	}
+
+	for _, c := range cases {
+		res, err := Calculate(c.doc)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if res.Act.Date != c.date || res.Act.Explanation.Rule != c.rule {
+			t.Errorf("%s: got %s (%+v), want %s (%s)", c.name, res.Act.Date, res.Act.Explanation, c.date, c.rule)
+		}
+	}
+}
+
+func TestCalculateArticles(t *testing.T) {
+	doc := act("2023-03-31",
+		article("art_1", "Art. 1", "1\\. Oggetto."),
+		article("art_5", "Art. 5", "1\\. Le imprese comunicano i dati.", "2\\. Le disposizioni del presente articolo si applicano a decorrere dal 1° gennaio 2024."),
+		article("art_9", "Art. 9 - Disposizioni finali",
+			"1\\. Gli articoli 3 e 4 acquistano efficacia decorsi sei mesi dalla data di entrata in vigore del presente decreto.",
+			"2\\. Il presente decreto entra in vigore il giorno stesso della sua pubblicazione."))
+
+	res, err := Calculate(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Act.Date != "2023-03-31" || res.Act.Explanation.Rule != RulePublicationDay || res.Act.Explanation.SectionID != "art_9" {
+		t.Errorf("unexpected act entry into force %+v", res.Act)
+	}
+	if len(res.Articles) != 2 {
+		t.Fatalf("expected 2 article-level dates, got %+v", res.Articles)
+	}
+	if a := res.Articles[0]; a.Date != "2024-01-01" || len(a.Articles) != 1 || a.Articles[0] != "5" || a.SectionIDs[0] != "art_5" {
+		t.Errorf("unexpected date for art. 5: %+v", a)
+	}
+	if a := res.Articles[1]; a.Date != "2023-09-30" || len(a.Articles) != 2 || a.Explanation.Rule != RuleAfterEntryIntoForce || a.Explanation.Offset != "P6M" {
+		t.Errorf("unexpected date for artt. 3 e 4: %+v", a)
+	}
+}
+
+func TestCalculateArticlesOfOtherActs(t *testing.T) {
+	doc := act("2023-03-31",
+		article("art_3", "Art. 3", "1\\. Oggetto."),
+		article("art_5", "Art. 5", "1\\. Ambito."),
+		article("art_9", "Art. 9 - Disposizioni finali",
+			"1\\. Gli articoli 3 e 5 del decreto legislativo n. 50 del 2016 entrano in vigore il 1° gennaio 2024.",
+			"2\\. L'articolo 5 si applica a decorrere dal 1° luglio 2023, l'art. 7 della legge 7 agosto 1990, n. 241 dal 1° settembre 2023."))
+
+	res, err := Calculate(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Act.Date != "2023-04-15" || res.Act.Explanation.Rule != RuleOrdinaryVacatio {
+		t.Errorf("clause about another act taken for the act: %+v", res.Act)
+	}
+	if len(res.Articles) != 1 {
+		t.Fatalf("expected 1 article-level date, got %+v", res.Articles)
+	}
+	if a := res.Articles[0]; a.Date != "2023-07-01" || len(a.Articles) != 1 || a.Articles[0] != "5" {
+		t.Errorf("unexpected date for art. 5: %+v", a)
+	}
+}
@@ -0,0 +1,549 @@
+package novella
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gterranova/normaplus/backend/internal/citation"
+	"github.com/gterranova/normaplus/backend/internal/deadlines"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// Operation types
+const (
+	OpReplace = "replace" // text or unit replaced by new text
+	OpInsert  = "insert"  // new text or unit inserted after an existing one
+	OpRepeal  = "repeal"  // text deleted or unit repealed
+	OpAdd     = "add"     // new text or unit appended at the end
+)
+
+// Units an operation applies to
+const (
+	UnitWords   = "words"
+	UnitPeriod  = "period"
+	UnitNumber  = "number"
+	UnitLetter  = "letter"
+	UnitComma   = "comma"
+	UnitArticle = "article"
+)
+
+// Target locates the provision an instruction amends.
+type Target struct {
+	URN     string `json:"urn,omitempty"` // act URN, with ~artN-comM fragment when known
+	Act     string `json:"act,omitempty"` // the act as cited
+	Article string `json:"article,omitempty"`
+	Comma   string `json:"comma,omitempty"`
+	Letter  string `json:"letter,omitempty"`
+	Number  string `json:"number,omitempty"`
+	Period  string `json:"period,omitempty"` // "1", "2", ... or "last"
+	Path    string `json:"path"`             // e.g. "art. 5, comma 2, lettera b)"
+}
+
+// Operation is one amendment instruction ("novella") of an amending act, e.g.
+// "all'articolo 5, comma 2, le parole «X» sono sostituite dalle seguenti: «Y»".
+type Operation struct {
+	Type      string `json:"type"`
+	Unit      string `json:"unit"`
+	Target    Target `json:"target"`
+	Old       string `json:"old,omitempty"`   // words replaced or deleted
+	New       string `json:"new,omitempty"`   // words or provision introduced
+	After     string `json:"after,omitempty"` // words, or number of the unit, the insertion follows
+	Units     string `json:"units,omitempty"` // the units repealed or replaced, e.g. "3" or "3, 4"
+	Global    bool   `json:"global,omitempty"`
+	Text      string `json:"text"`
+	SectionID string `json:"sectionId"`
+	Point     string `json:"point,omitempty"` // position of the instruction in the amending act, e.g. "1.a.2"
+	// Source is the amending act, with the article and comma holding the instruction
+	Source document.Reference `json:"source"`
+}
+
+const numPattern = `\d+(?:[\s-]?(?:bis|ter|quater|quinquies|sexies|septies|octies|novies|decies|undecies|duodecies|terdecies|quaterdecies))?`
+const letterPattern = `[a-z]{1,2}(?:[\s-]?(?:bis|ter|quater|quinquies|sexies|septies|octies|novies|decies))?`
+const ordinalPattern = `primo|secondo|terzo|quarto|quinto|sesto|settimo|ottavo|nono|decimo|ultimo|penultimo`
+
+var (
+	// Point labels and the nesting level they open
+	labelRes = []struct {
+		re    *regexp.Regexp
+		level int
+	}{
+		{regexp.MustCompile(`^(\d+\.\d+)\)\s+`), 4},
+		{regexp.MustCompile(`^(` + numPattern + `)\)\s+`), 3},
+		{regexp.MustCompile(`^(` + letterPattern + `)\)\s+`), 2},
+		{regexp.MustCompile(`^(` + numPattern + `)\.\s+`), 1},
+	}
+
+	// Where the instruction applies: "all'articolo 5", "al comma 2", "alla lettera b)"
+	locArticleRe = regexp.MustCompile(`(?i)\b(?:all|dell|nell)'(?:articolo|art\.)\s*(` + numPattern + `)(?:\s*,\s*comma\s+(` + numPattern + `))?(?:\s*,\s*lettera\s+(` + letterPattern + `)\))?`)
+	locCommaRe   = regexp.MustCompile(`(?i)\b(?:al|del|nel)\s+comma\s+(` + numPattern + `)`)
+	locLetterRe  = regexp.MustCompile(`(?i)\b(?:alla|della|nella)\s+lettera\s+(` + letterPattern + `)\)`)
+	locNumberRe  = regexp.MustCompile(`(?i)\b(?:al|del|nel)\s+numero\s+(` + numPattern + `)\)?`)
+	locPeriodRe  = regexp.MustCompile(`(?i)\b(?:al|del|nel)\s+(` + ordinalPattern + `)\s+periodo`)
+	sameActRe    = regexp.MustCompile(`(?i)\b(?:medesimo|predetto|citato|suddetto)\s+(?:decreto|legge|codice)`)
+
+	// Instructions on words. Quoted text has been replaced by Q<n> placeholders.
+	replaceWordsRe = regexp.MustCompile(`(?i)\b(?:le\s+parole|la\s+parola|le\s+seguenti\s+parole)[:,]?\s*Q(\d+)\s*,?\s*(ovunque\s+ricorr\w+\s*,?\s*)?(?:sono|è)\s+sostituit[ae]\s+(?:dalle\s+seguenti|dalla\s+seguente)(?:\s+parol[ae])?\s*[:,]?\s*Q(\d+)`)
+	deleteWordsRe  = regexp.MustCompile(`(?i)\b(?:le\s+parole|la\s+parola)[:,]?\s*Q(\d+)\s*,?\s*(ovunque\s+ricorr\w+\s*,?\s*)?(?:sono|è)\s+soppress[ae]`)
+	insertWordsRe  = regexp.MustCompile(`(?i)\bdopo\s+(?:le\s+parole|la\s+parola)[:,]?\s*Q(\d+)\s*,?\s*(ovunque\s+ricorr\w+\s*,?\s*)?(?:sono|è)\s+inserit[ae]\s+(?:le\s+seguenti|la\s+seguente)(?:\s+parol[ae])?\s*[:,]?\s*Q(\d+)`)
+
+	// Instructions on units
+	unitNoun       = `(comma|commi|articolo|articoli|lettera|lettere|numero|numeri|periodo|periodi)`
+	replaceUnitRe  = regexp.MustCompile(`(?i)\b(?:il|l'|la|i|gli|le)\s*` + unitNoun + `\s+((?:` + numPattern + `|` + letterPattern + `)\)?(?:\s*(?:,|e)\s*(?:` + numPattern + `|` + letterPattern + `)\)?)*)(?:\s+[^;:]*?)?\s*(?:è|sono)\s+sostituit[oiae]\s+(?:dal|dalla|dai|dalle)\s+seguent[ei]`)
+	repealUnitRe   = regexp.MustCompile(`(?i)\b(?:il|l'|la|i|gli|le)\s*` + unitNoun + `\s+((?:` + numPattern + `|` + letterPattern + `)\)?(?:\s*(?:,|e)\s*(?:` + numPattern + `|` + letterPattern + `)\)?)*)(?:\s+[^;:]*?)?\s*(?:è|sono)\s+(?:abrogat|soppress)[oiae]`)
+	periodOpRe     = regexp.MustCompile(`(?i)\b(?:il\s+)?(` + ordinalPattern + `)\s+periodo\s+(?:è\s+sostituito\s+dal\s+seguente|è\s+soppresso)`)
+	insertUnitRe   = regexp.MustCompile(`(?i)\bdopo\s+(?:il|l'|la|i)\s*(comma|articolo|lettera|numero|periodo)\s+(` + numPattern + `|` + letterPattern + `)\)?\s*,?\s*(?:(?:è|sono)\s+inserit[oiae])\s+(?:il|i|la|le)\s+seguent[ei]`)
+	addUnitRe      = regexp.MustCompile(`(?i)\b(?:è|sono)\s+aggiunt[oiae]\s*,?\s*(?:in\s+fine\s*,?\s*)?(?:il|i|la|le)\s+seguent[ei](?:\s+(comma|commi|articolo|articoli|lettera|lettere|numero|numeri|periodo|periodi|parola|parole))?`)
+	contextUnitRe  = regexp.MustCompile(`(?i)^\s*(?:è|sono)\s+sostituit[oiae]\s+(?:dal|dalla|dai|dalle)\s+seguent[ei]|^\s*(?:è|sono)\s+(?:abrogat|soppress)[oiae]`)
+	placeholderRe  = regexp.MustCompile(`Q(\d+)`)
+	unitNumberRe   = regexp.MustCompile(`(?i)` + numPattern)
+	unitLetterRe   = regexp.MustCompile(`(?i)\b(` + letterPattern + `)\)`)
+	newCommaTextRe = regexp.MustCompile(`^\(?\d+(?:-?[a-z]+)?\.\s`)
+	newArticleRe   = regexp.MustCompile(`(?i)^\(?art(?:icolo|\.)\s*\d+`)
+	newLetterRe    = regexp.MustCompile(`^\(?[a-z]{1,2}(?:-[a-z]+)?\)\s`)
+	newNumberRe    = regexp.MustCompile(`^\(?\d+(?:-[a-z]+)?\)\s`)
+)
+
+// scope is what the instructions of a point inherit from the enclosing ones.
+type scope struct {
+	urn, act                               string
+	article, comma, letter, number, period string
+}
+
+// Extract returns the amendment instructions contained in doc, in order.
+func Extract(doc *document.Document) []Operation {
+	var ops []Operation
+	doc.Walk(func(path []*document.DocumentSection, s *document.DocumentSection) error {
+		if len(s.Content) == 0 {
+			return nil
+		}
+		// "Modifiche al decreto legislativo 31 marzo 2023, n. 36", in the
+		// title of the section or of the nearest enclosing one citing an act
+		var ctx scope
+		for _, title := range append(titles(path), s.Title) {
+			if c := citation.Find(title); len(c) > 0 {
+				ctx.urn, ctx.act = c[0].URN(), c[0].Text
+			}
+		}
+		ops = append(ops, extractSection(s, ctx)...)
+		return nil
+	})
+
+	source := document.Reference{Text: doc.Title, URN: document.ActURN(doc.URN), Kind: document.RefItalianAct}
+	if c, ok := citation.FromURN(doc.URN); ok {
+		source.Text = c.Text
+	}
+	for i := range ops {
+		ops[i].Source = source
+		ops[i].Source.Article, _ = document.EIdTarget(ops[i].SectionID)
+		if comma, _, _ := strings.Cut(ops[i].Point, "."); comma != "" && comma[0] >= '0' && comma[0] <= '9' {
+			ops[i].Source.Comma = normalize(comma)
+		}
+	}
+	return ops
+}
+
+func titles(path []*document.DocumentSection) []string {
+	titles := make([]string, len(path))
+	for i, s := range path {
+		titles[i] = s.Title
+	}
+	return titles
+}
+
+// line is one point of an instruction list, with its quoted text pulled out.
+type line struct {
+	label  string
+	level  int
+	text   string   // as written
+	masked string   // with quotes replaced by Q<n>
+	quotes []string // quoted text, without « »
+}
+
+func extractSection(s *document.DocumentSection, base scope) []Operation {
+	var ops []Operation
+	stack := map[int]scope{0: base}
+	points := map[int]string{}
+	lastLevel := 0
+
+	for _, l := range splitLines(s.Content) {
+		level := l.level
+		if level == 0 {
+			// Unlabeled text belongs to the point before it
+			level = lastLevel + 1
+		}
+		parent := base
+		for p := level - 1; p >= 0; p-- {
+			if c, ok := stack[p]; ok {
+				parent = c
+				break
+			}
+		}
+		ctx := locate(parent, l.masked)
+		for k := range stack {
+			if k > level {
+				delete(stack, k)
+			}
+		}
+		stack[level] = ctx
+		if l.level > 0 {
+			lastLevel = l.level
+			points[l.level] = l.label
+			for k := range points {
+				if k > l.level {
+					delete(points, k)
+				}
+			}
+		}
+
+		for _, op := range parseLine(l, ctx) {
+			op.Text = l.text
+			op.SectionID = s.ID
+			op.Point = pointPath(points)
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// splitLines turns the content blocks of a section into labeled lines,
+// keeping text inside « » on one line even when it spans several.
+func splitLines(content []string) []line {
+	var texts []string
+	pending := false
+	for _, block := range content {
+		for _, raw := range strings.Split(document.PlainText(block), "\n") {
+			raw = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(raw), ">"))
+			if raw == "" {
+				continue
+			}
+			// Quoted text continuing, or starting right after "il seguente:"
+			last := len(texts) - 1
+			if pending || last >= 0 && strings.HasSuffix(texts[last], ":") && strings.HasPrefix(raw, "«") {
+				texts[last] += "\n" + raw
+			} else {
+				texts = append(texts, raw)
+				last++
+			}
+			pending = strings.Count(texts[last], "«") > strings.Count(texts[last], "»")
+		}
+	}
+
+	lines := make([]line, len(texts))
+	for i, text := range texts {
+		lines[i] = newLine(text)
+	}
+	return lines
+}
+
+func newLine(text string) line {
+	l := line{text: text}
+	body := text
+	for _, lr := range labelRes {
+		if m := lr.re.FindStringSubmatch(text); m != nil {
+			l.label, l.level = m[1], lr.level
+			body = text[len(m[0]):]
+			break
+		}
+	}
+	l.masked, l.quotes = maskQuotes(body)
+	return l
+}
+
+// maskQuotes replaces each top-level «…» (or “…”) with Q<n>, so citations and
+// verbs inside the quoted text are not mistaken for the instruction's own.
+func maskQuotes(text string) (string, []string) {
+	var sb, quote strings.Builder
+	var quotes []string
+	depth := 0
+	for _, r := range text {
+		switch {
+		case r == '«' || r == '“':
+			if depth == 0 {
+				quote.Reset()
+			} else {
+				quote.WriteRune(r)
+			}
+			depth++
+		case (r == '»' || r == '”') && depth > 0:
+			depth--
+			if depth == 0 {
+				fmt.Fprintf(&sb, "Q%d", len(quotes))
+				quotes = append(quotes, strings.TrimSpace(quote.String()))
+			} else {
+				quote.WriteRune(r)
+			}
+		case depth > 0:
+			quote.WriteRune(r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if depth > 0 {
+		fmt.Fprintf(&sb, "Q%d", len(quotes))
+		quotes = append(quotes, strings.TrimSpace(quote.String()))
+	}
+	return sb.String(), quotes
+}
+
+// locate refines ctx with the act and the provision named by a line.
+func locate(ctx scope, masked string) scope {
+	if c := citation.Find(masked); len(c) > 0 {
+		ctx = scope{urn: c[0].URN(), act: c[0].Text}
+	} else if sameActRe.MatchString(masked) {
+		ctx = scope{urn: ctx.urn, act: ctx.act}
+	}
+	if m := locArticleRe.FindStringSubmatch(masked); m != nil {
+		ctx.article, ctx.comma, ctx.letter, ctx.number, ctx.period = normalize(m[1]), normalize(m[2]), normalize(m[3]), "", ""
+	}
+	if m := locCommaRe.FindStringSubmatch(masked); m != nil {
+		ctx.comma, ctx.letter, ctx.number, ctx.period = normalize(m[1]), "", "", ""
+	}
+	if m := locLetterRe.FindStringSubmatch(masked); m != nil {
+		ctx.letter, ctx.number, ctx.period = normalize(m[1]), "", ""
+	}
+	if m := locNumberRe.FindStringSubmatch(masked); m != nil {
+		ctx.number, ctx.period = normalize(m[1]), ""
+	}
+	if m := locPeriodRe.FindStringSubmatch(masked); m != nil {
+		ctx.period = period(m[1])
+	}
+	return ctx
+}
+
+// parseLine recognizes the instructions in one line.
+func parseLine(l line, ctx scope) []Operation {
+	var ops []Operation
+	quote := func(i string) string {
+		var n int
+		fmt.Sscan(i, &n)
+		if n < len(l.quotes) {
+			return l.quotes[n]
+		}
+		return ""
+	}
+	// quotesAfter joins the quoted text following offset pos: the new provisions.
+	quotesAfter := func(pos int) string {
+		rest := l.masked[pos:]
+		if i := strings.Index(rest, ";"); i >= 0 {
+			rest = rest[:i]
+		}
+		var parts []string
+		for _, m := range placeholderRe.FindAllStringSubmatch(rest, -1) {
+			parts = append(parts, quote(m[1]))
+		}
+		return strings.Join(parts, "\n")
+	}
+
+	for _, m := range replaceWordsRe.FindAllStringSubmatch(l.masked, -1) {
+		ops = append(ops, Operation{Type: OpReplace, Unit: UnitWords, Target: target(ctx), Old: quote(m[1]), New: quote(m[3]), Global: m[2] != ""})
+	}
+	for _, m := range deleteWordsRe.FindAllStringSubmatch(l.masked, -1) {
+		ops = append(ops, Operation{Type: OpRepeal, Unit: UnitWords, Target: target(ctx), Old: quote(m[1]), Global: m[2] != ""})
+	}
+	for _, m := range insertWordsRe.FindAllStringSubmatch(l.masked, -1) {
+		ops = append(ops, Operation{Type: OpInsert, Unit: UnitWords, Target: target(ctx), After: quote(m[1]), New: quote(m[3]), Global: m[2] != ""})
+	}
+	if len(ops) > 0 {
+		return ops
+	}
+
+	if m := insertUnitRe.FindStringSubmatchIndex(l.masked); m != nil {
+		unit := unitName(l.masked[m[2]:m[3]])
+		after := normalize(l.masked[m[4]:m[5]])
+		return []Operation{{Type: OpInsert, Unit: unit, Target: target(ctx), After: after, New: quotesAfter(m[1])}}
+	}
+	if m := addUnitRe.FindStringSubmatchIndex(l.masked); m != nil {
+		noun := ""
+		if m[2] >= 0 {
+			noun = l.masked[m[2]:m[3]]
+		}
+		text := quotesAfter(m[1])
+		return []Operation{{Type: OpAdd, Unit: inferUnit(noun, text), Target: target(ctx), New: text}}
+	}
+	if m := periodOpRe.FindStringSubmatchIndex(l.masked); m != nil {
+		c := ctx
+		c.period = period(l.masked[m[2]:m[3]])
+		op := Operation{Type: OpRepeal, Unit: UnitPeriod, Target: target(c)}
+		if strings.Contains(strings.ToLower(l.masked[m[0]:m[1]]), "sostituito") {
+			op.Type, op.New = OpReplace, quotesAfter(m[1])
+		}
+		return []Operation{op}
+	}
+	for _, re := range []*regexp.Regexp{replaceUnitRe, repealUnitRe} {
+		m := re.FindStringSubmatchIndex(l.masked)
+		if m == nil {
+			continue
+		}
+		unit := unitName(l.masked[m[2]:m[3]])
+		var ops []Operation
+		var numbers []string
+		if unit == UnitLetter {
+			for _, lm := range unitLetterRe.FindAllStringSubmatch(l.masked[m[4]:m[5]], -1) {
+				numbers = append(numbers, lm[1])
+			}
+		} else {
+			numbers = unitNumberRe.FindAllString(l.masked[m[4]:m[5]], -1)
+		}
+		for _, n := range numbers {
+			n = normalize(n)
+			c := withUnit(ctx, unit, n)
+			op := Operation{Type: OpRepeal, Unit: unit, Target: target(c), Units: n}
+			if re == replaceUnitRe {
+				op.Type, op.New = OpReplace, quotesAfter(m[1])
+			}
+			ops = append(ops, op)
+		}
+		// Replacing several units with one text is a single operation
+		if re == replaceUnitRe && len(ops) > 1 {
+			var units []string
+			for _, op := range ops {
+				units = append(units, op.Units)
+			}
+			op := Operation{Type: OpReplace, Unit: unit, Target: target(ctx), Units: strings.Join(units, ", "), New: ops[0].New}
+			ops = []Operation{op}
+		}
+		return ops
+	}
+	// "b) l'articolo 7: è sostituito dal seguente" or "c) è abrogato": the unit is the context
+	if m := contextUnitRe.FindStringIndex(l.masked); m != nil && ctx.urn+ctx.article != "" {
+		unit := contextUnit(ctx)
+		op := Operation{Type: OpRepeal, Unit: unit, Target: target(ctx)}
+		if strings.Contains(strings.ToLower(l.masked[m[0]:m[1]]), "sostituit") {
+			op.Type, op.New = OpReplace, quotesAfter(m[1])
+		}
+		return []Operation{op}
+	}
+	return nil
+}
+
+// inferUnit names the unit added by "è aggiunto il seguente[ noun]: «text»",
+// looking at the text when the noun is omitted.
+func inferUnit(noun, text string) string {
+	if noun != "" {
+		return unitName(noun)
+	}
+	switch {
+	case newArticleRe.MatchString(text):
+		return UnitArticle
+	case newCommaTextRe.MatchString(text):
+		return UnitComma
+	case newLetterRe.MatchString(text):
+		return UnitLetter
+	case newNumberRe.MatchString(text):
+		return UnitNumber
+	}
+	return UnitWords
+}
+
+func unitName(noun string) string {
+	noun = strings.ToLower(noun)
+	switch {
+	case strings.HasPrefix(noun, "comm"):
+		return UnitComma
+	case strings.HasPrefix(noun, "artic"):
+		return UnitArticle
+	case strings.HasPrefix(noun, "letter"):
+		return UnitLetter
+	case strings.HasPrefix(noun, "numer"):
+		return UnitNumber
+	case strings.HasPrefix(noun, "period"):
+		return UnitPeriod
+	}
+	return UnitWords
+}
+
+// contextUnit is the innermost unit named by ctx.
+func contextUnit(ctx scope) string {
+	switch {
+	case ctx.period != "":
+		return UnitPeriod
+	case ctx.number != "":
+		return UnitNumber
+	case ctx.letter != "":
+		return UnitLetter
+	case ctx.comma != "":
+		return UnitComma
+	}
+	return UnitArticle
+}
+
+func withUnit(ctx scope, unit, n string) scope {
+	switch unit {
+	case UnitArticle:
+		ctx.article, ctx.comma, ctx.letter, ctx.number = n, "", "", ""
+	case UnitComma:
+		ctx.comma, ctx.letter, ctx.number = n, "", ""
+	case UnitLetter:
+		ctx.letter, ctx.number = n, ""
+	case UnitNumber:
+		ctx.number = n
+	}
+	return ctx
+}
+
+func target(ctx scope) Target {
+	t := Target{
+		Act:     ctx.act,
+		Article: ctx.article,
+		Comma:   ctx.comma,
+		Letter:  ctx.letter,
+		Number:  ctx.number,
+		Period:  ctx.period,
+	}
+	t.URN = document.Reference{URN: ctx.urn, Article: ctx.article, Comma: ctx.comma}.TargetURN()
+
+	var path []string
+	if t.Article != "" {
+		path = append(path, "art. "+t.Article)
+	}
+	if t.Comma != "" {
+		path = append(path, "comma "+t.Comma)
+	}
+	if t.Letter != "" {
+		path = append(path, "lettera "+t.Letter+")")
+	}
+	if t.Number != "" {
+		path = append(path, "numero "+t.Number+")")
+	}
+	switch t.Period {
+	case "":
+	case "last":
+		path = append(path, "ultimo periodo")
+	default:
+		path = append(path, "periodo "+t.Period)
+	}
+	t.Path = strings.Join(path, ", ")
+	return t
+}
+
+// period turns "secondo" into "2" and "ultimo" into "last".
+func period(ordinal string) string {
+	ordinal = strings.ToLower(ordinal)
+	switch ordinal {
+	case "ultimo":
+		return "last"
+	case "penultimo":
+		return "second-to-last"
+	}
+	if n, ok := deadlines.ParseNumber(ordinal); ok {
+		return fmt.Sprint(n)
+	}
+	return ordinal
+}
+
+// normalize turns "5-bis" or "5 bis" into "5bis", as in URN fragments.
+func normalize(n string) string {
+	n = strings.ToLower(strings.TrimSpace(strings.TrimSuffix(n, ")")))
+	return strings.NewReplacer("-", "", " ", "").Replace(n)
+}
+
+func pointPath(points map[int]string) string {
+	var parts []string
+	for level := 1; level <= 4; level++ {
+		if p, ok := points[level]; ok {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ".")
+}
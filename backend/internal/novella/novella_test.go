@@ -0,0 +1,45 @@
+package novella
+
+import (
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func TestExtract(t *testing.T) {
+	doc := document.Document{Sections: []document.DocumentSection{{
+		ID:    "art_1",
+		Type:  "article",
+		Title: "Art. 1 - Modifiche al decreto legislativo 31 marzo 2023, n. 36",
+		Content: []string{
+			"1\\. Al decreto legislativo 31 marzo 2023, n. 36, sono apportate le seguenti modificazioni:\n\na) all'articolo 5, comma 2, le parole «trenta giorni» sono sostituite dalle seguenti: «sessanta giorni»;\n\nb) all'articolo 7:\n\n  1) al comma 1, la parola «esclusivamente» è soppressa;\n\n  2) dopo il comma 3 è inserito il seguente: «3-bis. Le stazioni appaltanti pubblicano gli atti.»;\n\n  3) i commi 4 e 5 sono abrogati;\n\nc) dopo l'articolo 9 è inserito il seguente:\n«Art. 9-bis (Controlli). - 1. I controlli sono svolti dall'ANAC.»;\n\nd) all'articolo 12, comma 1, dopo le parole «operatori economici» sono inserite le seguenti: «, anche in forma associata,».",
+			"2\\. L'articolo 3 della legge 7 agosto 1990, n. 241, è sostituito dal seguente: «Art. 3. (Motivazione) 1. Ogni provvedimento è motivato.».",
+		},
+	}}}
+
+	ops := Extract(&doc)
+	want := []struct {
+		typ, unit, urn, path, old, new, after string
+	}{
+		{OpReplace, UnitWords, "urn:nir:stato:decreto.legislativo:2023-03-31;36~art5-com2", "art. 5, comma 2", "trenta giorni", "sessanta giorni", ""},
+		{OpRepeal, UnitWords, "urn:nir:stato:decreto.legislativo:2023-03-31;36~art7-com1", "art. 7, comma 1", "esclusivamente", "", ""},
+		{OpInsert, UnitComma, "urn:nir:stato:decreto.legislativo:2023-03-31;36~art7", "art. 7", "", "3-bis. Le stazioni appaltanti pubblicano gli atti.", "3"},
+		{OpRepeal, UnitComma, "urn:nir:stato:decreto.legislativo:2023-03-31;36~art7-com4", "art. 7, comma 4", "", "", ""},
+		{OpRepeal, UnitComma, "urn:nir:stato:decreto.legislativo:2023-03-31;36~art7-com5", "art. 7, comma 5", "", "", ""},
+		{OpInsert, UnitArticle, "urn:nir:stato:decreto.legislativo:2023-03-31;36", "", "", "Art. 9-bis (Controlli). - 1. I controlli sono svolti dall'ANAC.", "9"},
+		{OpInsert, UnitWords, "urn:nir:stato:decreto.legislativo:2023-03-31;36~art12-com1", "art. 12, comma 1", "", ", anche in forma associata,", "operatori economici"},
+		{OpReplace, UnitArticle, "urn:nir:stato:legge:1990-08-07;241~art3", "art. 3", "", "Art. 3. (Motivazione) 1. Ogni provvedimento è motivato.", ""},
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("expected %d operations, got %d: %+v", len(want), len(ops), ops)
+	}
+	for i, w := range want {
+		op := ops[i]
+		if op.Type != w.typ || op.Unit != w.unit || op.Target.URN != w.urn || op.Target.Path != w.path || op.Old != w.old || op.New != w.new || op.After != w.after {
+			t.Errorf("operation %d = %+v\nwant %+v", i, op, w)
+		}
+	}
+	if ops[1].Point != "1.b.1" || ops[1].SectionID != "art_1" {
+		t.Errorf("unexpected position of operation 1: %q in %q", ops[1].Point, ops[1].SectionID)
+	}
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+type Citation struct {
+	ID            int    `json:"id"`
+	SourceDoc     string `json:"source_doc"` // codice redazionale of the citing act
+	SourceURN     string `json:"source_urn"`
+	SourceTitle   string `json:"source_title"`
+	SourceDate    string `json:"source_date"`
+	SourceSection string `json:"source_section"` // ID of the citing article
+	SourceLabel   string `json:"source_label"`
+	TargetURN     string `json:"target_urn"` // act URN, or ELI for EU acts
+	TargetArticle string `json:"target_article"`
+	TargetComma   string `json:"target_comma"`
+	Kind          string `json:"kind"`
+	Text          string `json:"text"`
+}
+
+// DocumentCitations lists the outgoing references of doc, one per reference,
+// attributed to the nearest enclosing section with an ID.
+func DocumentCitations(doc *document.Document) []Citation {
+	var citations []Citation
+	self := document.ActURN(doc.URN)
+
+	// source is the nearest section of s or its ancestors with an ID
+	source := func(s *document.DocumentSection) (id, label string, ok bool) {
+		switch {
+		case s.ID != "":
+			return s.ID, s.Title, true
+		case s.Type == "preamble":
+			return "preamble", "Preambolo", true
+		}
+		return "", "", false
+	}
+	doc.Walk(func(path []*document.DocumentSection, s *document.DocumentSection) error {
+		id, label, ok := source(s)
+		for i := len(path) - 1; i >= 0 && !ok; i-- {
+			id, label, ok = source(path[i])
+		}
+		for _, ref := range s.References {
+			target := document.ActURN(ref.URN)
+			switch {
+			case ref.Kind == document.RefEUAct:
+				target = ref.ELI
+			case ref.Kind == document.RefSameAct && target == "":
+				target = self
+			}
+			if target == "" {
+				continue
+			}
+			citations = append(citations, Citation{
+				SourceDoc:     doc.CodiceRedazionale,
+				SourceURN:     self,
+				SourceTitle:   doc.Title,
+				SourceDate:    doc.DataGU,
+				SourceSection: id,
+				SourceLabel:   label,
+				TargetURN:     target,
+				TargetArticle: ref.Article,
+				TargetComma:   ref.Comma,
+				Kind:          string(ref.Kind),
+				Text:          ref.Text,
+			})
+		}
+		return nil
+	})
+
+	return citations
+}
+
+// SaveCitations replaces the stored outgoing references of doc.
+func (s *Store) SaveCitations(ctx context.Context, doc *document.Document) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM citations WHERE source_doc = ?", doc.CodiceRedazionale); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx,
+		"INSERT INTO citations (source_doc, source_urn, source_title, source_date, source_section, source_label, target_urn, target_article, target_comma, kind, text) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, c := range DocumentCitations(doc) {
+		if _, err := stmt.ExecContext(ctx, c.SourceDoc, c.SourceURN, c.SourceTitle, c.SourceDate, c.SourceSection, c.SourceLabel,
+			c.TargetURN, c.TargetArticle, c.TargetComma, c.Kind, c.Text); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListCitedBy returns the stored references from other acts to the act with
+// the given URN, optionally restricted to one article.
+func (s *Store) ListCitedBy(ctx context.Context, urn, article string) ([]Citation, error) {
+	query := "SELECT id, source_doc, source_urn, source_title, source_date, source_section, source_label, target_urn, target_article, target_comma, kind, text FROM citations WHERE target_urn = ? AND kind != ?"
+	args := []interface{}{document.ActURN(urn), string(document.RefSameAct)}
+
+	if article != "" {
+		query += " AND target_article = ?"
+		args = append(args, document.NormalizeNum(article))
+	}
+	query += " ORDER BY source_date DESC, source_doc, id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var citations []Citation
+	for rows.Next() {
+		var c Citation
+		if err := rows.Scan(&c.ID, &c.SourceDoc, &c.SourceURN, &c.SourceTitle, &c.SourceDate, &c.SourceSection, &c.SourceLabel,
+			&c.TargetURN, &c.TargetArticle, &c.TargetComma, &c.Kind, &c.Text); err != nil {
+			return nil, err
+		}
+		citations = append(citations, c)
+	}
+	return citations, nil
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func TestListCitedByArticle(t *testing.T) {
+	s, err := NewStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	target := "urn:nir:stato:decreto.legislativo:2023-03-31;36"
+	doc := &document.Document{CodiceRedazionale: "24G00001", URN: "urn:nir:stato:legge:2024-01-10;1", Sections: []document.DocumentSection{
+		{ID: "art_1", Type: "articolo", Title: "Art. 1", References: []document.Reference{
+			{Text: "articolo 18-bis", URN: target, Kind: document.RefItalianAct, Article: "18bis"},
+			{Text: "articolo 18", URN: target, Kind: document.RefItalianAct, Article: "18"},
+		}},
+	}}
+	if err := s.SaveCitations(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, article := range []string{"18bis", "18-bis", "18 bis", "18-BIS"} {
+		citations, err := s.ListCitedBy(ctx, target, article)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(citations) != 1 || citations[0].TargetArticle != "18bis" {
+			t.Errorf("article %q: got %+v", article, citations)
+		}
+	}
+}
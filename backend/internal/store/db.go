@@ -70,6 +70,53 @@ func (s *Store) migrate() error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
 		);`,
+		`CREATE TABLE IF NOT EXISTS citations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			source_doc TEXT NOT NULL,
+			source_urn TEXT NOT NULL DEFAULT '',
+			source_title TEXT NOT NULL DEFAULT '',
+			source_date TEXT NOT NULL DEFAULT '',
+			source_section TEXT NOT NULL DEFAULT '',
+			source_label TEXT NOT NULL DEFAULT '',
+			target_urn TEXT NOT NULL,
+			target_article TEXT NOT NULL DEFAULT '',
+			target_comma TEXT NOT NULL DEFAULT '',
+			kind TEXT NOT NULL DEFAULT '',
+			text TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_citations_target ON citations(target_urn, target_article);`,
+		`CREATE INDEX IF NOT EXISTS idx_citations_source ON citations(source_doc);`,
+		`CREATE TABLE IF NOT EXISTS export_templates (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL DEFAULT 0, -- 0: shared with the team
+			name TEXT NOT NULL,
+			firm_name TEXT NOT NULL DEFAULT '',
+			header_text TEXT NOT NULL DEFAULT '',
+			footer_text TEXT NOT NULL DEFAULT '',
+			stylesheet TEXT NOT NULL DEFAULT '',
+			reference_docx BLOB,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, name)
+		);`,
+		`CREATE TABLE IF NOT EXISTS sources (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			doc_id TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			date TEXT NOT NULL DEFAULT '',
+			vigenza TEXT NOT NULL DEFAULT '',
+			urn TEXT NOT NULL DEFAULT '',
+			source_url TEXT NOT NULL DEFAULT '',
+			fetched_at TEXT NOT NULL,
+			content_hash TEXT NOT NULL,
+			raw_hash TEXT NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_sources_content ON sources(content_hash);`,
+		`CREATE INDEX IF NOT EXISTS idx_sources_raw ON sources(raw_hash);`,
+		`CREATE TABLE IF NOT EXISTS source_xml (
+			raw_hash TEXT PRIMARY KEY, -- the same XML fetched again is kept once
+			xml BLOB NOT NULL
+		);`,
 	}
 
 	for _, q := range queries {
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// Source is an archived fetch of an act: what was asked for and the
+// Integrity record of what was received. The XML itself is stored once per
+// raw hash, see SourceXML.
+type Source struct {
+	ID          int    `json:"id"`
+	DocID       string `json:"doc_id"` // codice redazionale
+	Name        string `json:"name"`
+	Date        string `json:"date"`
+	Vigenza     string `json:"vigenza"`
+	URN         string `json:"urn"`
+	SourceURL   string `json:"source_url"`
+	FetchedAt   string `json:"fetched_at"`
+	ContentHash string `json:"content_hash"`
+	RawHash     string `json:"raw_hash"`
+}
+
+// ArchiveSource stores the XML doc was parsed from, with its Integrity
+// record. Documents without one are not archived.
+func (s *Store) ArchiveSource(ctx context.Context, doc *document.Document, raw []byte) error {
+	if doc.Integrity == nil {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	in := doc.Integrity
+	if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO source_xml (raw_hash, xml) VALUES (?, ?)", in.RawHash, raw); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO sources (doc_id, name, date, vigenza, urn, source_url, fetched_at, content_hash, raw_hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		doc.CodiceRedazionale, doc.Name, doc.DataGU, doc.Vigenza, doc.URN, in.SourceURL, in.FetchedAt, in.ContentHash, in.RawHash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindSources returns the archived fetches whose content or raw hash is
+// hash, oldest first.
+func (s *Store) FindSources(ctx context.Context, hash string) ([]Source, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, doc_id, name, date, vigenza, urn, source_url, fetched_at, content_hash, raw_hash
+		FROM sources WHERE content_hash = ? OR raw_hash = ? ORDER BY fetched_at, id`, hash, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sources []Source
+	for rows.Next() {
+		var src Source
+		if err := rows.Scan(&src.ID, &src.DocID, &src.Name, &src.Date, &src.Vigenza, &src.URN, &src.SourceURL, &src.FetchedAt, &src.ContentHash, &src.RawHash); err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+	return sources, rows.Err()
+}
+
+// SourceXML returns the archived XML with the given raw hash, or nil if it
+// is not in the archive.
+func (s *Store) SourceXML(ctx context.Context, rawHash string) ([]byte, error) {
+	var xml []byte
+	err := s.db.QueryRowContext(ctx, "SELECT xml FROM source_xml WHERE raw_hash = ?", rawHash).Scan(&xml)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return xml, err
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ExportTemplate is a named letterhead and theme for exports, owned by a
+// user or, with UserID 0, shared with the whole team.
+type ExportTemplate struct {
+	ID         int    `json:"id"`
+	UserID     int    `json:"user_id"`
+	Name       string `json:"name"`
+	FirmName   string `json:"firm_name"`
+	HeaderText string `json:"header_text"`
+	FooterText string `json:"footer_text"`
+	Stylesheet string `json:"stylesheet"`
+	// ReferenceDocx is a DOCX/DOTX whose styles DOCX exports use; it is
+	// left out of listings, which report HasReference instead.
+	ReferenceDocx []byte `json:"reference_docx,omitempty"`
+	HasReference  bool   `json:"has_reference"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// SaveExportTemplate creates a template, or replaces the one with the same
+// owner and name.
+func (s *Store) SaveExportTemplate(ctx context.Context, t *ExportTemplate) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO export_templates (user_id, name, firm_name, header_text, footer_text, stylesheet, reference_docx) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, name) DO UPDATE SET firm_name = excluded.firm_name, header_text = excluded.header_text, footer_text = excluded.footer_text,
+		stylesheet = excluded.stylesheet, reference_docx = excluded.reference_docx`,
+		t.UserID, t.Name, t.FirmName, t.HeaderText, t.FooterText, t.Stylesheet, t.ReferenceDocx)
+	if err != nil {
+		return err
+	}
+	return s.db.QueryRowContext(ctx, "SELECT id, created_at FROM export_templates WHERE user_id = ? AND name = ?", t.UserID, t.Name).
+		Scan(&t.ID, &t.CreatedAt)
+}
+
+// ListExportTemplates returns the templates of a user and the shared ones,
+// without their reference documents.
+func (s *Store) ListExportTemplates(ctx context.Context, userID int) ([]ExportTemplate, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, user_id, name, firm_name, header_text, footer_text, stylesheet, reference_docx IS NOT NULL AND length(reference_docx) > 0, created_at
+		FROM export_templates WHERE user_id IN (?, 0) ORDER BY user_id = 0, name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []ExportTemplate
+	for rows.Next() {
+		var t ExportTemplate
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.FirmName, &t.HeaderText, &t.FooterText, &t.Stylesheet, &t.HasReference, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// GetExportTemplate returns the template a user sees under name: their own,
+// or else the shared one. It returns nil if there is neither.
+func (s *Store) GetExportTemplate(ctx context.Context, userID int, name string) (*ExportTemplate, error) {
+	var t ExportTemplate
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, user_id, name, firm_name, header_text, footer_text, stylesheet, reference_docx, created_at
+		FROM export_templates WHERE user_id IN (?, 0) AND name = ? ORDER BY user_id = 0 LIMIT 1`, userID, name).
+		Scan(&t.ID, &t.UserID, &t.Name, &t.FirmName, &t.HeaderText, &t.FooterText, &t.Stylesheet, &t.ReferenceDocx, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.HasReference = len(t.ReferenceDocx) > 0
+	return &t, nil
+}
+
+// DeleteExportTemplate deletes a template owned by the user, or a shared one.
+func (s *Store) DeleteExportTemplate(ctx context.Context, userID, id int) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM export_templates WHERE id = ? AND user_id IN (?, 0)", id, userID)
+	return err
+}
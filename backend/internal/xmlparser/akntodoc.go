@@ -30,9 +30,15 @@ func aknToDocument(d *document.Document, xmlBytes []byte) error {
 		d.Title = normalizeWhitespace(docTitle)
 	}
 
+	if urn := extractURN(doc); urn != "" {
+		d.URN = urn
+	}
+	parseModifications(d, doc)
+
 	// 2. Preamble
 	preambleSection := document.NewDocumentSection("preamble", "", d)
 	doc.Find("preamble").Each(func(_ int, preamble *goquery.Selection) {
+		preambleSection.References = append(preambleSection.References, collectReferences(preamble)...)
 		preamble.Children().Each(func(_ int, elem *goquery.Selection) {
 			tagName := goquery.NodeName(elem)
 			if tagName == "formula" || tagName == "p" || tagName == "citations" {
@@ -161,6 +167,8 @@ func processArticle(s *document.DocumentSection, selection *goquery.Selection) {
 		s.ID = eid
 	}
 
+	s.References = append(s.References, collectReferences(selection)...)
+
 	// User requirement: heading separated from text body by a blank line.
 	header := num
 	if heading != "" {
@@ -380,22 +388,17 @@ func processQuotedStructure(child *goquery.Selection) string {
 }
 
 func processUpdates(s *document.DocumentSection, child *goquery.Selection) {
-	var sb strings.Builder
-
 	text := processInlineElements(child)
-	lines := strings.Split(text, "\n")
+	lines := strings.Split(subsAccent(text), "\n")
 
-	isUpdate := false
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if !isUpdate && strings.HasPrefix(line, "---") {
-			isUpdate = true
-			continue
-		} else if isUpdate && line != "" {
-			sb.WriteString(fmt.Sprintf("> %s\n> \n", line))
+	// Skip everything up to the "--------" separator
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "---") {
+			lines = lines[i+1:]
+			break
 		}
 	}
-	s.AddContent(subsAccent(sb.String()))
+	s.Updates = append(s.Updates, parseUpdateNotes(lines)...)
 }
 
 func processAttachmentNode(s *document.DocumentSection, selection *goquery.Selection) {
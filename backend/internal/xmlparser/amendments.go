@@ -0,0 +1,168 @@
+package xmlparser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gterranova/normaplus/backend/internal/citation"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+var (
+	updateHeadingRe = regexp.MustCompile(`^AGGIORNAMENTO\s*\((\d+)\)`)
+	updateSplitRe   = regexp.MustCompile(`\s+(AGGIORNAMENTO\s*\(\d+\))`)
+	noteMarkerRe    = regexp.MustCompile(`^\s*\(\((\d+)\)\)`)
+	amendingArtRe   = regexp.MustCompile(`\(con\s+l'art\.\s*[^)]*\)`)
+)
+
+// parseUpdateNotes splits the lines following a "--------" separator into
+// numbered update notes. Lines before the first "AGGIORNAMENTO (n)" heading
+// are kept as an unnumbered note. Headings run together with their text on a
+// single line (as NIR paragraphs are) are split apart first.
+func parseUpdateNotes(lines []string) []document.UpdateNote {
+	var notes []document.UpdateNote
+	var current *document.UpdateNote
+	var text []string
+
+	var split []string
+	for _, line := range lines {
+		split = append(split, strings.Split(updateSplitRe.ReplaceAllString(line, "\n$1"), "\n")...)
+	}
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Text = strings.Join(text, "\n")
+		current.Amending = amendingActs(current.Text)
+		notes = append(notes, *current)
+		current = nil
+		text = nil
+	}
+
+	for _, line := range split {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-"))
+		if line == "" {
+			continue
+		}
+		if m := updateHeadingRe.FindStringSubmatch(line); m != nil {
+			flush()
+			num, _ := strconv.Atoi(m[1])
+			current = &document.UpdateNote{Number: num, Heading: m[0]}
+			if rest := strings.TrimSpace(line[len(m[0]):]); rest != "" {
+				text = append(text, rest)
+			}
+			continue
+		}
+		if current == nil {
+			current = &document.UpdateNote{}
+		}
+		text = append(text, line)
+	}
+	flush()
+
+	return notes
+}
+
+// amendingActs extracts the acts cited by an update note. Notes read
+// "Il D.L. ..., convertito ... dalla L. ..., ha disposto (con l'art. 13, comma 1) ...":
+// the article in parentheses belongs to the first act cited before it.
+func amendingActs(text string) []document.Reference {
+	var acts []document.Reference
+	artLoc := amendingArtRe.FindStringIndex(text)
+	for i, c := range citation.Find(text) {
+		if i == 0 && artLoc != nil && artLoc[0] >= c.End {
+			c.Article, c.Comma = citation.FindArticle(text[artLoc[0]:artLoc[1]])
+		}
+		acts = append(acts, document.Reference{
+			Text:    c.Text,
+			URN:     c.URN(),
+			Kind:    document.RefItalianAct,
+			Article: c.Article,
+			Comma:   c.Comma,
+		})
+	}
+	return acts
+}
+
+// indexAmendments records the (( … )) spans of every section as ModifiedSpans
+// and links each to the update note whose ((n)) marker follows it.
+func indexAmendments(d *document.Document) {
+	d.Walk(func(_ []*document.DocumentSection, s *document.DocumentSection) error {
+		s.Modified = findModifiedSpans(s.Content)
+		return nil
+	})
+}
+
+// findModifiedSpans scans content blocks for (( … )) pairs. A span closes at the
+// last two parentheses of the first run of two or more, so "lettera e)))" keeps
+// its own closing parenthesis. Spans left open at the end of a block continue
+// into the next one.
+func findModifiedSpans(content []string) []document.ModifiedSpan {
+	var spans []document.ModifiedSpan
+	open := false
+
+	for ci, text := range content {
+		pos := 0
+		for pos < len(text) {
+			start := pos
+			if !open {
+				idx := strings.Index(text[pos:], "((")
+				if idx < 0 {
+					break
+				}
+				start = pos + idx + 2
+				// ((n)) is a note marker, not amended text
+				if m := noteMarkerRe.FindStringSubmatch(text[pos+idx:]); m != nil {
+					num, _ := strconv.Atoi(m[1])
+					linkNote(spans, ci, pos+idx, num)
+					pos += idx + len(m[0])
+					continue
+				}
+				open = true
+			}
+
+			end := closingRun(text, start)
+			if end < 0 {
+				if start < len(text) {
+					spans = append(spans, document.ModifiedSpan{Content: ci, Start: start, End: len(text), Text: text[start:]})
+				}
+				break
+			}
+			if end > start {
+				spans = append(spans, document.ModifiedSpan{Content: ci, Start: start, End: end, Text: text[start:end]})
+			}
+			open = false
+			pos = end + 2
+		}
+	}
+	return spans
+}
+
+// closingRun returns the offset of the "))" closing a span that starts at from, or -1.
+func closingRun(text string, from int) int {
+	for i := from; i < len(text)-1; i++ {
+		if text[i] != ')' || text[i+1] != ')' {
+			continue
+		}
+		j := i
+		for j < len(text) && text[j] == ')' {
+			j++
+		}
+		return j - 2
+	}
+	return -1
+}
+
+// linkNote assigns note to the span immediately preceding a ((n)) marker at
+// offset at in content block ci.
+func linkNote(spans []document.ModifiedSpan, ci, at, note int) {
+	if len(spans) == 0 {
+		return
+	}
+	last := &spans[len(spans)-1]
+	if last.Content == ci && at-last.End <= 3 {
+		last.Note = note
+	}
+}
@@ -0,0 +1,83 @@
+package xmlparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+const amendedAKN = `<?xml version="1.0" encoding="UTF-8"?>
+<akomaNtoso>
+  <act>
+    <body>
+      <article eId="art_5">
+        <num>Art. 5</num>
+        <heading>Termini</heading>
+        <paragraph eId="art_5__para_1">
+          <num>1.</num>
+          <content><p>Il termine e' fissato in ((sessanta giorni)) ((1)).</p></content>
+        </paragraph>
+        <paragraph eId="art_5__para_2">
+          <content><p>((2. Le disposizioni di cui alla lettera e) si applicano dal 2024.))</p></content>
+        </paragraph>
+        <paragraph>
+          <content><p>-------------<eol/>AGGIORNAMENTO (1)<eol/>Il D.L. 22 giugno 2012, n. 83, convertito con modificazioni dalla L. 7 agosto 2012, n. 134, ha disposto (con l'art. 13, comma 1) la modifica.</p></content>
+        </paragraph>
+      </article>
+    </body>
+  </act>
+</akomaNtoso>`
+
+func TestAmendmentsAndUpdateNotes(t *testing.T) {
+	doc := document.NewDocument("", "", "", "")
+	if err := FromXML(&doc, []byte(amendedAKN)); err != nil {
+		t.Fatalf("FromXML failed: %v", err)
+	}
+
+	var article *document.DocumentSection
+	for i := range doc.Sections {
+		for j := range doc.Sections[i].Children {
+			if doc.Sections[i].Children[j].ID == "art_5" {
+				article = &doc.Sections[i].Children[j]
+			}
+		}
+	}
+	if article == nil {
+		t.Fatal("article art_5 not found")
+	}
+
+	if len(article.Modified) != 2 {
+		t.Fatalf("expected 2 modified spans, got %d: %+v", len(article.Modified), article.Modified)
+	}
+	if got := article.Modified[0]; got.Text != "sessanta giorni" || got.Note != 1 {
+		t.Errorf("unexpected first span: %+v", got)
+	}
+	if got := article.Modified[1].Text; !strings.HasSuffix(got, "lettera e) si applicano dal 2024.") {
+		t.Errorf("second span should keep the list parenthesis, got %q", got)
+	}
+
+	if len(article.Updates) != 1 {
+		t.Fatalf("expected 1 update note, got %d", len(article.Updates))
+	}
+	note := article.Updates[0]
+	if note.Number != 1 || len(note.Amending) != 2 {
+		t.Fatalf("unexpected note: %+v", note)
+	}
+	if a := note.Amending[0]; a.URN != "urn:nir:stato:decreto.legge:2012-06-22;83" || a.Article != "13" || a.Comma != "1" {
+		t.Errorf("unexpected amending act: %+v", a)
+	}
+	if note.Amending[1].URN != "urn:nir:stato:legge:2012-08-07;134" {
+		t.Errorf("unexpected converting law URN: %s", note.Amending[1].URN)
+	}
+
+	md, _ := doc.ToMarkdown()
+	if !strings.Contains(string(md), "**((sessanta giorni))**") || !strings.Contains(string(md), "> AGGIORNAMENTO (1)") {
+		t.Errorf("markdown should highlight amendments and keep notes:\n%s", md)
+	}
+
+	md, _ = doc.ToMarkdownWithOptions(document.MarkdownOptions{HideApparatus: true})
+	if strings.Contains(string(md), "((") || strings.Contains(string(md), "AGGIORNAMENTO") {
+		t.Errorf("apparatus should be hidden:\n%s", md)
+	}
+}
@@ -13,10 +13,25 @@ import (
 func FromXML(d *document.Document, xmlBytes []byte) error {
 	d.Title, _ = extractTitle(xmlBytes)
 	format := detectXMLFormat(xmlBytes)
+	var err error
 	if format == "NIR" {
-		return nirToDocument(d, xmlBytes)
+		err = nirToDocument(d, xmlBytes)
+	} else {
+		err = aknToDocument(d, xmlBytes)
 	}
-	return aknToDocument(d, xmlBytes)
+	if err != nil {
+		return err
+	}
+	Reindex(d)
+	return nil
+}
+
+// Reindex recomputes what FromXML derives from the content of d (amended
+// spans, reference kinds, article status), for documents edited after parsing.
+func Reindex(d *document.Document) {
+	indexAmendments(d)
+	classifyReferences(d)
+	detectStatus(d)
 }
 
 // Global regex for detecting NIR vs AKN
@@ -97,23 +112,9 @@ func processInlineElements(root *goquery.Selection) string {
 				href, _ := selection.Attr("href")
 				text := processInlineElements(selection)
 
-				// Transform AKN/URN to valid Normattiva linker
-				if strings.HasPrefix(href, "/akn/") {
-					href = aknToUrn(href)
-				}
-				if strings.HasPrefix(href, "urn:nir:") {
-					href = "https://www.normattiva.it/uri-res/N2Ls?" + href
-				} else if strings.HasPrefix(href, "/act/") { // Sometimes relative path?
-					// Fallback
-					href = "https://www.normattiva.it" + href
-				}
-
-				if href != "" {
-					sb.WriteString(fmt.Sprintf("[%s](%s)", text, href))
-				} else {
-					// Could be a link to eu regulation or directive
-					sb.WriteString(text)
-				}
+				// The Markdown link is one rendering of the structured reference:
+				// Normattiva resolver for Italian acts, ELI for EU acts.
+				sb.WriteString(resolveReference(href, text).Markdown())
 			case "ins":
 				sb.WriteString(subsAccent(processInlineElements(selection)))
 			//case "authorialNote":
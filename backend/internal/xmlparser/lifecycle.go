@@ -0,0 +1,95 @@
+package xmlparser
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// parseModifications reads the <lifecycle> and the passive and active
+// modifications of the AKN <analysis> into d. Sources point to the
+// <references> of <meta>. A modification is dated by the <lifecycle> event
+// its period points to, either directly or through a <temporalGroup>; it has
+// no date without one.
+func parseModifications(d *document.Document, doc *goquery.Document) {
+	d.Lifecycle, d.Modifications = nil, nil
+
+	refs := map[string]document.Reference{}
+	doc.Find("references").Children().Each(func(_ int, ref *goquery.Selection) {
+		if id := ref.AttrOr("eid", ""); id != "" {
+			text := normalizeWhitespace(ref.AttrOr("showas", ""))
+			refs[id] = resolveReference(ref.AttrOr("href", ""), text)
+		}
+	})
+
+	events := map[string]document.LifecycleEvent{}
+	doc.Find("lifecycle eventRef").Each(func(_ int, e *goquery.Selection) {
+		source := strings.TrimPrefix(e.AttrOr("source", ""), "#")
+		event := document.LifecycleEvent{
+			ID:     e.AttrOr("eid", ""),
+			Date:   e.AttrOr("date", ""),
+			Type:   e.AttrOr("type", ""),
+			Source: refs[source],
+		}
+		d.Lifecycle = append(d.Lifecycle, event)
+		events[event.ID] = event
+	})
+
+	periods := map[string]string{}
+	doc.Find("temporalData temporalGroup").Each(func(_ int, g *goquery.Selection) {
+		start := strings.TrimPrefix(g.Find("timeInterval").First().AttrOr("start", ""), "#")
+		periods[g.AttrOr("eid", "")] = events[start].Date
+	})
+
+	self := document.ActURN(d.URN)
+	parse := func(direction string, mod *goquery.Selection) {
+		m := document.Modification{
+			ID:        mod.AttrOr("eid", ""),
+			Direction: direction,
+			Type:      mod.AttrOr("type", goquery.NodeName(mod)),
+			Old:       subsAccent(normalizeWhitespace(mod.ChildrenFiltered("old").Text())),
+			New:       subsAccent(normalizeWhitespace(mod.ChildrenFiltered("new").Text())),
+		}
+
+		source := mod.ChildrenFiltered("source").First().AttrOr("href", "")
+		if ref, ok := refs[strings.TrimPrefix(source, "#")]; ok {
+			m.Source = ref
+		} else if source != "" {
+			m.Source = resolveReference(source, "")
+		}
+		if direction == document.ModActive && (m.Source.Kind == document.RefSameAct || m.Source.URN == "") {
+			m.Source.Text, m.Source.URN, m.Source.Kind = d.Title, self, document.RefSameAct
+		}
+
+		destination := mod.ChildrenFiltered("destination").First().AttrOr("href", "")
+		if ref, ok := refs[strings.TrimPrefix(destination, "#")]; ok {
+			destination = ref.Href
+		}
+		path, fragment, _ := strings.Cut(destination, "#")
+		m.Target = fragment
+		if direction == document.ModActive {
+			if strings.HasPrefix(path, "/akn/") {
+				m.TargetURN = aknToUrn(path)
+			} else {
+				m.TargetURN, _, _ = document.SplitURN(path)
+			}
+		}
+
+		if period := strings.TrimPrefix(mod.AttrOr("period", ""), "#"); period != "" {
+			if date, ok := periods[period]; ok {
+				m.Date = date
+			} else {
+				m.Date = events[period].Date
+			}
+		}
+		d.Modifications = append(d.Modifications, m)
+	}
+
+	doc.Find("analysis passiveModifications").Children().Each(func(_ int, mod *goquery.Selection) {
+		parse(document.ModPassive, mod)
+	})
+	doc.Find("analysis activeModifications").Children().Each(func(_ int, mod *goquery.Selection) {
+		parse(document.ModActive, mod)
+	})
+}
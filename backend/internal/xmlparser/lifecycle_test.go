@@ -0,0 +1,101 @@
+package xmlparser
+
+import (
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+const lifecycleAKN = `<?xml version="1.0" encoding="UTF-8"?>
+<akomaNtoso>
+  <act>
+    <meta>
+      <identification source="#redattore">
+        <FRBRWork>
+          <FRBRthis value="/akn/it/act/decreto.legislativo/stato/2023-03-31/36/!main"/>
+        </FRBRWork>
+      </identification>
+      <lifecycle source="#redattore">
+        <eventRef eId="evt_1" date="2023-03-31" source="#ro1" type="generation"/>
+        <eventRef eId="evt_2" date="2025-01-01" source="#rp1" type="amendment"/>
+        <eventRef eId="evt_3" date="2025-07-01" source="#rp1" type="amendment"/>
+      </lifecycle>
+      <analysis source="#redattore">
+        <passiveModifications>
+          <textualMod eId="pmod_1" type="substitution">
+            <source href="#rp1"/>
+            <destination href="#art_5__para_2"/>
+            <old>trenta giorni</old>
+            <new>sessanta giorni</new>
+          </textualMod>
+          <textualMod eId="pmod_3" type="substitution" period="#evt_2">
+            <source href="#rp1"/>
+            <destination href="#art_9__para_1"/>
+          </textualMod>
+          <textualMod eId="pmod_2" type="repeal" period="#tg_2">
+            <source href="#rp1"/>
+            <destination href="#art_7"/>
+          </textualMod>
+        </passiveModifications>
+        <activeModifications>
+          <textualMod eId="amod_1" type="insertion">
+            <source href="#art_5__para_3"/>
+            <destination href="#ra1"/>
+          </textualMod>
+        </activeModifications>
+      </analysis>
+      <temporalData source="#redattore">
+        <temporalGroup eId="tg_2">
+          <timeInterval start="#evt_3" refersTo="#vigenza"/>
+        </temporalGroup>
+      </temporalData>
+      <references source="#redattore">
+        <original eId="ro1" href="/akn/it/act/decreto.legislativo/stato/2023-03-31/36/!main" showAs="D.Lgs. 31 marzo 2023, n. 36"/>
+        <passiveRef eId="rp1" href="/akn/it/act/decreto.legislativo/stato/2024-12-31/209/!main" showAs="D.Lgs. 31 dicembre 2024, n. 209"/>
+        <activeRef eId="ra1" href="/akn/it/act/legge/stato/1990-08-07/241/!main#art_3" showAs="L. 7 agosto 1990, n. 241"/>
+      </references>
+    </meta>
+    <body>
+      <article eId="art_5">
+        <num>Art. 5</num>
+        <paragraph eId="art_5__para_2">
+          <num>2.</num>
+          <content><p>Il ricorso è proposto entro ((sessanta giorni)).</p></content>
+        </paragraph>
+      </article>
+    </body>
+  </act>
+</akomaNtoso>`
+
+func TestModifications(t *testing.T) {
+	doc := document.NewDocument("", "", "2023-03-31", "2025-08-01")
+	if err := FromXML(&doc, []byte(lifecycleAKN)); err != nil {
+		t.Fatalf("FromXML failed: %v", err)
+	}
+
+	if len(doc.Lifecycle) != 3 || doc.Lifecycle[1].Source.URN != "urn:nir:stato:decreto.legislativo:2024-12-31;209" {
+		t.Errorf("unexpected lifecycle %+v", doc.Lifecycle)
+	}
+	if len(doc.Modifications) != 4 {
+		t.Fatalf("expected 4 modifications, got %+v", doc.Modifications)
+	}
+
+	mods := doc.ModificationsOf("art_5")
+	if len(mods) != 1 {
+		t.Fatalf("expected 1 modification of art. 5, got %+v", mods)
+	}
+	if m := mods[0]; m.Type != "substitution" || m.Target != "art_5__para_2" || m.Date != "" || m.Source.Text != "D.Lgs. 31 dicembre 2024, n. 209" || m.New != "sessanta giorni" {
+		t.Errorf("unexpected modification %+v", m)
+	}
+	if m := doc.ModificationsOf("art_7"); len(m) != 1 || m[0].Date != "2025-07-01" {
+		t.Errorf("the repeal of art. 7 should take effect from the temporal group, got %+v", m)
+	}
+	if m := doc.ModificationsOf("art_9"); len(m) != 1 || m[0].Date != "2025-01-01" {
+		t.Errorf("the modification of art. 9 should take effect from its event, got %+v", m)
+	}
+
+	active := doc.Modifications[3]
+	if active.Direction != document.ModActive || active.TargetURN != "urn:nir:stato:legge:1990-08-07;241" || active.Target != "art_3" || active.Source.Article != "5" || active.Source.Comma != "3" {
+		t.Errorf("unexpected active modification %+v", active)
+	}
+}
@@ -28,9 +28,14 @@ func nirToDocument(d *document.Document, xmlBytes []byte) error {
 		d.Title = subsAccent(normalizeWhitespace(docTitle))
 	}
 
+	if urn := extractURN(doc); urn != "" {
+		d.URN = urn
+	}
+
 	// 2. Preamble
 	preambleSection := document.NewDocumentSection("preamble", "", d)
 	doc.Find("formulainiziale").Each(func(_ int, preamble *goquery.Selection) {
+		preambleSection.References = append(preambleSection.References, collectReferences(preamble)...)
 		preamble.Children().Each(func(_ int, elem *goquery.Selection) {
 			tagName := goquery.NodeName(elem)
 			if tagName == "formula" || tagName == "p" || tagName == "citations" {
@@ -128,6 +133,14 @@ func processNIRContent(s *document.DocumentSection, selection *goquery.Selection
 		}
 		return text
 
+	case "rif":
+		href := selection.AttrOr("xlink:href", selection.AttrOr("href", ""))
+		text := processNIRInner(s, selection)
+		if href != "" {
+			return resolveReference(href, text).Markdown()
+		}
+		return text
+
 	case "span", "b", "strong", "i", "em", "testata", "denAnnesso", "titAnnesso":
 		// Formatting could be added here, for now pass through
 		return processNIRInner(s, selection)
@@ -289,6 +302,7 @@ func processNIRArticle(s *document.DocumentSection, selection *goquery.Selection
 
 	s.ID = strings.ToLower(strings.ReplaceAll(strings.ReplaceAll(num, ".", "_"), " ", ""))
 	s.Title = header
+	s.References = append(s.References, collectReferences(selection)...)
 
 	// If we have cleanArticleNodes (from split), iterate them
 	// Note: cleanArticleNodes are purely the *contents* of the first comma (and maybe others?)
@@ -487,8 +501,14 @@ func processNIRComma(s *document.DocumentSection, selection *goquery.Selection,
 	}
 
 	newCommaRe := regexp.MustCompile(`^[\(\s]*\d+[a-z-]*\\?\.[\s\)\)]+`)
+	var updates []string
 	for _, line := range strings.Split(text, "\n\n") {
 		line = normalizeWhitespace(line)
+		// Everything after the "--------" separator is the update notes apparatus
+		if len(updates) > 0 || strings.HasPrefix(line, "---") || updateHeadingRe.MatchString(line) {
+			updates = append(updates, subsAccent(line))
+			continue
+		}
 		if line != "" {
 			possibleNewComma := newCommaRe.FindString(line)
 			num := extractNumberFromComma(possibleNewComma)
@@ -510,6 +530,10 @@ func processNIRComma(s *document.DocumentSection, selection *goquery.Selection,
 		s.AddContent(subsAccent(strings.TrimSpace(remaining)))
 	}
 
+	if len(updates) > 0 {
+		s.Updates = append(s.Updates, parseUpdateNotes(updates)...)
+	}
+
 	/*
 		if num != "" {
 			// Ensure num has dot
@@ -0,0 +1,123 @@
+package xmlparser
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+// EU act types as they appear in AKN hrefs, mapped to ELI resource types.
+var euActTypes = map[string]string{
+	"regolamento": "reg",
+	"direttiva":   "dir",
+	"decisione":   "dec",
+}
+
+// resolveReference turns the href of a <ref>, <rif> or <a> element into a
+// Reference. Whether it points back to the same act is only known once the
+// whole document has been parsed; see classifyReferences.
+func resolveReference(href, text string) document.Reference {
+	href = strings.TrimSpace(href)
+	ref := document.Reference{Text: text, Href: href, Kind: document.RefUnknown}
+
+	switch {
+	case strings.HasPrefix(href, "/akn/"):
+		resolveAKNHref(&ref, href)
+	case strings.HasPrefix(href, "urn:nir:"):
+		ref.Kind = document.RefItalianAct
+		ref.URN, ref.Article, ref.Comma = document.SplitURN(href)
+	case strings.Contains(href, "/uri-res/N2Ls?"):
+		ref.Kind = document.RefItalianAct
+		ref.URN, ref.Article, ref.Comma = document.SplitURN(href[strings.Index(href, "?")+1:])
+	case strings.HasPrefix(href, "#"):
+		ref.Kind = document.RefSameAct
+		ref.Article, ref.Comma = document.EIdTarget(href[1:])
+	}
+	return ref
+}
+
+// resolveAKNHref handles /akn/it/act/{type}/{authority}/{date}/{number}/!main#eId
+func resolveAKNHref(ref *document.Reference, href string) {
+	path, fragment, _ := strings.Cut(href, "#")
+	parts := strings.Split(path, "/")
+	if len(parts) < 8 {
+		return
+	}
+	docType, authority, date, number := parts[4], parts[5], parts[6], parts[7]
+
+	if (docType == "regolamento" && authority == "") || authority == "eu" {
+		ref.Kind = document.RefEUAct
+		if eliType, ok := euActTypes[docType]; ok && len(date) >= 4 && number != "" && number != "0" {
+			ref.ELI = "http://data.europa.eu/eli/" + eliType + "/" + date[:4] + "/" + number + "/oj"
+		}
+		return
+	}
+
+	ref.Kind = document.RefItalianAct
+	ref.URN = aknToUrn(path)
+	ref.Article, ref.Comma = document.EIdTarget(fragment)
+}
+
+// collectReferences returns the references found anywhere below selection.
+func collectReferences(selection *goquery.Selection) []document.Reference {
+	var refs []document.Reference
+	selection.Find("*").Each(func(_ int, elem *goquery.Selection) {
+		switch goquery.NodeName(elem) {
+		case "ref", "rif", "a", "h:a":
+		default:
+			return
+		}
+		href := elem.AttrOr("href", elem.AttrOr("xlink:href", ""))
+		if href == "" {
+			return
+		}
+		text := subsAccent(normalizeWhitespace(elem.Text()))
+		refs = append(refs, resolveReference(href, text))
+	})
+	return refs
+}
+
+// classifyReferences marks the references that point back to d itself, once
+// its URN is known.
+func classifyReferences(d *document.Document) {
+	self := document.ActURN(d.URN)
+	mark := func(refs []document.Reference) {
+		for i := range refs {
+			if self != "" && refs[i].URN != "" && document.ActURN(refs[i].URN) == self {
+				refs[i].Kind = document.RefSameAct
+			}
+		}
+	}
+	d.Walk(func(_ []*document.DocumentSection, s *document.DocumentSection) error {
+		mark(s.References)
+		for j := range s.Updates {
+			mark(s.Updates[j].Amending)
+		}
+		return nil
+	})
+}
+
+// extractURN returns the URN of the act described by an AKN or NIR document.
+func extractURN(doc *goquery.Document) string {
+	var urn string
+	doc.Find("FRBRWork FRBRalias").EachWithBreak(func(_ int, alias *goquery.Selection) bool {
+		if strings.HasPrefix(alias.AttrOr("value", ""), "urn:nir:") {
+			urn = alias.AttrOr("value", "")
+			return false
+		}
+		return true
+	})
+	if urn == "" {
+		if this := doc.Find("FRBRWork FRBRthis").First().AttrOr("value", ""); strings.HasPrefix(this, "/akn/") {
+			urn = aknToUrn(this)
+		}
+	}
+	if urn == "" {
+		// NIR: <meta><descrittori><urn valore="urn:nir:..."/>
+		nir := doc.Find("descrittori urn").First()
+		urn = nir.AttrOr("valore", strings.TrimSpace(nir.Text()))
+	}
+	act, _, _ := document.SplitURN(urn)
+	return act
+}
@@ -0,0 +1,101 @@
+package xmlparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+func TestResolveReference(t *testing.T) {
+	cases := []struct {
+		href    string
+		kind    document.ReferenceKind
+		urn     string
+		eli     string
+		article string
+		comma   string
+	}{
+		{"/akn/it/act/legge/stato/1990-08-07/241/!main#art_3__para_2", document.RefItalianAct, "urn:nir:stato:legge:1990-08-07;241", "", "3", "2"},
+		{"urn:nir:stato:decreto.legislativo:2023-03-31;36~art5-com2", document.RefItalianAct, "urn:nir:stato:decreto.legislativo:2023-03-31;36", "", "5", "2"},
+		{"/akn/it/act/regolamento//2016-04-27/679/!main", document.RefEUAct, "", "http://data.europa.eu/eli/reg/2016/679/oj", "", ""},
+		{"#art_12bis", document.RefSameAct, "", "", "12bis", ""},
+		{"mailto:someone", document.RefUnknown, "", "", "", ""},
+	}
+
+	for _, c := range cases {
+		ref := resolveReference(c.href, "text")
+		if ref.Kind != c.kind || ref.URN != c.urn || ref.ELI != c.eli || ref.Article != c.article || ref.Comma != c.comma {
+			t.Errorf("resolveReference(%q) = %+v", c.href, ref)
+		}
+	}
+}
+
+const referencesAKN = `<?xml version="1.0" encoding="UTF-8"?>
+<akomaNtoso>
+  <act>
+    <meta>
+      <identification>
+        <FRBRWork>
+          <FRBRthis value="/akn/it/act/legge/stato/1990-08-07/241/!main"/>
+          <FRBRalias name="urn:nir" value="urn:nir:stato:legge:1990-08-07;241"/>
+        </FRBRWork>
+      </identification>
+    </meta>
+    <body>
+      <article eId="art_2">
+        <num>Art. 2</num>
+        <paragraph eId="art_2__para_1">
+          <num>1.</num>
+          <content><p>Ai sensi dell'<ref href="/akn/it/act/legge/stato/1990-08-07/241/!main#art_1">articolo 1</ref> e del <ref href="/akn/it/act/regolamento//2016-04-27/679/!main">regolamento (UE) 2016/679</ref>.</p></content>
+        </paragraph>
+      </article>
+    </body>
+  </act>
+</akomaNtoso>`
+
+func TestReferencesOnSection(t *testing.T) {
+	doc := document.NewDocument("", "", "", "")
+	if err := FromXML(&doc, []byte(referencesAKN)); err != nil {
+		t.Fatalf("FromXML failed: %v", err)
+	}
+	if doc.URN != "urn:nir:stato:legge:1990-08-07;241" {
+		t.Fatalf("unexpected document URN %q", doc.URN)
+	}
+
+	article := doc.Sections[1].Children[0]
+	if len(article.References) != 2 {
+		t.Fatalf("expected 2 references, got %+v", article.References)
+	}
+	if ref := article.References[0]; ref.Kind != document.RefSameAct || ref.Article != "1" {
+		t.Errorf("expected a same-act reference to art. 1, got %+v", ref)
+	}
+	if ref := article.References[1]; ref.Kind != document.RefEUAct || ref.Text != "regolamento (UE) 2016/679" {
+		t.Errorf("expected an EU reference, got %+v", ref)
+	}
+}
+
+func TestLinkModes(t *testing.T) {
+	doc := document.NewDocument("", "", "", "")
+	if err := FromXML(&doc, []byte(referencesAKN)); err != nil {
+		t.Fatalf("FromXML failed: %v", err)
+	}
+	doc.Sections[1].Children = append(doc.Sections[1].Children, document.DocumentSection{ID: "art_1", Type: "article", Title: "Art. 1"})
+	doc.Sections[1].Children[0].Content = append(doc.Sections[1].Children[0].Content,
+		"Si veda la [legge 1990, n. 241](https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:legge:1990-08-07;241~art1) e il [decreto](https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:decreto.legislativo:2023-03-31;36~art5).")
+
+	cases := map[document.LinkMode][]string{
+		document.LinkNormattiva: {"(https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:legge:1990-08-07;241~art1)", "(https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:decreto.legislativo:2023-03-31;36~art5)"},
+		document.LinkApp:        {"(#art_1)", "(/?urn=urn%3Anir%3Astato%3Adecreto.legislativo%3A2023-03-31%3B36~art5)"},
+		document.LinkStandalone: {"(#art_1)", "(https://www.normattiva.it/uri-res/N2Ls?urn:nir:stato:decreto.legislativo:2023-03-31;36~art5)"},
+		document.LinkNone:       {"Si veda la legge 1990, n. 241 e il decreto."},
+	}
+	for mode, want := range cases {
+		md, _ := doc.ToMarkdownWithOptions(document.MarkdownOptions{Links: mode})
+		for _, w := range want {
+			if !strings.Contains(string(md), w) {
+				t.Errorf("links=%s: expected %q in\n%s", mode, w, md)
+			}
+		}
+	}
+}
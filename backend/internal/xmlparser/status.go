@@ -0,0 +1,184 @@
+package xmlparser
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/gterranova/normaplus/backend/internal/deadlines"
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+var (
+	repealedRe   = regexp.MustCompile(`(?i)\b(?:abrogat[oaie]|soppress[oaie])\b`)
+	repealNoteRe = regexp.MustCompile(`(?i)\b(?:abrogat[oaie]|soppress[oaie]|abrogazione|soppressione)\b`)
+	suspendedRe  = regexp.MustCompile(`(?i)\b(?:sospes[oaie]|sospensione)\b`)
+	notInForceRe = regexp.MustCompile(`(?i)\bnon ancora in vigore\b`)
+	// What a marker such as "ARTICOLO ABROGATO DAL ..." refers to
+	markerScopeRe = regexp.MustCompile(`^(ARTICOLO|ARTT?\.|COMMA|COMMI|CAPO|TITOLO|SEZIONE|LIBRO|PARTE|ALLEGATO)\b`)
+	statusCommaRe = regexp.MustCompile(`^[\(\s]*(\d+(?:-?[a-z]+)?)\.\s*`)
+	noteRefRe     = regexp.MustCompile(`\s*\(\(\d+\)\)`)
+	// Update notes deferring the application of the whole article
+	thisArticleRe = regexp.MustCompile(`(?i)\b(?:disposizioni (?:di cui al|del)|il) presente articolo\b`)
+	takesEffectRe = regexp.MustCompile(`(?i)\b(?:acquista(?:no)? efficacia|si applica(?:no)?|entra(?:no)? in vigore|ha(?:nno)? effetto)\b`)
+)
+
+// detectStatus records which sections and commas are repealed, suspended or
+// not yet in force at the document's vigenza. Normattiva states it with
+// uppercase markers replacing the text ("((ARTICOLO ABROGATO DAL D.LGS. ...))",
+// "((COMMA SOPPRESSO DALLA L. ...))") or, for deferred and suspended articles,
+// in the update notes.
+func detectStatus(d *document.Document) {
+	d.Walk(func(_ []*document.DocumentSection, s *document.DocumentSection) error {
+		s.Status, s.CommaStatus = nil, nil
+
+		if _, heading, ok := strings.Cut(s.Title, " - "); ok {
+			if st, _ := markerStatus(heading); st != nil {
+				s.Status = st
+			}
+		}
+
+		for ci, block := range s.Content {
+			text := document.PlainText(block)
+			comma := ""
+			if m := statusCommaRe.FindStringSubmatch(text); m != nil {
+				comma, text = m[1], text[len(m[0]):]
+			}
+			st, scope := markerStatus(text)
+			if st == nil {
+				continue
+			}
+			if st.By == nil {
+				st.By = noteReference(s, noteOf(s, ci), st.Status)
+			}
+			if s.Status == nil && comma == "" && (scope == "ARTICOLO" || scope == "" && len(s.Content) == 1) {
+				s.Status = st
+				continue
+			}
+			s.CommaStatus = append(s.CommaStatus, document.CommaStatus{Content: ci, Comma: comma, SectionStatus: *st})
+		}
+
+		if s.Status == nil {
+			s.Status = noteStatus(d, s)
+		} else if s.Status.By == nil {
+			s.Status.By = noteReference(s, nil, s.Status.Status)
+		}
+		return nil
+	})
+}
+
+// markerStatus reads a Normattiva status marker, returning nil when text is
+// not one. scope is the kind of unit named by the marker (ARTICOLO, COMMA, ...).
+func markerStatus(text string) (*document.SectionStatus, string) {
+	inner := strings.TrimSpace(noteRefRe.ReplaceAllString(text, ""))
+	wrapped := strings.HasPrefix(inner, "((")
+	inner = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(inner, "(("), "))"))
+	inner = strings.TrimSpace(strings.Trim(inner, "()."))
+	if inner == "" || len(inner) > 400 || !mostlyUpper(inner) {
+		return nil, ""
+	}
+	scope := markerScopeRe.FindString(inner)
+	// Uppercase headings such as "NORME ABROGATE" are not markers
+	if first, _, _ := strings.Cut(inner, " "); !wrapped && scope == "" && !repealedRe.MatchString(first) && !suspendedRe.MatchString(first) {
+		return nil, ""
+	}
+
+	st := &document.SectionStatus{Text: inner}
+	switch {
+	case notInForceRe.MatchString(inner):
+		st.Status = document.StatusNotInForce
+	case suspendedRe.MatchString(inner):
+		st.Status = document.StatusSuspended
+	case repealedRe.MatchString(inner):
+		st.Status = document.StatusRepealed
+	default:
+		return nil, ""
+	}
+	if acts := amendingActs(inner); len(acts) > 0 {
+		st.By = &acts[0]
+	}
+
+	if strings.HasPrefix(scope, "ART") {
+		scope = "ARTICOLO"
+	}
+	return st, scope
+}
+
+// noteStatus derives the status of an article from its update notes: notes
+// suspending the article, or deferring it to a date after the vigenza.
+func noteStatus(d *document.Document, s *document.DocumentSection) *document.SectionStatus {
+	if d.Vigenza == "" {
+		return nil
+	}
+	for i := range s.Updates {
+		note := &s.Updates[i]
+		for _, sentence := range strings.Split(note.Text, "\n") {
+			if !thisArticleRe.MatchString(sentence) || strings.Contains(strings.ToLower(sentence), "modific") {
+				continue
+			}
+			for _, dl := range deadlines.Find(sentence) {
+				if dl.Kind != deadlines.KindDate {
+					continue
+				}
+				st := &document.SectionStatus{Text: sentence, Date: dl.Date, By: noteReference(s, note, "")}
+				switch {
+				case suspendedRe.MatchString(sentence) && dl.Relation == "until" && d.Vigenza <= dl.Date:
+					st.Status = document.StatusSuspended
+				case takesEffectRe.MatchString(sentence) && dl.Relation == "from" && d.Vigenza < dl.Date:
+					st.Status = document.StatusNotInForce
+				default:
+					continue
+				}
+				return st
+			}
+		}
+	}
+	return nil
+}
+
+// noteOf returns the update note linked to the amended text of content block ci.
+func noteOf(s *document.DocumentSection, ci int) *document.UpdateNote {
+	for _, span := range s.Modified {
+		if span.Content == ci && span.Note != 0 {
+			return s.Note(span.Note)
+		}
+	}
+	return nil
+}
+
+// noteReference returns the amending act of note or, when note is nil, of the
+// first note of s mentioning status; nil if none cites an act.
+func noteReference(s *document.DocumentSection, note *document.UpdateNote, status document.Status) *document.Reference {
+	if note == nil {
+		re := map[document.Status]*regexp.Regexp{
+			document.StatusRepealed:   repealNoteRe,
+			document.StatusSuspended:  suspendedRe,
+			document.StatusNotInForce: takesEffectRe,
+		}[status]
+		for i := range s.Updates {
+			if re != nil && re.MatchString(s.Updates[i].Text) {
+				note = &s.Updates[i]
+				break
+			}
+		}
+	}
+	if note == nil || len(note.Amending) == 0 {
+		return nil
+	}
+	ref := note.Amending[0]
+	return &ref
+}
+
+// mostlyUpper tells whether at least 80% of the letters of s are uppercase.
+func mostlyUpper(s string) bool {
+	var letters, upper int
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return letters > 0 && upper*5 >= letters*4
+}
@@ -0,0 +1,85 @@
+package xmlparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gterranova/normaplus/backend/normattiva/document"
+)
+
+const statusAKN = `<?xml version="1.0" encoding="UTF-8"?>
+<akomaNtoso>
+  <act>
+    <body>
+      <article eId="art_3">
+        <num>Art. 3</num>
+        <paragraph eId="art_3__para_1">
+          <content><p>((ARTICOLO ABROGATO DAL D.LGS. 31 MARZO 2023, N. 36))</p></content>
+        </paragraph>
+      </article>
+      <article eId="art_4">
+        <num>Art. 4</num>
+        <heading>Norme abrogate</heading>
+        <paragraph eId="art_4__para_1">
+          <num>1.</num>
+          <content><p>Sono abrogate le disposizioni incompatibili.</p></content>
+        </paragraph>
+        <paragraph eId="art_4__para_2">
+          <num>2.</num>
+          <content><p>((COMMA SOPPRESSO DALLA L. 27 DICEMBRE 2017, N. 205))</p></content>
+        </paragraph>
+      </article>
+      <article eId="art_6">
+        <num>Art. 6</num>
+        <heading>Obblighi</heading>
+        <paragraph eId="art_6__para_1">
+          <num>1.</num>
+          <content><p>Le imprese pubblicano i dati.</p></content>
+        </paragraph>
+        <paragraph>
+          <content><p>-------------<eol/>AGGIORNAMENTO (1)<eol/>Il D.Lgs. 10 marzo 2023, n. 24 ha disposto (con l'art. 24, comma 1) che le disposizioni del presente articolo si applicano a decorrere dal 1° gennaio 2030.</p></content>
+        </paragraph>
+      </article>
+    </body>
+  </act>
+</akomaNtoso>`
+
+func TestStatusDetection(t *testing.T) {
+	doc := document.NewDocument("", "", "2020-01-01", "2025-01-01")
+	if err := FromXML(&doc, []byte(statusAKN)); err != nil {
+		t.Fatalf("FromXML failed: %v", err)
+	}
+
+	articles := map[string]*document.DocumentSection{}
+	for i := range doc.Sections {
+		for j := range doc.Sections[i].Children {
+			s := &doc.Sections[i].Children[j]
+			articles[s.ID] = s
+		}
+	}
+
+	if st := articles["art_3"].Status; st == nil || st.Status != document.StatusRepealed || st.By == nil || st.By.URN != "urn:nir:stato:decreto.legislativo:2023-03-31;36" {
+		t.Errorf("art. 3 should be repealed by D.Lgs. 36/2023, got %+v", st)
+	}
+	if art4 := articles["art_4"]; !art4.IsActive() || len(art4.CommaStatus) != 1 || art4.CommaStatus[0].Comma != "2" || art4.CommaStatus[0].Status != document.StatusRepealed {
+		t.Errorf("only comma 2 of art. 4 should be repealed, got %+v / %+v", art4.Status, art4.CommaStatus)
+	}
+	if st := articles["art_6"].Status; st == nil || st.Status != document.StatusNotInForce || st.Date != "2030-01-01" {
+		t.Errorf("art. 6 should not be in force yet, got %+v", st)
+	}
+
+	summary := doc.StatusSummary()
+	if summary.Articles != 3 || summary.InForce != 1 || summary.Repealed != 1 || summary.NotInForce != 1 || summary.Commas != 1 || len(summary.Entries) != 3 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	md, _ := doc.ToMarkdownWithOptions(document.MarkdownOptions{Inactive: document.InactiveCollapse})
+	if !strings.Contains(string(md), "*Abrogato* ([D.LGS. 31 MARZO 2023, N. 36]") || !strings.Contains(string(md), "**2\\.** *Abrogato*") {
+		t.Errorf("inactive sections should be collapsed:\n%s", md)
+	}
+
+	md, _ = doc.ToMarkdownWithOptions(document.MarkdownOptions{Inactive: document.InactiveOmit})
+	if strings.Contains(string(md), "Art. 3") || strings.Contains(string(md), "SOPPRESSO") || strings.Contains(string(md), "Art. 6") || !strings.Contains(string(md), "Norme abrogate") {
+		t.Errorf("inactive sections should be omitted:\n%s", md)
+	}
+}
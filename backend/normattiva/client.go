@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -19,6 +20,11 @@ import (
 
 type Client struct {
 	httpClient *http.Client
+	onParse    []func(*document.Document)
+	onSource   []func(*document.Document, []byte)
+	// reported holds the cache files already passed to the OnParse
+	// functions by this process
+	reported sync.Map
 }
 
 func NewClient(timeout time.Duration) *Client {
@@ -31,6 +37,21 @@ func NewClient(timeout time.Duration) *Client {
 	}
 }
 
+// OnParse registers fn to be called with every document Fetch parses from
+// Normattiva XML. Documents served from the cache are reported too, the first
+// time this Client reads them, so that acts cached by an earlier run are not
+// missed.
+func (c *Client) OnParse(fn func(*document.Document)) {
+	c.onParse = append(c.onParse, fn)
+}
+
+// OnSource registers fn to be called, after the OnParse functions, with
+// every document Fetch parses and the XML it was parsed from, to archive the
+// source its Integrity record refers to.
+func (c *Client) OnSource(fn func(doc *document.Document, raw []byte)) {
+	c.onSource = append(c.onSource, fn)
+}
+
 type DocumentMetadata struct {
 	Title                     string `json:"title"`
 	DataPubblicazioneGazzetta string `json:"data_pubblicazione_gazzetta"`
@@ -158,6 +179,11 @@ func (c *Client) Fetch(codiceRedazionale, name, date, vigenza string) (*document
 	} else {
 		result, err := c.retrieveFromCache(codiceRedazionale, vigenza, cacheDir)
 		if err == nil && result != nil {
+			if _, seen := c.reported.LoadOrStore(codiceRedazionale+"_"+vigenza, true); !seen {
+				for _, fn := range c.onParse {
+					fn(result)
+				}
+			}
 			return result, nil
 		}
 	}
@@ -173,19 +199,43 @@ func (c *Client) Fetch(codiceRedazionale, name, date, vigenza string) (*document
 		}
 	}
 
-	data, err := c.FetchXML(codiceRedazionale, date, vigenza)
+	data, source, err := c.fetchXML(codiceRedazionale, date, vigenza)
 	if err != nil {
 		return nil, err
 	}
 
-	doc := document.NewDocument(codiceRedazionale, name, date, vigenza)
-	if err := xmlparser.FromXML(&doc, data); err != nil {
+	doc, err := ParseXML(codiceRedazionale, name, date, vigenza, data)
+	if err != nil {
 		return nil, err
 	}
+	doc.Integrity = &document.Integrity{
+		ContentHash: doc.ContentHash(),
+		SourceURL:   source,
+		FetchedAt:   time.Now().UTC().Format(time.RFC3339),
+		RawHash:     document.HashBytes(data),
+	}
+
+	for _, fn := range c.onParse {
+		fn(doc)
+	}
+	c.reported.Store(codiceRedazionale+"_"+vigenza, true)
+	for _, fn := range c.onSource {
+		fn(doc, data)
+	}
 
 	// Save to cache
-	c.saveToCache(doc, cacheDir)
+	c.saveToCache(*doc, cacheDir)
 
+	return doc, nil
+}
+
+// ParseXML parses the XML of a document as Fetch does, so that an archived
+// source gives back the same document and content hash.
+func ParseXML(codiceRedazionale, name, date, vigenza string, data []byte) (*document.Document, error) {
+	doc := document.NewDocument(codiceRedazionale, name, date, vigenza)
+	if err := xmlparser.FromXML(&doc, data); err != nil {
+		return nil, err
+	}
 	return &doc, nil
 }
 
@@ -229,9 +279,17 @@ func (*Client) retrieveFromCache(codiceRedazionale, vigenza, cacheDir string) (*
 	return nil, nil
 }
 
+// FetchXML fetches the XML of a document, in Akoma Ntoso when Normattiva
+// has it and in its plain export format otherwise.
 func (c *Client) FetchXML(codiceRedazionale, date, vigenza string) ([]byte, error) {
+	data, _, err := c.fetchXML(codiceRedazionale, date, vigenza)
+	return data, err
+}
+
+// fetchXML is FetchXML, also returning the URL the XML was read from.
+func (c *Client) fetchXML(codiceRedazionale, date, vigenza string) ([]byte, string, error) {
 	if err := c.ensureCookies(); err != nil {
-		return nil, fmt.Errorf("failed to init cookies: %w", err)
+		return nil, "", fmt.Errorf("failed to init cookies: %w", err)
 	}
 
 	// Endpoint: /do/atto/caricaAKN?dataGU=...&codiceRedaz=...&dataVigenza=...
@@ -249,42 +307,43 @@ func (c *Client) FetchXML(codiceRedazionale, date, vigenza string) ([]byte, erro
 	//fmt.Printf("DEBUG: Visiting detail page: %s\n", detailURL)
 	detailReq, err := http.NewRequest("GET", detailURL, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	detailReq.Header.Set("User-Agent", userAgent)
 	detailReq.Header.Set("Referer", baseURL+"/ricerca/veloce/0") // Referer from search
 	detailResp, err := c.httpClient.Do(detailReq)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer detailResp.Body.Close()
 
 	if detailResp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("normattiva error: %s", detailResp.Status)
+		return nil, "", fmt.Errorf("normattiva error: %s", detailResp.Status)
 	}
 
 	// Read response
 	detailData, err := io.ReadAll(detailResp.Body)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var data []byte
+	var source string
 
 	if strings.Contains(string(detailData), "/do/atto/caricaAKN") {
-		data, err = c.fetchAKNXML(codiceRedazionale, date, vigenza)
+		data, source, err = c.fetchAKNXML(codiceRedazionale, date, vigenza)
 	} else {
-		data, err = c.fetchPlainXML(codiceRedazionale, date, vigenza)
+		data, source, err = c.fetchPlainXML(codiceRedazionale, date, vigenza)
 	}
 
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return data, nil
+	return data, source, nil
 }
 
 // fetchAKNXML fetches the Akoma Ntoso XML for a given document.
-func (c *Client) fetchAKNXML(codiceRedazionale, date, vigenza string) ([]byte, error) {
+func (c *Client) fetchAKNXML(codiceRedazionale, date, vigenza string) ([]byte, string, error) {
 
 	// Normalize dates to YYYYMMDD
 	dateParam := strings.ReplaceAll(date, "-", "")
@@ -309,7 +368,7 @@ func (c *Client) fetchAKNXML(codiceRedazionale, date, vigenza string) ([]byte, e
 
 	req, err := http.NewRequest("GET", xmlURL, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	req.Header.Set("User-Agent", userAgent)
 	req.Header.Set("Referer", detailURL) // Referer from detail page
@@ -317,36 +376,36 @@ func (c *Client) fetchAKNXML(codiceRedazionale, date, vigenza string) ([]byte, e
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 
 	//fmt.Printf("DEBUG: XML Fetch Status: %s\n", resp.Status)
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch XML: %s", resp.Status)
+		return nil, "", fmt.Errorf("failed to fetch XML: %s", resp.Status)
 	}
 
 	// Read body
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// Validation: Check if it's actually XML (Normattiva sometimes returns HTML error pages with 200 OK)
 	bodyStr := strings.TrimSpace(string(data))
 	if !strings.HasPrefix(bodyStr, "<?xml") && strings.HasPrefix(bodyStr, "<!DOCTYPE") {
-		data, err = c.fetchPlainXML(codiceRedazionale, date, vigenza)
+		data, xmlURL, err = c.fetchPlainXML(codiceRedazionale, date, vigenza)
 		if err != nil {
-			return nil, fmt.Errorf("normattiva session error: returned HTML instead of XML. Try refreshing the page.")
+			return nil, "", fmt.Errorf("normattiva session error: returned HTML instead of XML. Try refreshing the page.")
 		}
 	}
 
 	if len(data) < 100 {
-		return nil, fmt.Errorf("normattiva error: empty or too small response")
+		return nil, "", fmt.Errorf("normattiva error: empty or too small response")
 	}
 
-	return data, nil
+	return data, xmlURL, nil
 }
 
 // ResolveURN resolves a Normattiva URN to its Codice Redazionale and Date.
@@ -417,9 +476,9 @@ func (c *Client) ResolveURN(urn string) (string, string, string, error) {
 
 // fetchPlainXML attempts to fetch XML via the /do/atto/export endpoint
 // This is used as a fallback for documents that don't have AKN format available
-func (c *Client) fetchPlainXML(codiceRedazionale, date, vigenza string) ([]byte, error) {
+func (c *Client) fetchPlainXML(codiceRedazionale, date, vigenza string) ([]byte, string, error) {
 	if err := c.ensureCookies(); err != nil {
-		return nil, fmt.Errorf("failed to init cookies: %w", err)
+		return nil, "", fmt.Errorf("failed to init cookies: %w", err)
 	}
 	//fmt.Printf("DEBUG: Attempting plain XML export for %s (%s) vigenza=%s\n", codiceRedazionale, date, vigenza)
 
@@ -446,7 +505,7 @@ func (c *Client) fetchPlainXML(codiceRedazionale, date, vigenza string) ([]byte,
 
 	req, err := http.NewRequest("POST", exportURL, strings.NewReader(formData.Encode()))
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -457,32 +516,33 @@ func (c *Client) fetchPlainXML(codiceRedazionale, date, vigenza string) ([]byte,
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 
 	//fmt.Printf("DEBUG: Export endpoint Status: %s\n", resp.Status)
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to export XML: %s", resp.Status)
+		return nil, "", fmt.Errorf("failed to export XML: %s", resp.Status)
 	}
 
 	// Read response
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// Validation: Check if it's actually XML
 	bodyStr := strings.TrimSpace(string(data))
 	if !strings.HasPrefix(bodyStr, "<?xml") && strings.HasPrefix(bodyStr, "<!DOCTYPE") {
-		return nil, fmt.Errorf("export endpoint returned HTML instead of XML (document may not have XML export available)")
+		return nil, "", fmt.Errorf("export endpoint returned HTML instead of XML (document may not have XML export available)")
 	}
 
 	if len(data) < 100 {
-		return nil, fmt.Errorf("export endpoint returned empty or too small response")
+		return nil, "", fmt.Errorf("export endpoint returned empty or too small response")
 	}
 
 	//fmt.Printf("DEBUG: Successfully fetched plain XML (%d bytes)\n", len(data))
-	return data, nil
+	// The form is part of the source: record it as the query
+	return data, exportURL + "?" + formData.Encode(), nil
 }
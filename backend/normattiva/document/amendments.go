@@ -0,0 +1,63 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModifiedSpan is a run of text that Normattiva marks as amended by wrapping it
+// in (( … )). Start and End are byte offsets of the text between the markers
+// inside Content[Content]; a span that opens in one content block and closes in
+// a later one is split into one span per block.
+type ModifiedSpan struct {
+	Content int    `json:"content"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Text    string `json:"text"`
+	Note    int    `json:"note,omitempty"` // number of the UpdateNote explaining the change, if known
+}
+
+// UpdateNote is one "AGGIORNAMENTO (n)" block Normattiva appends to an article
+// after a "--------" separator.
+type UpdateNote struct {
+	Number   int         `json:"number"`
+	Heading  string      `json:"heading,omitempty"`
+	Text     string      `json:"text"`
+	Amending []Reference `json:"amending,omitempty"` // acts cited by the note, amending act first
+}
+
+// Note returns the update note with the given number, or nil.
+func (s *DocumentSection) Note(number int) *UpdateNote {
+	for i := range s.Updates {
+		if s.Updates[i].Number == number {
+			return &s.Updates[i]
+		}
+	}
+	return nil
+}
+
+// markdown renders the note as a blockquote, one paragraph per line.
+func (n UpdateNote) markdown() string {
+	var sb strings.Builder
+	lines := strings.Split(n.Text, "\n")
+	if n.Heading != "" {
+		lines = append([]string{n.Heading}, lines...)
+	}
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			sb.WriteString(fmt.Sprintf("> %s\n> \n", line))
+		}
+	}
+	return sb.String()
+}
+
+// spansIn returns the modified spans of the content block at index i, in order.
+func (s *DocumentSection) spansIn(i int) []ModifiedSpan {
+	var spans []ModifiedSpan
+	for _, span := range s.Modified {
+		if span.Content == i {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
@@ -0,0 +1,125 @@
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CompiledAct is an act of a compilation: the document, possibly reduced
+// with Subset, and the targets it was reduced to, if any.
+type CompiledAct struct {
+	Document *Document
+	Sections []string
+}
+
+var anchorBlockRe = regexp.MustCompile(`^<span id="([^"]*)"></span>$`)
+
+// Compile joins acts into one document, a "raccolta". Each act becomes a
+// top-level section of type "act" titled with the act, whose content is a
+// title page with its vigenza and source; its sections follow with the IDs
+// prefixed by the act's ("act2__art_5") so that they stay unique. A "Fonti"
+// appendix lists the acts with their URN and vigenza. Rendered with LinkApp
+// or LinkStandalone, references to any of the acts point to their sections
+// in the compilation.
+func Compile(title string, acts []CompiledAct) *Document {
+	d := &Document{Title: title, compiled: map[string]map[string]string{}}
+	for i, act := range acts {
+		doc := act.Document
+		id := fmt.Sprintf("act%d", i+1)
+		s := DocumentSection{ID: id, Type: "act", Title: actTitle(doc), Content: actTitlePage(doc)}
+		s.Children = prefixSections(doc.Sections, id+"__")
+		d.Sections = append(d.Sections, s)
+
+		// The first occurrence of an act is the target of its references
+		urn := ActURN(doc.URN)
+		if _, seen := d.compiled[urn]; urn == "" || seen {
+			continue
+		}
+		anchors := map[string]string{"": id}
+		for article, section := range doc.ArticleAnchors() {
+			anchors[article] = id + "__" + section
+		}
+		d.compiled[urn] = anchors
+	}
+	d.Sections = append(d.Sections, compilationSources(acts))
+	return d
+}
+
+func actTitle(doc *Document) string {
+	if doc.Title != "" {
+		return doc.Title
+	}
+	if doc.Name != "" {
+		return doc.Name
+	}
+	return doc.CodiceRedazionale
+}
+
+// actTitlePage is the content of the section opening an act.
+func actTitlePage(doc *Document) []string {
+	var lines []string
+	if doc.Vigenza != "" {
+		lines = append(lines, "Testo in vigore al: "+compilationDate(doc.Vigenza))
+	}
+	if doc.DataGU != "" {
+		lines = append(lines, "Gazzetta Ufficiale del "+compilationDate(doc.DataGU))
+	}
+	if doc.URN != "" {
+		lines = append(lines, doc.URN)
+	}
+	return lines
+}
+
+// prefixSections copies sections with prefix added to their IDs and to the
+// anchors standing alone in their content.
+func prefixSections(sections []DocumentSection, prefix string) []DocumentSection {
+	out := make([]DocumentSection, len(sections))
+	for i, s := range sections {
+		if s.ID != "" {
+			s.ID = prefix + s.ID
+		}
+		content := make([]string, len(s.Content))
+		for j, c := range s.Content {
+			if m := anchorBlockRe.FindStringSubmatch(c); m != nil {
+				c = `<span id="` + prefix + m[1] + `"></span>`
+			}
+			content[j] = c
+		}
+		s.Content = content
+		s.Children = prefixSections(s.Children, prefix)
+		out[i] = s
+	}
+	return out
+}
+
+// compilationSources is the appendix listing the sources of the acts.
+func compilationSources(acts []CompiledAct) DocumentSection {
+	cell := strings.NewReplacer("|", `\|`, "[", `\[`, "]", `\]`, "\n", " ")
+	rows := []string{"| Atto | URN | Testo vigente al | Parti incluse |", "| --- | --- | --- | --- |"}
+	for i, act := range acts {
+		doc := act.Document
+		parts := "Intero atto"
+		if len(act.Sections) > 0 {
+			parts = strings.Join(act.Sections, ", ")
+		}
+		vigenza := "-"
+		if doc.Vigenza != "" {
+			vigenza = compilationDate(doc.Vigenza)
+		}
+		rows = append(rows, fmt.Sprintf("| [%s](#act%d) | %s | %s | %s |",
+			cell.Replace(actTitle(doc)), i+1, cell.Replace(doc.URN), vigenza, cell.Replace(parts)))
+	}
+	return DocumentSection{ID: "sources", Type: "appendix", Title: "Fonti", Content: []string{strings.Join(rows, "\n")}}
+}
+
+// compilationDate turns YYYY-MM-DD and YYYYMMDD dates into DD-MM-YYYY.
+func compilationDate(date string) string {
+	if len(date) == 8 && !strings.Contains(date, "-") {
+		date = date[:4] + "-" + date[4:6] + "-" + date[6:]
+	}
+	if parts := strings.Split(date, "-"); len(parts) == 3 {
+		return parts[2] + "-" + parts[1] + "-" + parts[0]
+	}
+	return date
+}
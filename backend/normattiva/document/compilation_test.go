@@ -0,0 +1,50 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	law := selectorDocument()
+	law.Title, law.URN, law.Vigenza = "LEGGE 1 marzo 2020, n. 1", "urn:nir:stato:legge:2020-03-01;1", "2025-01-01"
+	law.Sections[1].Children[1].Content = []string{
+		"1\\. Si veda l'[articolo 3 del decreto](" + normattivaResolver + "urn:nir:stato:decreto.legislativo:2021-05-10;20~art3), " +
+			"l'[articolo 1](" + normattivaResolver + "urn:nir:stato:legge:2020-03-01;1~art1) e la [legge 241](" + normattivaResolver + "urn:nir:stato:legge:1990-08-07;241).",
+	}
+	decree := &Document{Title: "DECRETO LEGISLATIVO 10 maggio 2021, n. 20", URN: "urn:nir:stato:decreto.legislativo:2021-05-10;20", Vigenza: "2024-06-30", DataGU: "20210520",
+		Sections: []DocumentSection{
+			{ID: "art_3", Type: "articolo", Title: "Art. 3", Content: []string{`<span id="art_3__para_1"></span>`, "1\\. In attuazione della [legge](" + normattivaResolver + "urn:nir:stato:legge:2020-03-01;1)."}},
+		}}
+
+	doc := Compile("Raccolta appalti", []CompiledAct{{Document: law}, {Document: decree, Sections: []string{"art_3"}}})
+
+	if doc.Title != "Raccolta appalti" || len(doc.Sections) != 3 {
+		t.Fatalf("unexpected compilation: %q, %d sections", doc.Title, len(doc.Sections))
+	}
+	act := doc.Sections[1]
+	if act.ID != "act2" || act.Type != "act" || act.Title != decree.Title || act.Children[0].ID != "act2__art_3" || act.Children[0].Content[0] != `<span id="act2__art_3__para_1"></span>` {
+		t.Errorf("unexpected act section %+v", act)
+	}
+	if strings.Join(act.Content, "|") != "Testo in vigore al: 30-06-2024|Gazzetta Ufficiale del 20-05-2021|"+decree.URN {
+		t.Errorf("unexpected title page %q", act.Content)
+	}
+	if law.Sections[1].ID != "capo_I" || decree.Sections[0].Content[0] != `<span id="art_3__para_1"></span>` {
+		t.Error("acts modified")
+	}
+
+	md, _ := doc.ToMarkdownWithOptions(MarkdownOptions{Links: LinkStandalone})
+	for _, want := range []string{
+		"[articolo 3 del decreto](#act2__art_3)",
+		"[articolo 1](#act1__art_1)",
+		"[legge](#act1)",
+		"[legge 241](" + normattivaResolver + "urn:nir:stato:legge:1990-08-07;241)",
+		`<span id="sources"></span>`,
+		"| [LEGGE 1 marzo 2020, n. 1](#act1) | urn:nir:stato:legge:2020-03-01;1 | 01-01-2025 | Intero atto |",
+		"| [DECRETO LEGISLATIVO 10 maggio 2021, n. 20](#act2) | " + decree.URN + " | 30-06-2024 | art_3 |",
+	} {
+		if !strings.Contains(string(md), want) {
+			t.Errorf("missing %q in\n%s", want, md)
+		}
+	}
+}
@@ -10,9 +10,19 @@ type Document struct {
 	Name              string            `json:"name"`
 	Title             string            `json:"title"`
 	CodiceRedazionale string            `json:"codiceRedazionale"`
+	URN               string            `json:"urn,omitempty"`
 	DataGU            string            `json:"dataGU"`
 	Vigenza           string            `json:"vigenza"`
 	Sections          []DocumentSection `json:"sections"`
+	// Lifecycle and Modifications come from the AKN <meta>, when present
+	Lifecycle     []LifecycleEvent `json:"lifecycle,omitempty"`
+	Modifications []Modification   `json:"modifications,omitempty"`
+	// Integrity records the source the document was parsed from
+	Integrity *Integrity `json:"integrity,omitempty"`
+
+	// compiled maps the acts of a compilation, by act URN, to the section
+	// IDs of their articles; "" is the act itself
+	compiled map[string]map[string]string
 }
 
 func NewDocument(codiceRedazionale, name, dataPubblicazioneGazzetta, vigenza string) Document {
@@ -32,7 +42,32 @@ func (d *Document) ToJSON() ([]byte, error) {
 	return json.MarshalIndent(d, "", "  ")
 }
 
+// MarkdownOptions controls how a Document is rendered to Markdown.
+type MarkdownOptions struct {
+	// HideApparatus omits Normattiva's editorial apparatus: the (( )) markers
+	// around amended text and the AGGIORNAMENTO update notes.
+	HideApparatus bool
+	// Links selects where references point to; the zero value keeps the
+	// normattiva.it links stored in Content.
+	Links LinkMode
+	// Inactive collapses or omits repealed, suspended and not yet in force
+	// articles and commas; the zero value shows them as any other.
+	Inactive InactiveMode
+
+	rewriteLinks func(string) string
+}
+
 func (d *Document) ToMarkdown() ([]byte, error) {
+	return d.ToMarkdownWithOptions(MarkdownOptions{})
+}
+
+func (d *Document) ToMarkdownWithOptions(opts MarkdownOptions) ([]byte, error) {
+	return d.RenderedMarkdown(d.Render(opts)), nil
+}
+
+// RenderedMarkdown prints sections, as returned by Render and possibly
+// edited since, under the header of d.
+func (d *Document) RenderedMarkdown(sections []RenderedSection) []byte {
 	var sb strings.Builder
 
 	if d.Vigenza != "" {
@@ -49,12 +84,9 @@ func (d *Document) ToMarkdown() ([]byte, error) {
 		sb.WriteString(fmt.Sprintf("# %s\n\n", d.Title))
 	}
 
-	for _, section := range d.Sections {
-		// Level 1 logic implies main sections start at some level.
-		// If "preamble" is a section, handle it.
-		// "Body" usually contains children which are the top level structure.
-		section.WriteMarkdown(&sb, 1)
+	for i := range sections {
+		sections[i].writeMarkdown(&sb)
 	}
 
-	return []byte(sb.String()), nil
+	return []byte(sb.String())
 }
@@ -1,18 +1,22 @@
 package document
 
 import (
-	"fmt"
 	"regexp"
 	"strings"
 )
 
 type DocumentSection struct {
-	ID       string            `json:"id,omitempty"`
-	Type     string            `json:"type"`
-	Title    string            `json:"title"`
-	Children []DocumentSection `json:"children,omitempty"`
-	Content  []string          `json:"content,omitempty"`
-	Root     *Document         `json:"-"`
+	ID          string            `json:"id,omitempty"`
+	Type        string            `json:"type"`
+	Title       string            `json:"title"`
+	Children    []DocumentSection `json:"children,omitempty"`
+	Content     []string          `json:"content,omitempty"`
+	Modified    []ModifiedSpan    `json:"modified,omitempty"`
+	Updates     []UpdateNote      `json:"updates,omitempty"`
+	References  []Reference       `json:"references,omitempty"`
+	Status      *SectionStatus    `json:"status,omitempty"`
+	CommaStatus []CommaStatus     `json:"commaStatus,omitempty"`
+	Root        *Document         `json:"-"`
 }
 
 type Attachment struct {
@@ -36,55 +40,84 @@ func (s *DocumentSection) AddSection(doc DocumentSection) {
 	s.Children = append(s.Children, doc)
 }
 
-func (s *DocumentSection) WriteMarkdown(sb *strings.Builder, level int) {
-	// 1. Title/Header
-	if s.Title != "" {
-		if s.ID != "" {
-			sb.WriteString(fmt.Sprintf(`<span id="%s"></span>`, s.ID) + "\n\n")
-		}
+var (
+	newCommaRe   = regexp.MustCompile(`^[\(\s]*\d+[a-z-]*\\?\.[\s\)\)]+`)
+	noteMarkerRe = regexp.MustCompile(`\s*\(\(\d+\)\)`)
+)
 
-		// If level > 6, cap at 6
-		lvl := level
-		if lvl > 6 {
-			lvl = 6
-		}
-		prefix := strings.Repeat("#", lvl)
+func (s *DocumentSection) WriteMarkdown(sb *strings.Builder, level int, opts MarkdownOptions) {
+	if r, ok := s.render(level, opts); ok {
+		r.writeMarkdown(sb)
+	}
+}
+
+// renderContent renders one content block: the comma number in bold and the
+// amended spans either highlighted with their (( )) markers or, when the
+// apparatus is hidden, as plain text.
+func (s *DocumentSection) renderContent(i int, content string, opts MarkdownOptions) string {
+	var sb strings.Builder
 
-		sb.WriteString(fmt.Sprintf("%s %s\n\n", prefix, s.Title))
-	} else if s.Type == "preamble" {
-		// Preamble might not have a title but acts as a block
+	// The comma number may sit inside an opening (( marker; it is always
+	// rendered on its own so amended and original commas look the same.
+	skip := 0
+	if possibleNewComma := newCommaRe.FindString(content); possibleNewComma != "" {
+		skip = len(possibleNewComma)
+		sb.WriteString("**" + strings.Trim(possibleNewComma, "() ") + "** ")
 	}
 
-	// 2. Content
-	newCommaRe := regexp.MustCompile(`^[\(\s]*\d+[a-z-]*\\?\.[\s\)\)]+`)
-	possibleInsRe := regexp.MustCompile(`\(\(([^)]+|[^)]*\)\s[^)]*)\)\)`)
-	for _, content := range s.Content {
-		possibleNewComma := newCommaRe.FindString(content)
-		if len(possibleNewComma) > 0 {
-			// remove possibleNewComma from content
-			content = content[len(possibleNewComma):]
-			// add possibleNewComma to sb
-			sb.WriteString("**" + strings.TrimSpace(possibleNewComma) + "** ")
+	pos := skip
+	for _, span := range s.spansIn(i) {
+		if span.End <= skip {
+			continue
+		}
+		start, end := span.Start, span.End
+		opened := strings.HasSuffix(content[:start], "((") || start < skip
+		closed := strings.HasPrefix(content[end:], "))")
+		if start < skip {
+			start = skip
+		}
+		if opened && start >= 2 && content[start-2:start] == "((" && start-2 >= pos {
+			sb.WriteString(content[pos : start-2])
+		} else {
+			sb.WriteString(content[pos:start])
 		}
 
-		content = possibleInsRe.ReplaceAllString(content, "**(($1))**")
+		text := content[start:end]
+		if opts.HideApparatus {
+			sb.WriteString(text)
+		} else if trimmed := strings.TrimSpace(text); trimmed != "" {
+			open, close := "", ""
+			if opened {
+				open = "(("
+			}
+			if closed {
+				close = "))"
+			}
+			// Keep surrounding whitespace outside the emphasis or Markdown won't bold it.
+			lead := text[:strings.Index(text, trimmed)]
+			trail := text[len(lead)+len(trimmed):]
+			sb.WriteString(lead + "**" + open + trimmed + close + "**" + trail)
+		}
 
-		sb.WriteString(content + "\n\n")
+		pos = end
+		if closed {
+			pos += 2
+		}
 	}
+	sb.WriteString(content[pos:])
 
-	// 3. Children
-	// Calculate next level
-	nextLevel := level + 1
-	// Some container types might stay at same level?
-	// Usually hierarchy implies deeper level.
-	// Exception: "body" -> "libri" -> "titles".
-	// If current section is just a wrapper (like "body"), maybe don't increase?
-	// But our DocumentSection usually represents a structural node.
-	if s.Type == "body" {
-		nextLevel = level // Body doesn't add a header itself usually
+	text := sb.String()
+	if opts.HideApparatus {
+		text = noteMarkerRe.ReplaceAllString(text, "")
 	}
+	return text
+}
 
-	for _, child := range s.Children {
-		child.WriteMarkdown(sb, nextLevel)
+// collapsedComma renders an inactive comma as its number followed by the
+// status placeholder.
+func collapsedComma(content string, st *SectionStatus) string {
+	if num := newCommaRe.FindString(content); num != "" {
+		return "**" + strings.Trim(num, "() ") + "** " + st.collapsed()
 	}
+	return st.collapsed()
 }
@@ -0,0 +1,59 @@
+package document
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Integrity records where the text of a Document comes from, so that the
+// text relied on can be proven later: the hash of its canonical text, the
+// URL and time it was fetched and the hash of the XML as received, which is
+// archived under that hash.
+type Integrity struct {
+	ContentHash string `json:"contentHash"` // HashBytes of CanonicalText
+	SourceURL   string `json:"sourceUrl"`
+	FetchedAt   string `json:"fetchedAt"` // RFC 3339, UTC
+	RawHash     string `json:"rawHash"`   // HashBytes of the XML
+}
+
+// HashBytes is the hash used for integrity records: "sha256:" and the hex
+// digest of data.
+func HashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// CanonicalText is the text of d the content hash is computed on: the
+// identification of the act, then each section in document order with its
+// type, ID, title and content. Line endings and trailing blanks are
+// normalized; the name found by search and the Integrity record itself are
+// left out, so the same XML always gives the same text.
+func (d *Document) CanonicalText() []byte {
+	var sb strings.Builder
+	line := func(s string) {
+		for _, l := range strings.Split(strings.TrimRight(strings.ReplaceAll(s, "\r\n", "\n"), " \t\n"), "\n") {
+			sb.WriteString(strings.TrimRight(l, " \t\r"))
+			sb.WriteByte('\n')
+		}
+	}
+	line("urn: " + d.URN)
+	line("codiceRedazionale: " + d.CodiceRedazionale)
+	line("dataGU: " + d.DataGU)
+	line("vigenza: " + d.Vigenza)
+	line("title: " + d.Title)
+	d.Walk(func(_ []*DocumentSection, s *DocumentSection) error {
+		sb.WriteByte('\n')
+		line("[" + s.Type + " " + s.ID + "] " + s.Title)
+		for _, c := range s.Content {
+			line(c)
+		}
+		return nil
+	})
+	return []byte(sb.String())
+}
+
+// ContentHash is the hash of the canonical text of d.
+func (d *Document) ContentHash() string {
+	return HashBytes(d.CanonicalText())
+}
@@ -0,0 +1,47 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContentHash(t *testing.T) {
+	doc := selectorDocument()
+	doc.URN, doc.Vigenza = "urn:nir:stato:decreto.legislativo:2023-03-31;36", "2025-01-01"
+	hash := doc.ContentHash()
+	if !strings.HasPrefix(hash, "sha256:") || len(hash) != len("sha256:")+64 {
+		t.Fatalf("hash = %q", hash)
+	}
+
+	text := string(doc.CanonicalText())
+	for _, want := range []string{
+		"urn: urn:nir:stato:decreto.legislativo:2023-03-31;36\n",
+		"vigenza: 2025-01-01\n",
+		"\n[articolo art_1] Art. 1 - Oggetto\n1\\. Il presente decreto disciplina i contratti.\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("canonical text lacks %q:\n%s", want, text)
+		}
+	}
+
+	// Neither the name nor the integrity record count, line endings neither
+	same := selectorDocument()
+	same.URN, same.Vigenza, same.Name = doc.URN, doc.Vigenza, "DECRETO LEGISLATIVO 31 marzo 2023, n. 36"
+	same.Integrity = &Integrity{ContentHash: hash, FetchedAt: "2025-01-01T10:00:00Z"}
+	same.Sections[1].Children[0].Content[0] += " \r\n"
+	if got := same.ContentHash(); got != hash {
+		t.Errorf("hash changed to %s", got)
+	}
+
+	// Any change to the text does
+	same.Sections[1].Children[0].Content[0] = "1\\. Il presente decreto disciplina gli appalti."
+	if same.ContentHash() == hash {
+		t.Error("hash unchanged after editing a comma")
+	}
+}
+
+func TestHashBytes(t *testing.T) {
+	if got := HashBytes([]byte("abc")); got != "sha256:ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("HashBytes = %s", got)
+	}
+}
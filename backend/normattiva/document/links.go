@@ -0,0 +1,227 @@
+package document
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// LinkMode selects where the references in rendered output point to.
+type LinkMode string
+
+const (
+	// LinkNormattiva points every reference to normattiva.it. This is the
+	// default, and what the parsers store in Content.
+	LinkNormattiva LinkMode = "normattiva"
+	// LinkApp keeps readers inside Norma+: references to the same act become
+	// in-document anchors, references to other acts become /?urn=... routes.
+	LinkApp LinkMode = "app"
+	// LinkStandalone is meant for exported files: references to the same act,
+	// or to the acts of a compilation, become anchors, other acts still point
+	// to normattiva.it.
+	LinkStandalone LinkMode = "standalone"
+	// LinkNone renders references as plain text.
+	LinkNone LinkMode = "none"
+)
+
+var (
+	markdownLinkRe = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]+)\)`)
+	urnFragmentRe  = regexp.MustCompile(`~art([0-9]+[a-z]*)(?:-com([0-9]+[a-z]*))?`)
+	eidArticleRe   = regexp.MustCompile(`(?:^|__)art_([0-9]+[a-z-]*)(?:__para_([0-9]+[a-z-]*))?`)
+)
+
+// ParseLinkMode returns the LinkMode named by s, or def when s is empty or unknown.
+func ParseLinkMode(s string, def LinkMode) LinkMode {
+	switch mode := LinkMode(s); mode {
+	case LinkNormattiva, LinkApp, LinkStandalone, LinkNone:
+		return mode
+	}
+	return def
+}
+
+// SplitURN separates a urn:nir into the act URN and its ~artN-comM fragment.
+func SplitURN(urn string) (act, article, comma string) {
+	if m := urnFragmentRe.FindStringSubmatch(urn); m != nil {
+		article, comma = m[1], m[2]
+	}
+	if i := strings.IndexAny(urn, "~!@$#"); i >= 0 {
+		urn = urn[:i]
+	}
+	return urn, article, comma
+}
+
+// EIdTarget extracts the article and comma numbers from an eId such as
+// "art_3bis__para_2".
+func EIdTarget(eid string) (article, comma string) {
+	m := eidArticleRe.FindStringSubmatch(strings.ToLower(eid))
+	if m == nil {
+		return "", ""
+	}
+	return strings.ReplaceAll(m[1], "-", ""), strings.ReplaceAll(m[2], "-", "")
+}
+
+// ReferenceFromURL rebuilds a Reference from a link produced by Reference.URL.
+// Only Normattiva resolver links can be recognized; ok is false otherwise.
+func ReferenceFromURL(text, link string) (Reference, bool) {
+	if !strings.HasPrefix(link, normattivaResolver) {
+		return Reference{}, false
+	}
+	ref := Reference{Text: text, Href: link, Kind: RefItalianAct}
+	ref.URN, ref.Article, ref.Comma = SplitURN(strings.TrimPrefix(link, normattivaResolver))
+	return ref, true
+}
+
+// ArticleAnchors maps normalized article numbers ("3", "12bis") to the ID of
+// the section holding that article.
+func (d *Document) ArticleAnchors() map[string]string {
+	anchors := make(map[string]string)
+	d.Walk(func(_ []*DocumentSection, s *DocumentSection) error {
+		if s.ID != "" && s.IsArticle() {
+			if article, _ := EIdTarget(s.ID); article != "" {
+				if _, seen := anchors[article]; !seen {
+					anchors[article] = s.ID
+				}
+			}
+		}
+		return nil
+	})
+	return anchors
+}
+
+// CommaAnchor is the anchor of comma num ("2", "2-bis") of the section
+// with the given ID, following the AKN eId convention: "art_5__para_2".
+// Rendered output places it before the comma.
+func CommaAnchor(sectionID, num string) string {
+	if sectionID == "" || num == "" {
+		return ""
+	}
+	return sectionID + "__para_" + strings.ReplaceAll(num, " ", "-")
+}
+
+// NormalizeNum turns article and comma numbers as written ("2-bis",
+// "2 bis") into the form of Reference.Article and Reference.Comma ("2bis").
+func NormalizeNum(num string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(num)))
+}
+
+// commaAnchors maps the ID of each article to the anchors of its numbered
+// commas, by normalized number. Commas left out of the output are skipped.
+func (d *Document) commaAnchors(opts MarkdownOptions) map[string]map[string]string {
+	commas := make(map[string]map[string]string)
+	d.Walk(func(_ []*DocumentSection, s *DocumentSection) error {
+		if s.ID == "" || !s.IsArticle() {
+			return nil
+		}
+		if !s.IsActive() && opts.Inactive == InactiveOmit {
+			return SkipSection
+		}
+		for i, content := range s.Content {
+			if s.contentStatus(i) != nil && opts.Inactive == InactiveOmit {
+				continue
+			}
+			num := strings.TrimSuffix(strings.TrimSuffix(strings.Trim(newCommaRe.FindString(content), "() "), "."), `\`)
+			if num == "" {
+				continue
+			}
+			if commas[s.ID] == nil {
+				commas[s.ID] = make(map[string]string)
+			}
+			commas[s.ID][NormalizeNum(num)] = CommaAnchor(s.ID, num)
+		}
+		return nil
+	})
+	return commas
+}
+
+// referencesByURL indexes the structured references of d by the link the
+// parsers render them as, so that links in Content resolve to them.
+func (d *Document) referencesByURL() map[string]Reference {
+	refs := make(map[string]Reference)
+	add := func(list []Reference) {
+		for _, ref := range list {
+			if url := ref.URL(); url != "" {
+				if _, seen := refs[url]; !seen {
+					refs[url] = ref
+				}
+			}
+		}
+	}
+	d.Walk(func(_ []*DocumentSection, s *DocumentSection) error {
+		add(s.References)
+		for _, note := range s.Updates {
+			add(note.Amending)
+		}
+		return nil
+	})
+	return refs
+}
+
+// linkRewriter returns a function rewriting the Markdown links of a content
+// block according to opts.Links, or nil when links are kept as stored. Each
+// link is resolved through the Reference it was rendered from; links to
+// normattiva.it that match none are still understood.
+func (d *Document) linkRewriter(opts MarkdownOptions) func(string) string {
+	mode := opts.Links
+	if mode == "" || mode == LinkNormattiva {
+		return nil
+	}
+	if mode == LinkNone {
+		return func(s string) string {
+			return markdownLinkRe.ReplaceAllString(s, "$1")
+		}
+	}
+
+	self := ActURN(d.URN)
+	anchors := d.ArticleAnchors()
+	commas := d.commaAnchors(opts)
+	refs := d.referencesByURL()
+
+	// anchor is the comma of the article section id when it is rendered,
+	// otherwise the article itself.
+	anchor := func(id, comma string) string {
+		if a, ok := commas[id][NormalizeNum(comma)]; ok && comma != "" {
+			return a
+		}
+		return id
+	}
+
+	return func(s string) string {
+		return markdownLinkRe.ReplaceAllStringFunc(s, func(link string) string {
+			m := markdownLinkRe.FindStringSubmatch(link)
+			ref, ok := refs[m[2]]
+			if !ok {
+				if ref, ok = ReferenceFromURL(m[1], m[2]); !ok {
+					return link
+				}
+			}
+			text := m[1]
+			if compiled, ok := d.compiled[ActURN(ref.URN)]; ok && ref.URN != "" {
+				if id, ok := compiled[ref.Article]; ok {
+					return "[" + text + "](#" + anchor(id, ref.Comma) + ")"
+				}
+			}
+			// References within the act may carry no URN, only an eId
+			sameAct := (self != "" && ActURN(ref.URN) == self) || (ref.URN == "" && ref.Kind == RefSameAct && d.compiled == nil)
+			if sameAct {
+				if id, ok := anchors[ref.Article]; ok {
+					return "[" + text + "](#" + anchor(id, ref.Comma) + ")"
+				}
+				if ref.Article == "" {
+					return "[" + text + "](#preamble)"
+				}
+			}
+			if mode == LinkApp && ref.URN != "" {
+				return "[" + text + "](/?urn=" + url.QueryEscape(ref.TargetURN()) + ")"
+			}
+			return link
+		})
+	}
+}
+
+// links applies the link rewriting selected by the options to rendered Markdown.
+func (o MarkdownOptions) links(s string) string {
+	if o.rewriteLinks == nil {
+		return s
+	}
+	return o.rewriteLinks(s)
+}
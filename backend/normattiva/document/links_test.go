@@ -0,0 +1,46 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLinkRewriter(t *testing.T) {
+	doc := &Document{URN: "urn:nir:stato:legge:2020-03-01;1", Sections: []DocumentSection{
+		{ID: "art_3", Type: "articolo", Title: "Art. 3", Content: []string{"1\\. Primo comma.", "2-bis\\. Secondo comma."}},
+		{ID: "art_4", Type: "articolo", Title: "Art. 4",
+			Content: []string{
+				"1\\. Si vedano l'[articolo 3, comma 2-bis](" + normattivaResolver + "urn:nir:stato:legge:2020-03-01;1~art3-com2bis), " +
+					"l'[articolo 3, comma 5](" + normattivaResolver + "urn:nir:stato:legge:2020-03-01;1~art3-com5), " +
+					"il [comma 1 dell'articolo 3](#art_3__para_1) e la [legge 241](" + normattivaResolver + "urn:nir:stato:legge:1990-08-07;241).",
+			},
+			References: []Reference{
+				{Text: "comma 1 dell'articolo 3", Href: "#art_3__para_1", Kind: RefSameAct, Article: "3", Comma: "1"},
+			}},
+	}}
+
+	for mode, wants := range map[LinkMode][]string{
+		LinkStandalone: {
+			`<span id="art_3__para_2-bis"></span>**2-bis\.**`,
+			"[articolo 3, comma 2-bis](#art_3__para_2-bis)",
+			"[articolo 3, comma 5](#art_3)",
+			"[comma 1 dell'articolo 3](#art_3__para_1)",
+			"[legge 241](" + normattivaResolver + "urn:nir:stato:legge:1990-08-07;241)",
+		},
+		LinkApp: {
+			"[articolo 3, comma 2-bis](#art_3__para_2-bis)",
+			"[legge 241](/?urn=urn%3Anir%3Astato%3Alegge%3A1990-08-07%3B241)",
+		},
+		LinkNone: {"il comma 1 dell'articolo 3 e la legge 241."},
+	} {
+		md, err := doc.ToMarkdownWithOptions(MarkdownOptions{Links: mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range wants {
+			if !strings.Contains(string(md), want) {
+				t.Errorf("%s: missing %q in\n%s", mode, want, md)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package document
+
+import "strings"
+
+// Directions of a Modification
+const (
+	ModPassive = "passive" // the act is amended by Source
+	ModActive  = "active"  // the act amends TargetURN
+)
+
+// LifecycleEvent is an <eventRef> of the AKN <lifecycle>: the original
+// publication of the act and each amendment, with the act causing it.
+type LifecycleEvent struct {
+	ID     string    `json:"id"`
+	Date   string    `json:"date"`
+	Type   string    `json:"type"` // generation, amendment, repeal, ...
+	Source Reference `json:"source"`
+}
+
+// Modification is one <textualMod> of the AKN <analysis>, as recorded by the
+// publisher rather than inferred from the text.
+type Modification struct {
+	ID        string    `json:"id,omitempty"`
+	Direction string    `json:"direction"`
+	Type      string    `json:"type"`   // substitution, insertion, repeal, ...
+	Source    Reference `json:"source"` // the amending act, with the amending provision when known
+	Target    string    `json:"target"` // eId of the amended provision
+	TargetURN string    `json:"targetUrn,omitempty"`
+	Date      string    `json:"date,omitempty"` // date the modification takes effect
+	Old       string    `json:"old,omitempty"`
+	New       string    `json:"new,omitempty"`
+}
+
+// ModificationsOf returns the passive modifications of the section with the
+// given eId, including those of its commas and points ("art_5__para_2").
+func (d *Document) ModificationsOf(id string) []Modification {
+	var mods []Modification
+	for _, m := range d.Modifications {
+		if m.Direction == ModPassive && (m.Target == id || strings.HasPrefix(m.Target, id+"__")) {
+			mods = append(mods, m)
+		}
+	}
+	return mods
+}
@@ -0,0 +1,26 @@
+package document
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	plainLinkRe     = regexp.MustCompile(`\[([^\]]*)\]\([^)\s]*\)`)
+	plainEscapeRe   = regexp.MustCompile(`\\([\\.\-*_#|>\[\]()])`)
+	plainSpanRe     = regexp.MustCompile(`<span id="[^"]*"></span>`)
+	plainQuoteRe    = regexp.MustCompile(`(?m)^(?:>\s?)+`)
+	plainEmphasisRe = regexp.MustCompile(`\*\*`)
+)
+
+// PlainText strips the Markdown the parsers store in Content (links, anchors,
+// bold markers, blockquotes and backslash escapes such as "3\."), leaving the
+// text as a reader would see it. Amendment markers (( )) are kept.
+func PlainText(s string) string {
+	s = plainSpanRe.ReplaceAllString(s, "")
+	s = plainLinkRe.ReplaceAllString(s, "$1")
+	s = plainEmphasisRe.ReplaceAllString(s, "")
+	s = plainQuoteRe.ReplaceAllString(s, "")
+	s = plainEscapeRe.ReplaceAllString(s, "$1")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,79 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReferenceKind classifies the target of a Reference.
+type ReferenceKind string
+
+const (
+	RefSameAct    ReferenceKind = "same-act"
+	RefItalianAct ReferenceKind = "italian-act"
+	RefEUAct      ReferenceKind = "eu-act"
+	RefUnknown    ReferenceKind = "unknown"
+)
+
+const normattivaResolver = "https://www.normattiva.it/uri-res/N2Ls?"
+
+// Reference is a citation of another act (or of another part of the same act)
+// as found in a <ref>/<rif> element or in an update note.
+type Reference struct {
+	Text    string        `json:"text"`
+	Href    string        `json:"href,omitempty"` // raw href from the XML
+	URN     string        `json:"urn,omitempty"`  // urn:nir of the target act, without fragment
+	ELI     string        `json:"eli,omitempty"`  // ELI of the target act, for EU acts
+	Kind    ReferenceKind `json:"kind"`
+	Article string        `json:"article,omitempty"`
+	Comma   string        `json:"comma,omitempty"`
+}
+
+// TargetURN returns the URN of the target including the ~artN-comM fragment.
+func (r Reference) TargetURN() string {
+	if r.URN == "" {
+		return ""
+	}
+	urn := r.URN
+	if r.Article != "" {
+		urn += "~art" + r.Article
+		if r.Comma != "" {
+			urn += "-com" + r.Comma
+		}
+	}
+	return urn
+}
+
+// URL returns the public URL of the target: the Normattiva resolver for
+// Italian acts, the ELI for EU acts, otherwise the raw href.
+func (r Reference) URL() string {
+	switch {
+	case r.URN != "":
+		return normattivaResolver + r.TargetURN()
+	case r.ELI != "":
+		return r.ELI
+	case r.Kind == RefEUAct:
+		return ""
+	case strings.HasPrefix(r.Href, "/act/"):
+		return "https://www.normattiva.it" + r.Href
+	}
+	return r.Href
+}
+
+// Markdown renders the reference as a Markdown link, or as plain text when
+// there is nothing to link to.
+func (r Reference) Markdown() string {
+	if url := r.URL(); url != "" {
+		return fmt.Sprintf("[%s](%s)", r.Text, url)
+	}
+	return r.Text
+}
+
+// ActURN strips fragments and version suffixes (~art, !vig=, @originale, $...)
+// from a urn:nir so URNs of the same act compare equal.
+func ActURN(urn string) string {
+	if i := strings.IndexAny(urn, "~!@$#"); i >= 0 {
+		urn = urn[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(urn))
+}
@@ -0,0 +1,110 @@
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RenderedSection is a section as WriteMarkdown prints it, with the
+// MarkdownOptions already applied: inactive parts collapsed or dropped,
+// comma numbers and amended text in **bold**, (( )) markers kept or stripped,
+// links rewritten. Renderers for other formats walk this tree rather than
+// re-implementing the options. Content blocks are still Markdown, limited to
+// what the parsers produce: paragraphs, "> " quotes, "- " bullets, pipe
+// tables, links, bold and backslash escapes.
+type RenderedSection struct {
+	ID       string            `json:"id,omitempty"`
+	Type     string            `json:"type"`
+	Title    string            `json:"title,omitempty"`
+	Level    int               `json:"level"` // heading level, not capped at 6
+	Status   *SectionStatus    `json:"status,omitempty"`
+	Content  []string          `json:"content,omitempty"`
+	Notes    []UpdateNote      `json:"notes,omitempty"`
+	Children []RenderedSection `json:"children,omitempty"`
+}
+
+// renderedCommaRe matches the number renderContent puts in bold at the
+// start of a comma.
+var renderedCommaRe = regexp.MustCompile(`^\*\*\(*(\d+(?:[\s-]?[a-z]+)?)\\?\.\)*\*\*`)
+
+// Render prepares the sections of d for output with opts.
+func (d *Document) Render(opts MarkdownOptions) []RenderedSection {
+	opts.rewriteLinks = d.linkRewriter(opts)
+	var sections []RenderedSection
+	for i := range d.Sections {
+		if r, ok := d.Sections[i].render(1, opts); ok {
+			sections = append(sections, r)
+		}
+	}
+	return sections
+}
+
+// render returns s prepared for output at the given heading level; ok is
+// false when s is omitted.
+func (s *DocumentSection) render(level int, opts MarkdownOptions) (r RenderedSection, ok bool) {
+	if !s.IsActive() && opts.Inactive == InactiveOmit {
+		return r, false
+	}
+	r = RenderedSection{ID: s.ID, Type: s.Type, Title: s.Title, Level: level, Status: s.Status}
+
+	if !s.IsActive() && opts.Inactive == InactiveCollapse {
+		r.Content = []string{opts.links(s.Status.collapsed())}
+		return r, true
+	}
+
+	for i, content := range s.Content {
+		if st := s.contentStatus(i); st != nil {
+			switch opts.Inactive {
+			case InactiveOmit:
+				continue
+			case InactiveCollapse:
+				r.Content = append(r.Content, opts.links(collapsedComma(content, st)))
+				continue
+			}
+		}
+		r.Content = append(r.Content, opts.links(s.renderContent(i, content, opts)))
+	}
+
+	if !opts.HideApparatus {
+		for _, note := range s.Updates {
+			note.Text = opts.links(note.Text)
+			r.Notes = append(r.Notes, note)
+		}
+	}
+
+	// The body wrapper adds no heading of its own
+	nextLevel := level + 1
+	if s.Type == "body" {
+		nextLevel = level
+	}
+	for i := range s.Children {
+		if child, ok := s.Children[i].render(nextLevel, opts); ok {
+			r.Children = append(r.Children, child)
+		}
+	}
+	return r, true
+}
+
+// writeMarkdown prints r and its children.
+func (r *RenderedSection) writeMarkdown(sb *strings.Builder) {
+	if r.Title != "" {
+		if r.ID != "" {
+			sb.WriteString(fmt.Sprintf(`<span id="%s"></span>`, r.ID) + "\n\n")
+		}
+		sb.WriteString(fmt.Sprintf("%s %s\n\n", strings.Repeat("#", min(r.Level, 6)), r.Title))
+	}
+	for _, content := range r.Content {
+		// Anchor numbered commas as the other formats do, for links to them
+		if m := renderedCommaRe.FindStringSubmatch(content); m != nil && r.ID != "" {
+			content = fmt.Sprintf(`<span id="%s"></span>`, CommaAnchor(r.ID, m[1])) + content
+		}
+		sb.WriteString(content + "\n\n")
+	}
+	for _, note := range r.Notes {
+		sb.WriteString(note.markdown() + "\n\n")
+	}
+	for i := range r.Children {
+		r.Children[i].writeMarkdown(sb)
+	}
+}
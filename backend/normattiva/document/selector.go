@@ -0,0 +1,279 @@
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Selector matches sections with a small CSS-like language:
+//
+//	article                       sections of a type ("*" for any)
+//	article[title~="sanzion"]     title matching a case-insensitive regexp
+//	*[id^="art_1"]                ID prefix (= exact, *= substring)
+//	chapter[depth<=2]             depth, 1 for top-level sections (= < <= > >=)
+//	article[content~="euro"]      text of the section's own content
+//	article[status="repealed"]    status (in-force, repealed, suspended, not-in-force)
+//	capo[title*="II"] article     articles inside a matching section
+//	article, allegato             either selector
+//
+// Types match across the AKN and NIR vocabularies, so "article" also matches
+// "articolo" and "chapter" matches "capo".
+type Selector struct {
+	alternatives [][]compound
+}
+
+type compound struct {
+	typ     string
+	filters []filter
+}
+
+type filter struct {
+	attr, op, value string
+	re              *regexp.Regexp
+	n               int
+}
+
+// Match is a section selected by a query, with the titles of its ancestors.
+type Match struct {
+	Section *DocumentSection `json:"section"`
+	Path    []string         `json:"path"`
+	Depth   int              `json:"depth"`
+}
+
+var typeAliases = map[string]string{
+	"articolo":  "article",
+	"capo":      "chapter",
+	"parte":     "part",
+	"titolo":    "title",
+	"sezione":   "section",
+	"libro":     "book",
+	"allegato":  "attachment",
+	"allegati":  "attachments",
+	"preambolo": "preamble",
+}
+
+var selectorFilterRe = regexp.MustCompile(`^\[\s*([a-z]+)\s*(\^=|\*=|~=|!=|<=|>=|=|<|>)\s*(?:"((?:[^"\\]|\\.)*)"|'((?:[^'\\]|\\.)*)'|([^\]\s]*))\s*\]`)
+
+// ParseSelector compiles a selector query.
+func ParseSelector(query string) (*Selector, error) {
+	sel := &Selector{}
+	for _, alt := range splitOutside(query, ',') {
+		var chain []compound
+		for _, part := range splitOutside(alt, ' ') {
+			c, err := parseCompound(part)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, c)
+		}
+		if len(chain) == 0 {
+			return nil, fmt.Errorf("empty selector in %q", query)
+		}
+		sel.alternatives = append(sel.alternatives, chain)
+	}
+	if len(sel.alternatives) == 0 {
+		return nil, fmt.Errorf("empty selector")
+	}
+	return sel, nil
+}
+
+func parseCompound(s string) (compound, error) {
+	c := compound{}
+	i := strings.IndexByte(s, '[')
+	if i < 0 {
+		i = len(s)
+	}
+	c.typ = canonicalType(s[:i])
+	if c.typ == "" {
+		c.typ = "*"
+	}
+
+	for rest := s[i:]; rest != ""; {
+		m := selectorFilterRe.FindStringSubmatch(rest)
+		if m == nil {
+			return c, fmt.Errorf("invalid filter %q", rest)
+		}
+		f := filter{attr: m[1], op: m[2], value: strings.NewReplacer(`\"`, `"`, `\'`, `'`, `\\`, `\`).Replace(m[3] + m[4] + m[5])}
+		if err := f.compile(); err != nil {
+			return c, err
+		}
+		c.filters = append(c.filters, f)
+		rest = rest[len(m[0]):]
+	}
+	return c, nil
+}
+
+func (f *filter) compile() error {
+	switch f.attr {
+	case "depth":
+		n, err := strconv.Atoi(f.value)
+		if err != nil {
+			return fmt.Errorf("depth must be a number, got %q", f.value)
+		}
+		f.n = n
+		return nil
+	case "type":
+		f.value = canonicalType(f.value)
+	case "id", "title", "content", "status":
+	default:
+		return fmt.Errorf("unknown attribute %q", f.attr)
+	}
+
+	switch f.op {
+	case "~=":
+		re, err := regexp.Compile("(?i)" + f.value)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", f.value, err)
+		}
+		f.re = re
+	case "<", "<=", ">", ">=":
+		return fmt.Errorf("%s does not support %s", f.attr, f.op)
+	}
+	return nil
+}
+
+// splitOutside splits s at sep, ignoring separators inside [ ] and quotes.
+func splitOutside(s string, sep byte) []string {
+	var parts []string
+	depth, quote, start := 0, byte(0), 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == sep && depth == 0:
+			if part := strings.TrimSpace(s[start:i]); part != "" {
+				parts = append(parts, part)
+			}
+			start = i + 1
+		}
+	}
+	if part := strings.TrimSpace(s[start:]); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+func canonicalType(t string) string {
+	t = strings.ToLower(strings.TrimSpace(t))
+	if alias, ok := typeAliases[t]; ok {
+		return alias
+	}
+	return t
+}
+
+// Select returns the sections of d matching sel, in document order.
+func (d *Document) Select(sel *Selector) []Match {
+	matches := []Match{}
+	d.Walk(func(path []*DocumentSection, s *DocumentSection) error {
+		if sel.matches(path, s) {
+			titles := make([]string, 0, len(path))
+			for _, p := range path {
+				if p.Title != "" {
+					titles = append(titles, p.Title)
+				}
+			}
+			matches = append(matches, Match{Section: s, Path: titles, Depth: len(path) + 1})
+		}
+		return nil
+	})
+	return matches
+}
+
+func (sel *Selector) matches(path []*DocumentSection, s *DocumentSection) bool {
+	for _, chain := range sel.alternatives {
+		last := len(chain) - 1
+		if !chain[last].matches(s, len(path)+1) {
+			continue
+		}
+		// The other compounds must match ancestors, outermost first
+		j := last - 1
+		for i := len(path) - 1; i >= 0 && j >= 0; i-- {
+			if chain[j].matches(path[i], i+1) {
+				j--
+			}
+		}
+		if j < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (c compound) matches(s *DocumentSection, depth int) bool {
+	if c.typ != "*" && canonicalType(s.Type) != c.typ {
+		return false
+	}
+	for _, f := range c.filters {
+		if !f.matches(s, depth) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f filter) matches(s *DocumentSection, depth int) bool {
+	if f.attr == "depth" {
+		switch f.op {
+		case "=":
+			return depth == f.n
+		case "!=":
+			return depth != f.n
+		case "<":
+			return depth < f.n
+		case "<=":
+			return depth <= f.n
+		case ">":
+			return depth > f.n
+		case ">=":
+			return depth >= f.n
+		}
+		return false
+	}
+
+	var value string
+	switch f.attr {
+	case "id":
+		value = s.ID
+	case "title":
+		value = s.Title
+	case "type":
+		value = canonicalType(s.Type)
+	case "status":
+		value = "in-force"
+		if !s.IsActive() {
+			value = string(s.Status.Status)
+		}
+	case "content":
+		texts := make([]string, len(s.Content))
+		for i, c := range s.Content {
+			texts[i] = PlainText(c)
+		}
+		value = strings.Join(texts, "\n")
+	}
+
+	switch f.op {
+	case "=":
+		return strings.EqualFold(value, f.value)
+	case "!=":
+		return !strings.EqualFold(value, f.value)
+	case "^=":
+		return strings.HasPrefix(strings.ToLower(value), strings.ToLower(f.value))
+	case "*=":
+		return strings.Contains(strings.ToLower(value), strings.ToLower(f.value))
+	case "~=":
+		return f.re.MatchString(value)
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package document
+
+import (
+	"errors"
+	"testing"
+)
+
+func selectorDocument() *Document {
+	return &Document{Sections: []DocumentSection{
+		{Type: "preamble", Content: []string{"IL PRESIDENTE DELLA REPUBBLICA"}},
+		{ID: "capo_I", Type: "capo", Title: "Capo I - Disposizioni generali", Children: []DocumentSection{
+			{ID: "art_1", Type: "articolo", Title: "Art. 1 - Oggetto", Content: []string{"1\\. Il presente decreto disciplina i contratti."}},
+			{ID: "art_2", Type: "articolo", Title: "Art. 2 - Sanzioni amministrative", Content: []string{"1\\. Si applica la sanzione da 500 a 5.000 euro."}},
+		}},
+		{ID: "capo_II", Type: "capo", Title: "Capo II - Vigilanza", Children: []DocumentSection{
+			{ID: "art_10", Type: "articolo", Title: "Art. 10 - Sanzioni penali", Status: &SectionStatus{Status: StatusRepealed}},
+			{ID: "art_11", Type: "articolo", Title: "Art. 11 - Controlli", Content: []string{"1\\. I controlli sono svolti dall'ANAC."}},
+		}},
+	}}
+}
+
+func TestSelect(t *testing.T) {
+	doc := selectorDocument()
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`article[title~="sanzion"]`, []string{"art_2", "art_10"}},
+		{`articolo[id^="art_1"]`, []string{"art_1", "art_10", "art_11"}},
+		{`*[depth=1][id^="capo"]`, []string{"capo_I", "capo_II"}},
+		{`chapter[title*="vigilanza"] article`, []string{"art_10", "art_11"}},
+		{`article[content~="\d+ euro"], article[status="repealed"]`, []string{"art_2", "art_10"}},
+		{`article[status="in-force"][depth>1][title!="Art. 1 - Oggetto"]`, []string{"art_2", "art_11"}},
+	}
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.query)
+		if err != nil {
+			t.Errorf("%s: %v", tt.query, err)
+			continue
+		}
+		var got []string
+		for _, m := range doc.Select(sel) {
+			got = append(got, m.Section.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s = %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+
+	matches := doc.Select(mustSelector(t, `article[id="art_11"]`))
+	if len(matches) != 1 || matches[0].Depth != 2 || len(matches[0].Path) != 1 || matches[0].Path[0] != "Capo II - Vigilanza" {
+		t.Errorf("unexpected match %+v", matches)
+	}
+
+	for _, query := range []string{`article[foo="x"]`, `article[depth="two"]`, `article[title~="("]`, `article[title<3]`, ``} {
+		if _, err := ParseSelector(query); err == nil {
+			t.Errorf("%q should not parse", query)
+		}
+	}
+}
+
+func TestWalkSkipAndStop(t *testing.T) {
+	doc := selectorDocument()
+
+	var visited []string
+	doc.Walk(func(path []*DocumentSection, s *DocumentSection) error {
+		visited = append(visited, s.ID)
+		if s.ID == "capo_I" {
+			return SkipSection
+		}
+		return nil
+	})
+	if len(visited) != 5 || visited[2] != "capo_II" {
+		t.Errorf("capo I should be skipped, visited %v", visited)
+	}
+
+	stop := errors.New("stop")
+	count := 0
+	err := doc.Walk(func(path []*DocumentSection, s *DocumentSection) error {
+		count++
+		if s.ID == "art_2" {
+			if len(path) != 1 || path[0].ID != "capo_I" {
+				t.Errorf("unexpected path for art. 2: %v", path)
+			}
+			return stop
+		}
+		return nil
+	})
+	if err != stop || count != 4 {
+		t.Errorf("walk should stop at art. 2, got %v after %d sections", err, count)
+	}
+}
+
+func mustSelector(t *testing.T, query string) *Selector {
+	t.Helper()
+	sel, err := ParseSelector(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sel
+}
@@ -0,0 +1,137 @@
+package document
+
+import "strings"
+
+// Status tells whether an article or comma applies at the document's vigenza.
+// The zero value means in force.
+type Status string
+
+const (
+	StatusInForce    Status = ""
+	StatusRepealed   Status = "repealed"
+	StatusSuspended  Status = "suspended"
+	StatusNotInForce Status = "not-in-force"
+)
+
+// label is the Italian wording used when a section is collapsed.
+func (st Status) label() string {
+	switch st {
+	case StatusRepealed:
+		return "Abrogato"
+	case StatusSuspended:
+		return "Sospeso"
+	case StatusNotInForce:
+		return "Non ancora in vigore"
+	}
+	return ""
+}
+
+// SectionStatus records why an article or comma is not in force.
+type SectionStatus struct {
+	Status Status     `json:"status"`
+	Text   string     `json:"text,omitempty"` // the marker or note stating it, e.g. "ARTICOLO ABROGATO DAL D.LGS. 31 MARZO 2023, N. 36"
+	By     *Reference `json:"by,omitempty"`   // the repealing or suspending act, when known
+	Date   string     `json:"date,omitempty"` // YYYY-MM-DD the status ends (suspension) or begins to apply (not in force)
+}
+
+// CommaStatus is the status of the comma held in Content[Content].
+type CommaStatus struct {
+	Content int    `json:"content"`
+	Comma   string `json:"comma,omitempty"`
+	SectionStatus
+}
+
+// InactiveMode selects how repealed, suspended and not yet in force articles
+// and commas are rendered.
+type InactiveMode string
+
+const (
+	InactiveShow     InactiveMode = "show"
+	InactiveCollapse InactiveMode = "collapse"
+	InactiveOmit     InactiveMode = "omit"
+)
+
+// ParseInactiveMode maps a query parameter value to an InactiveMode, falling
+// back to InactiveShow.
+func ParseInactiveMode(s string) InactiveMode {
+	switch mode := InactiveMode(strings.ToLower(s)); mode {
+	case InactiveCollapse, InactiveOmit:
+		return mode
+	}
+	return InactiveShow
+}
+
+// IsActive reports whether the section is in force.
+func (s *DocumentSection) IsActive() bool {
+	return s.Status == nil || s.Status.Status == StatusInForce
+}
+
+// contentStatus returns the status of the comma in content block i, or nil.
+func (s *DocumentSection) contentStatus(i int) *SectionStatus {
+	for j := range s.CommaStatus {
+		if s.CommaStatus[j].Content == i {
+			return &s.CommaStatus[j].SectionStatus
+		}
+	}
+	return nil
+}
+
+// collapsed renders a status as the one-line placeholder shown instead of
+// the text, e.g. "*Abrogato* (D.Lgs. 31 marzo 2023, n. 36)".
+func (st *SectionStatus) collapsed() string {
+	text := "*" + st.Status.label() + "*"
+	if st.By != nil {
+		text += " (" + st.By.Markdown() + ")"
+	}
+	return text
+}
+
+// StatusEntry is an article or comma listed by a StatusSummary.
+type StatusEntry struct {
+	SectionID string `json:"sectionId,omitempty"`
+	Section   string `json:"section"`
+	Comma     string `json:"comma,omitempty"`
+	SectionStatus
+}
+
+// StatusSummary counts the articles of an act by status and lists those, and
+// the commas, that are not in force.
+type StatusSummary struct {
+	Articles   int           `json:"articles"`
+	InForce    int           `json:"inForce"`
+	Repealed   int           `json:"repealed"`
+	Suspended  int           `json:"suspended"`
+	NotInForce int           `json:"notInForce"`
+	Commas     int           `json:"commas"` // inactive commas of articles in force
+	Entries    []StatusEntry `json:"entries"`
+}
+
+// StatusSummary summarizes the status of the articles and commas of d.
+func (d *Document) StatusSummary() StatusSummary {
+	summary := StatusSummary{Entries: []StatusEntry{}}
+	d.Walk(func(_ []*DocumentSection, s *DocumentSection) error {
+		if s.IsArticle() {
+			summary.Articles++
+			switch {
+			case s.IsActive():
+				summary.InForce++
+			case s.Status.Status == StatusRepealed:
+				summary.Repealed++
+			case s.Status.Status == StatusSuspended:
+				summary.Suspended++
+			case s.Status.Status == StatusNotInForce:
+				summary.NotInForce++
+			}
+		}
+		if !s.IsActive() {
+			summary.Entries = append(summary.Entries, StatusEntry{SectionID: s.ID, Section: s.Title, SectionStatus: *s.Status})
+		} else {
+			for _, cs := range s.CommaStatus {
+				summary.Commas++
+				summary.Entries = append(summary.Entries, StatusEntry{SectionID: s.ID, Section: s.Title, Comma: cs.Comma, SectionStatus: cs.SectionStatus})
+			}
+		}
+		return nil
+	})
+	return summary
+}
@@ -0,0 +1,86 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrSectionNotFound is returned by Subset for targets matching no section.
+var ErrSectionNotFound = errors.New("section not found")
+
+// Subset returns a copy of d holding only the sections named by targets,
+// in document order, with their ancestors for context: the ancestors keep
+// their heading but lose their own text and notes. A target is a section ID
+// ("art_5", "capo_II") or a "/"-separated path of IDs ("capo_II/art_5")
+// naming a section by some of its ancestors. The copy shares the selected
+// sections with d.
+func (d *Document) Subset(targets []string) (*Document, error) {
+	selected := make(map[*DocumentSection]bool)
+	found := make(map[string]bool)
+
+	d.Walk(func(path []*DocumentSection, s *DocumentSection) error {
+		if s.ID == "" {
+			return nil
+		}
+		for _, target := range targets {
+			if matchesPath(path, s, target) {
+				selected[s] = true
+				found[target] = true
+			}
+		}
+		return nil
+	})
+
+	var missing []string
+	for _, target := range targets {
+		if !found[target] {
+			missing = append(missing, target)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSectionNotFound, strings.Join(missing, ", "))
+	}
+
+	sub := *d
+	sub.Sections = subsetSections(d.Sections, selected)
+	return &sub, nil
+}
+
+// matchesPath reports whether s, under the ancestors in path, is named by
+// target.
+func matchesPath(path []*DocumentSection, s *DocumentSection, target string) bool {
+	parts := strings.Split(strings.Trim(target, "/"), "/")
+	if parts[len(parts)-1] != s.ID {
+		return false
+	}
+	// The leading parts must name ancestors, outermost first
+	i := 0
+	for _, ancestor := range path {
+		if i < len(parts)-1 && ancestor.ID == parts[i] {
+			i++
+		}
+	}
+	return i == len(parts)-1
+}
+
+func subsetSections(sections []DocumentSection, selected map[*DocumentSection]bool) []DocumentSection {
+	var out []DocumentSection
+	for i := range sections {
+		s := &sections[i]
+		if selected[s] {
+			out = append(out, *s)
+			continue
+		}
+		children := subsetSections(s.Children, selected)
+		if len(children) == 0 {
+			continue
+		}
+		heading := *s
+		heading.Content, heading.Modified, heading.Updates = nil, nil, nil
+		heading.References, heading.CommaStatus = nil, nil
+		heading.Children = children
+		out = append(out, heading)
+	}
+	return out
+}
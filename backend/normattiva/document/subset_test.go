@@ -0,0 +1,42 @@
+package document
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSubset(t *testing.T) {
+	doc := selectorDocument()
+	doc.Sections[1].Content = []string{"Testo introduttivo del capo."}
+
+	sub, err := doc.Subset([]string{"art_11", "art_1", "capo_II/art_10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	sub.Walk(func(_ []*DocumentSection, s *DocumentSection) error {
+		got = append(got, s.ID)
+		return nil
+	})
+	if strings.Join(got, ",") != "capo_I,art_1,capo_II,art_10,art_11" {
+		t.Errorf("sections = %v", got)
+	}
+	if capo := sub.Sections[0]; capo.Title != "Capo I - Disposizioni generali" || capo.Content != nil {
+		t.Errorf("ancestor should keep its heading only: %+v", capo)
+	}
+	if len(doc.Sections) != 3 || len(doc.Sections[1].Children) != 2 || doc.Sections[1].Content == nil {
+		t.Errorf("original document modified")
+	}
+
+	// A whole chapter keeps its text and all its articles
+	sub, _ = doc.Subset([]string{"capo_I"})
+	if len(sub.Sections) != 1 || len(sub.Sections[0].Children) != 2 || sub.Sections[0].Content == nil {
+		t.Errorf("capo_I subset = %+v", sub.Sections)
+	}
+
+	if _, err := doc.Subset([]string{"art_1", "art_99", "capo_I/art_11"}); !errors.Is(err, ErrSectionNotFound) ||
+		!strings.Contains(err.Error(), "art_99, capo_I/art_11") {
+		t.Errorf("expected ErrSectionNotFound for art_99 and capo_I/art_11, got %v", err)
+	}
+}
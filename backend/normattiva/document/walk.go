@@ -0,0 +1,48 @@
+package document
+
+import "errors"
+
+// SkipSection is returned by a WalkFunc to skip the children of the current
+// section; Walk itself never returns it.
+var SkipSection = errors.New("skip section")
+
+// WalkFunc is called by Walk for each section, with the chain of its
+// ancestors (outermost first). The path is reused between calls and must be
+// copied to be kept.
+type WalkFunc func(path []*DocumentSection, s *DocumentSection) error
+
+// Walk visits the sections of d depth-first, in document order. It stops at
+// the first error returned by fn other than SkipSection.
+func (d *Document) Walk(fn WalkFunc) error {
+	return walk(d.Sections, nil, fn)
+}
+
+// Walk visits s and its descendants as Document.Walk does.
+func (s *DocumentSection) Walk(fn WalkFunc) error {
+	return walkSection(s, nil, fn)
+}
+
+func walk(sections []DocumentSection, path []*DocumentSection, fn WalkFunc) error {
+	for i := range sections {
+		if err := walkSection(&sections[i], path, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkSection(s *DocumentSection, path []*DocumentSection, fn WalkFunc) error {
+	if err := fn(path, s); err != nil {
+		if errors.Is(err, SkipSection) {
+			return nil
+		}
+		return err
+	}
+	return walk(s.Children, append(path, s), fn)
+}
+
+// IsArticle reports whether s is an article, in either the AKN or the NIR
+// vocabulary.
+func (s *DocumentSection) IsArticle() bool {
+	return s.Type == "article" || s.Type == "articolo"
+}
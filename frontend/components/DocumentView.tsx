@@ -647,6 +647,28 @@ export default function DocumentView({ docData, onNavigate, onTOCParsed, onActiv
 
     const LinkRenderer = (props: any) => {
         const href = props.href || '';
+        if (href.startsWith('#')) {
+            // Reference to another article of the same act
+            return (
+                <a href={href} onClick={(e) => {
+                    e.preventDefault();
+                    document.getElementById(href.slice(1))?.scrollIntoView({ behavior: 'smooth', block: 'start' });
+                }} className="text-primary underline decoration-primary/30 underline-offset-4 hover:decoration-primary transition-colors cursor-pointer">
+                    {props.children}
+                </a>
+            );
+        }
+        if (href.startsWith('/?urn=')) {
+            return (
+                <a href={href} onClick={(e) => {
+                    e.preventDefault();
+                    const urn = new URLSearchParams(href.slice(1)).get('urn');
+                    if (urn) onNavigate(urn);
+                }} className="text-primary underline decoration-primary/30 underline-offset-4 hover:decoration-primary transition-colors font-bold cursor-pointer">
+                    {props.children}
+                </a>
+            );
+        }
         if (href.includes('normattiva.it/uri-res/N2Ls')) {
             return (
                 <a href={href} onClick={(e) => {